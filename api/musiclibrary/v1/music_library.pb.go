@@ -0,0 +1,886 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.35.1
+// 	protoc        (unknown)
+// source: musiclibrary/v1/music_library.proto
+
+// Библиотека песен для внутренних сервисов. Методы повторяют REST API /api/v1
+// и выполняются той же логикой, что и запросы GraphQL.
+
+package musiclibraryv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Song struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id    int64  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Group string `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	Song  string `protobuf:"bytes,3,opt,name=song,proto3" json:"song,omitempty"`
+	// Дата выхода в формате YYYY-MM-DD, пустая, если неизвестна.
+	ReleaseDate string `protobuf:"bytes,4,opt,name=release_date,json=releaseDate,proto3" json:"release_date,omitempty"`
+	Text        string `protobuf:"bytes,5,opt,name=text,proto3" json:"text,omitempty"`
+	Link        string `protobuf:"bytes,6,opt,name=link,proto3" json:"link,omitempty"`
+	// Версия песни, увеличивается при каждом изменении.
+	Version int64 `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+func (x *Song) Reset() {
+	*x = Song{}
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Song) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Song) ProtoMessage() {}
+
+func (x *Song) ProtoReflect() protoreflect.Message {
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Song.ProtoReflect.Descriptor instead.
+func (*Song) Descriptor() ([]byte, []int) {
+	return file_musiclibrary_v1_music_library_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Song) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Song) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *Song) GetSong() string {
+	if x != nil {
+		return x.Song
+	}
+	return ""
+}
+
+func (x *Song) GetReleaseDate() string {
+	if x != nil {
+		return x.ReleaseDate
+	}
+	return ""
+}
+
+func (x *Song) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Song) GetLink() string {
+	if x != nil {
+		return x.Link
+	}
+	return ""
+}
+
+func (x *Song) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// Полное представление песни. Незаданные release_date, text и link очищаются.
+type SongInput struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Group string `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Song  string `protobuf:"bytes,2,opt,name=song,proto3" json:"song,omitempty"`
+	// Дата выхода в формате YYYY-MM-DD, пустая, если неизвестна.
+	ReleaseDate string `protobuf:"bytes,3,opt,name=release_date,json=releaseDate,proto3" json:"release_date,omitempty"`
+	Text        string `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	Link        string `protobuf:"bytes,5,opt,name=link,proto3" json:"link,omitempty"`
+}
+
+func (x *SongInput) Reset() {
+	*x = SongInput{}
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SongInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SongInput) ProtoMessage() {}
+
+func (x *SongInput) ProtoReflect() protoreflect.Message {
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SongInput.ProtoReflect.Descriptor instead.
+func (*SongInput) Descriptor() ([]byte, []int) {
+	return file_musiclibrary_v1_music_library_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SongInput) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *SongInput) GetSong() string {
+	if x != nil {
+		return x.Song
+	}
+	return ""
+}
+
+func (x *SongInput) GetReleaseDate() string {
+	if x != nil {
+		return x.ReleaseDate
+	}
+	return ""
+}
+
+func (x *SongInput) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *SongInput) GetLink() string {
+	if x != nil {
+		return x.Link
+	}
+	return ""
+}
+
+type Pagination struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Page    int32 `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	Limit   int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Total   int32 `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	HasMore bool  `protobuf:"varint,4,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+}
+
+func (x *Pagination) Reset() {
+	*x = Pagination{}
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pagination) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pagination) ProtoMessage() {}
+
+func (x *Pagination) ProtoReflect() protoreflect.Message {
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pagination.ProtoReflect.Descriptor instead.
+func (*Pagination) Descriptor() ([]byte, []int) {
+	return file_musiclibrary_v1_music_library_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Pagination) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *Pagination) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *Pagination) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Pagination) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
+// Фильтры совпадают с параметрами GET /api/v1/songs: все, кроме release_date,
+// ищут подстроку без учёта регистра.
+type ListSongsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	GroupName   string `protobuf:"bytes,1,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	Song        string `protobuf:"bytes,2,opt,name=song,proto3" json:"song,omitempty"`
+	ReleaseDate string `protobuf:"bytes,3,opt,name=release_date,json=releaseDate,proto3" json:"release_date,omitempty"`
+	Text        string `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	Link        string `protobuf:"bytes,5,opt,name=link,proto3" json:"link,omitempty"`
+	// Номер страницы, по умолчанию 1. Учитывается только вместе с limit.
+	Page  int32 `protobuf:"varint,6,opt,name=page,proto3" json:"page,omitempty"`
+	Limit int32 `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
+}
+
+func (x *ListSongsRequest) Reset() {
+	*x = ListSongsRequest{}
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSongsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSongsRequest) ProtoMessage() {}
+
+func (x *ListSongsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSongsRequest.ProtoReflect.Descriptor instead.
+func (*ListSongsRequest) Descriptor() ([]byte, []int) {
+	return file_musiclibrary_v1_music_library_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListSongsRequest) GetGroupName() string {
+	if x != nil {
+		return x.GroupName
+	}
+	return ""
+}
+
+func (x *ListSongsRequest) GetSong() string {
+	if x != nil {
+		return x.Song
+	}
+	return ""
+}
+
+func (x *ListSongsRequest) GetReleaseDate() string {
+	if x != nil {
+		return x.ReleaseDate
+	}
+	return ""
+}
+
+func (x *ListSongsRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *ListSongsRequest) GetLink() string {
+	if x != nil {
+		return x.Link
+	}
+	return ""
+}
+
+func (x *ListSongsRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListSongsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type GetSongRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *GetSongRequest) Reset() {
+	*x = GetSongRequest{}
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSongRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSongRequest) ProtoMessage() {}
+
+func (x *GetSongRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSongRequest.ProtoReflect.Descriptor instead.
+func (*GetSongRequest) Descriptor() ([]byte, []int) {
+	return file_musiclibrary_v1_music_library_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetSongRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type GetSongTextRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Номер страницы, по умолчанию 1.
+	Page int32 `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	// Число куплетов на странице, по умолчанию 2.
+	Limit int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+}
+
+func (x *GetSongTextRequest) Reset() {
+	*x = GetSongTextRequest{}
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSongTextRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSongTextRequest) ProtoMessage() {}
+
+func (x *GetSongTextRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSongTextRequest.ProtoReflect.Descriptor instead.
+func (*GetSongTextRequest) Descriptor() ([]byte, []int) {
+	return file_musiclibrary_v1_music_library_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetSongTextRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *GetSongTextRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *GetSongTextRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type GetSongTextResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Verses     []string    `protobuf:"bytes,1,rep,name=verses,proto3" json:"verses,omitempty"`
+	Pagination *Pagination `protobuf:"bytes,2,opt,name=pagination,proto3" json:"pagination,omitempty"`
+}
+
+func (x *GetSongTextResponse) Reset() {
+	*x = GetSongTextResponse{}
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSongTextResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSongTextResponse) ProtoMessage() {}
+
+func (x *GetSongTextResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSongTextResponse.ProtoReflect.Descriptor instead.
+func (*GetSongTextResponse) Descriptor() ([]byte, []int) {
+	return file_musiclibrary_v1_music_library_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetSongTextResponse) GetVerses() []string {
+	if x != nil {
+		return x.Verses
+	}
+	return nil
+}
+
+func (x *GetSongTextResponse) GetPagination() *Pagination {
+	if x != nil {
+		return x.Pagination
+	}
+	return nil
+}
+
+type AddSongRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Group string `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Song  string `protobuf:"bytes,2,opt,name=song,proto3" json:"song,omitempty"`
+}
+
+func (x *AddSongRequest) Reset() {
+	*x = AddSongRequest{}
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddSongRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddSongRequest) ProtoMessage() {}
+
+func (x *AddSongRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddSongRequest.ProtoReflect.Descriptor instead.
+func (*AddSongRequest) Descriptor() ([]byte, []int) {
+	return file_musiclibrary_v1_music_library_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AddSongRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *AddSongRequest) GetSong() string {
+	if x != nil {
+		return x.Song
+	}
+	return ""
+}
+
+type UpdateSongRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id   int64      `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Song *SongInput `protobuf:"bytes,2,opt,name=song,proto3" json:"song,omitempty"`
+	// Ожидаемая версия песни, аналог If-Match. При несовпадении возвращается
+	// FAILED_PRECONDITION. Обязательна, если включён REQUIRE_IF_MATCH.
+	ExpectedVersion *int64 `protobuf:"varint,3,opt,name=expected_version,json=expectedVersion,proto3,oneof" json:"expected_version,omitempty"`
+}
+
+func (x *UpdateSongRequest) Reset() {
+	*x = UpdateSongRequest{}
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateSongRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateSongRequest) ProtoMessage() {}
+
+func (x *UpdateSongRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateSongRequest.ProtoReflect.Descriptor instead.
+func (*UpdateSongRequest) Descriptor() ([]byte, []int) {
+	return file_musiclibrary_v1_music_library_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UpdateSongRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateSongRequest) GetSong() *SongInput {
+	if x != nil {
+		return x.Song
+	}
+	return nil
+}
+
+func (x *UpdateSongRequest) GetExpectedVersion() int64 {
+	if x != nil && x.ExpectedVersion != nil {
+		return *x.ExpectedVersion
+	}
+	return 0
+}
+
+type DeleteSongRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Ожидаемая версия песни, как в UpdateSongRequest.
+	ExpectedVersion *int64 `protobuf:"varint,2,opt,name=expected_version,json=expectedVersion,proto3,oneof" json:"expected_version,omitempty"`
+}
+
+func (x *DeleteSongRequest) Reset() {
+	*x = DeleteSongRequest{}
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSongRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSongRequest) ProtoMessage() {}
+
+func (x *DeleteSongRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_musiclibrary_v1_music_library_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSongRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSongRequest) Descriptor() ([]byte, []int) {
+	return file_musiclibrary_v1_music_library_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeleteSongRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DeleteSongRequest) GetExpectedVersion() int64 {
+	if x != nil && x.ExpectedVersion != nil {
+		return *x.ExpectedVersion
+	}
+	return 0
+}
+
+var File_musiclibrary_v1_music_library_proto protoreflect.FileDescriptor
+
+var file_musiclibrary_v1_music_library_proto_rawDesc = []byte{
+	0x0a, 0x23, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x79, 0x2f, 0x76,
+	0x31, 0x2f, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x5f, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x79, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0f, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x6c, 0x69, 0x62, 0x72,
+	0x61, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x1a, 0x1b, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x22, 0xa5, 0x01, 0x0a, 0x04, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05,
+	0x67, 0x72, 0x6f, 0x75, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x6f,
+	0x75, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x6f, 0x6e, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x73, 0x6f, 0x6e, 0x67, 0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73,
+	0x65, 0x5f, 0x64, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65,
+	0x6c, 0x65, 0x61, 0x73, 0x65, 0x44, 0x61, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x78,
+	0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x6c, 0x69, 0x6e, 0x6b, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6c, 0x69, 0x6e,
+	0x6b, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x80, 0x01, 0x0a, 0x09,
+	0x53, 0x6f, 0x6e, 0x67, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72, 0x6f,
+	0x75, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x12,
+	0x12, 0x0a, 0x04, 0x73, 0x6f, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73,
+	0x6f, 0x6e, 0x67, 0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x5f, 0x64,
+	0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x6c, 0x65, 0x61,
+	0x73, 0x65, 0x44, 0x61, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x69,
+	0x6e, 0x6b, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6c, 0x69, 0x6e, 0x6b, 0x22, 0x67,
+	0x0a, 0x0a, 0x50, 0x61, 0x67, 0x69, 0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04,
+	0x70, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x61, 0x67, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x19, 0x0a, 0x08,
+	0x68, 0x61, 0x73, 0x5f, 0x6d, 0x6f, 0x72, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07,
+	0x68, 0x61, 0x73, 0x4d, 0x6f, 0x72, 0x65, 0x22, 0xba, 0x01, 0x0a, 0x10, 0x4c, 0x69, 0x73, 0x74,
+	0x53, 0x6f, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a, 0x0a,
+	0x67, 0x72, 0x6f, 0x75, 0x70, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x73,
+	0x6f, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x6f, 0x6e, 0x67, 0x12,
+	0x21, 0x0a, 0x0c, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x5f, 0x64, 0x61, 0x74, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x44, 0x61,
+	0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x69, 0x6e, 0x6b, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6c, 0x69, 0x6e, 0x6b, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61,
+	0x67, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x61, 0x67, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c,
+	0x69, 0x6d, 0x69, 0x74, 0x22, 0x20, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x02, 0x69, 0x64, 0x22, 0x4e, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x53, 0x6f, 0x6e,
+	0x67, 0x54, 0x65, 0x78, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04,
+	0x70, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x61, 0x67, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x22, 0x6a, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x53, 0x6f, 0x6e,
+	0x67, 0x54, 0x65, 0x78, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a,
+	0x06, 0x76, 0x65, 0x72, 0x73, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x76,
+	0x65, 0x72, 0x73, 0x65, 0x73, 0x12, 0x3b, 0x0a, 0x0a, 0x70, 0x61, 0x67, 0x69, 0x6e, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x6d, 0x75, 0x73, 0x69,
+	0x63, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x61, 0x67, 0x69,
+	0x6e, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x70, 0x61, 0x67, 0x69, 0x6e, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x22, 0x3a, 0x0a, 0x0e, 0x41, 0x64, 0x64, 0x53, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x6f,
+	0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x6f, 0x6e, 0x67, 0x22, 0x98,
+	0x01, 0x0a, 0x11, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x53, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x02, 0x69, 0x64, 0x12, 0x2e, 0x0a, 0x04, 0x73, 0x6f, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x72,
+	0x79, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6f, 0x6e, 0x67, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x52, 0x04,
+	0x73, 0x6f, 0x6e, 0x67, 0x12, 0x2e, 0x0a, 0x10, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64,
+	0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00,
+	0x52, 0x0f, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x88, 0x01, 0x01, 0x42, 0x13, 0x0a, 0x11, 0x5f, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65,
+	0x64, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x68, 0x0a, 0x11, 0x44, 0x65, 0x6c,
+	0x65, 0x74, 0x65, 0x53, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e,
+	0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x69, 0x64, 0x12, 0x2e,
+	0x0a, 0x10, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x0f, 0x65, 0x78, 0x70, 0x65,
+	0x63, 0x74, 0x65, 0x64, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x88, 0x01, 0x01, 0x42, 0x13,
+	0x0a, 0x11, 0x5f, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x5f, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x32, 0xca, 0x03, 0x0a, 0x0c, 0x4d, 0x75, 0x73, 0x69, 0x63, 0x4c, 0x69, 0x62,
+	0x72, 0x61, 0x72, 0x79, 0x12, 0x47, 0x0a, 0x09, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x6f, 0x6e, 0x67,
+	0x73, 0x12, 0x21, 0x2e, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x79,
+	0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x6c, 0x69, 0x62, 0x72,
+	0x61, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6f, 0x6e, 0x67, 0x30, 0x01, 0x12, 0x41, 0x0a,
+	0x07, 0x47, 0x65, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x1f, 0x2e, 0x6d, 0x75, 0x73, 0x69, 0x63,
+	0x6c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x6f,
+	0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x6d, 0x75, 0x73, 0x69,
+	0x63, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6f, 0x6e, 0x67,
+	0x12, 0x58, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x54, 0x65, 0x78, 0x74, 0x12,
+	0x23, 0x2e, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x79, 0x2e, 0x76,
+	0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x54, 0x65, 0x78, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x24, 0x2e, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x6c, 0x69, 0x62, 0x72,
+	0x61, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x54, 0x65,
+	0x78, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x41, 0x0a, 0x07, 0x41, 0x64,
+	0x64, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x1f, 0x2e, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x6c, 0x69, 0x62,
+	0x72, 0x61, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x64, 0x64, 0x53, 0x6f, 0x6e, 0x67, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x6c, 0x69,
+	0x62, 0x72, 0x61, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x47, 0x0a,
+	0x0a, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x22, 0x2e, 0x6d, 0x75,
+	0x73, 0x69, 0x63, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x53, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x15, 0x2e, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x79, 0x2e, 0x76,
+	0x31, 0x2e, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x48, 0x0a, 0x0a, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x53, 0x6f, 0x6e, 0x67, 0x12, 0x22, 0x2e, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x6c, 0x69, 0x62, 0x72,
+	0x61, 0x72, 0x79, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x53, 0x6f, 0x6e,
+	0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x42, 0x47, 0x5a, 0x45, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x69,
+	0x6e, 0x61, 0x6e, 0x6d, 0x61, 0x73, 0x6f, 0x76, 0x2f, 0x6d, 0x75, 0x73, 0x69, 0x63, 0x2d, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x6d, 0x75, 0x73, 0x69, 0x63,
+	0x6c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x79, 0x2f, 0x76, 0x31, 0x3b, 0x6d, 0x75, 0x73, 0x69, 0x63,
+	0x6c, 0x69, 0x62, 0x72, 0x61, 0x72, 0x79, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
+}
+
+var (
+	file_musiclibrary_v1_music_library_proto_rawDescOnce sync.Once
+	file_musiclibrary_v1_music_library_proto_rawDescData = file_musiclibrary_v1_music_library_proto_rawDesc
+)
+
+func file_musiclibrary_v1_music_library_proto_rawDescGZIP() []byte {
+	file_musiclibrary_v1_music_library_proto_rawDescOnce.Do(func() {
+		file_musiclibrary_v1_music_library_proto_rawDescData = protoimpl.X.CompressGZIP(file_musiclibrary_v1_music_library_proto_rawDescData)
+	})
+	return file_musiclibrary_v1_music_library_proto_rawDescData
+}
+
+var file_musiclibrary_v1_music_library_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_musiclibrary_v1_music_library_proto_goTypes = []any{
+	(*Song)(nil),                // 0: musiclibrary.v1.Song
+	(*SongInput)(nil),           // 1: musiclibrary.v1.SongInput
+	(*Pagination)(nil),          // 2: musiclibrary.v1.Pagination
+	(*ListSongsRequest)(nil),    // 3: musiclibrary.v1.ListSongsRequest
+	(*GetSongRequest)(nil),      // 4: musiclibrary.v1.GetSongRequest
+	(*GetSongTextRequest)(nil),  // 5: musiclibrary.v1.GetSongTextRequest
+	(*GetSongTextResponse)(nil), // 6: musiclibrary.v1.GetSongTextResponse
+	(*AddSongRequest)(nil),      // 7: musiclibrary.v1.AddSongRequest
+	(*UpdateSongRequest)(nil),   // 8: musiclibrary.v1.UpdateSongRequest
+	(*DeleteSongRequest)(nil),   // 9: musiclibrary.v1.DeleteSongRequest
+	(*emptypb.Empty)(nil),       // 10: google.protobuf.Empty
+}
+var file_musiclibrary_v1_music_library_proto_depIdxs = []int32{
+	2,  // 0: musiclibrary.v1.GetSongTextResponse.pagination:type_name -> musiclibrary.v1.Pagination
+	1,  // 1: musiclibrary.v1.UpdateSongRequest.song:type_name -> musiclibrary.v1.SongInput
+	3,  // 2: musiclibrary.v1.MusicLibrary.ListSongs:input_type -> musiclibrary.v1.ListSongsRequest
+	4,  // 3: musiclibrary.v1.MusicLibrary.GetSong:input_type -> musiclibrary.v1.GetSongRequest
+	5,  // 4: musiclibrary.v1.MusicLibrary.GetSongText:input_type -> musiclibrary.v1.GetSongTextRequest
+	7,  // 5: musiclibrary.v1.MusicLibrary.AddSong:input_type -> musiclibrary.v1.AddSongRequest
+	8,  // 6: musiclibrary.v1.MusicLibrary.UpdateSong:input_type -> musiclibrary.v1.UpdateSongRequest
+	9,  // 7: musiclibrary.v1.MusicLibrary.DeleteSong:input_type -> musiclibrary.v1.DeleteSongRequest
+	0,  // 8: musiclibrary.v1.MusicLibrary.ListSongs:output_type -> musiclibrary.v1.Song
+	0,  // 9: musiclibrary.v1.MusicLibrary.GetSong:output_type -> musiclibrary.v1.Song
+	6,  // 10: musiclibrary.v1.MusicLibrary.GetSongText:output_type -> musiclibrary.v1.GetSongTextResponse
+	0,  // 11: musiclibrary.v1.MusicLibrary.AddSong:output_type -> musiclibrary.v1.Song
+	0,  // 12: musiclibrary.v1.MusicLibrary.UpdateSong:output_type -> musiclibrary.v1.Song
+	10, // 13: musiclibrary.v1.MusicLibrary.DeleteSong:output_type -> google.protobuf.Empty
+	8,  // [8:14] is the sub-list for method output_type
+	2,  // [2:8] is the sub-list for method input_type
+	2,  // [2:2] is the sub-list for extension type_name
+	2,  // [2:2] is the sub-list for extension extendee
+	0,  // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_musiclibrary_v1_music_library_proto_init() }
+func file_musiclibrary_v1_music_library_proto_init() {
+	if File_musiclibrary_v1_music_library_proto != nil {
+		return
+	}
+	file_musiclibrary_v1_music_library_proto_msgTypes[8].OneofWrappers = []any{}
+	file_musiclibrary_v1_music_library_proto_msgTypes[9].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_musiclibrary_v1_music_library_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_musiclibrary_v1_music_library_proto_goTypes,
+		DependencyIndexes: file_musiclibrary_v1_music_library_proto_depIdxs,
+		MessageInfos:      file_musiclibrary_v1_music_library_proto_msgTypes,
+	}.Build()
+	File_musiclibrary_v1_music_library_proto = out.File
+	file_musiclibrary_v1_music_library_proto_rawDesc = nil
+	file_musiclibrary_v1_music_library_proto_goTypes = nil
+	file_musiclibrary_v1_music_library_proto_depIdxs = nil
+}
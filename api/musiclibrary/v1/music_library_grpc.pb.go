@@ -0,0 +1,333 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: musiclibrary/v1/music_library.proto
+
+// Библиотека песен для внутренних сервисов. Методы повторяют REST API /api/v1
+// и выполняются той же логикой, что и запросы GraphQL.
+
+package musiclibraryv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MusicLibrary_ListSongs_FullMethodName   = "/musiclibrary.v1.MusicLibrary/ListSongs"
+	MusicLibrary_GetSong_FullMethodName     = "/musiclibrary.v1.MusicLibrary/GetSong"
+	MusicLibrary_GetSongText_FullMethodName = "/musiclibrary.v1.MusicLibrary/GetSongText"
+	MusicLibrary_AddSong_FullMethodName     = "/musiclibrary.v1.MusicLibrary/AddSong"
+	MusicLibrary_UpdateSong_FullMethodName  = "/musiclibrary.v1.MusicLibrary/UpdateSong"
+	MusicLibrary_DeleteSong_FullMethodName  = "/musiclibrary.v1.MusicLibrary/DeleteSong"
+)
+
+// MusicLibraryClient is the client API for MusicLibrary service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MusicLibraryClient interface {
+	// Потоково передаёт песни, подходящие под фильтры, в порядке ID. Без limit
+	// передаются все подходящие песни.
+	ListSongs(ctx context.Context, in *ListSongsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Song], error)
+	// Возвращает песню по ID. NOT_FOUND, если песни нет.
+	GetSong(ctx context.Context, in *GetSongRequest, opts ...grpc.CallOption) (*Song, error)
+	// Возвращает страницу куплетов текста песни.
+	GetSongText(ctx context.Context, in *GetSongTextRequest, opts ...grpc.CallOption) (*GetSongTextResponse, error)
+	// Добавляет песню. Дата выхода, текст и ссылка запрашиваются во внешнем API.
+	AddSong(ctx context.Context, in *AddSongRequest, opts ...grpc.CallOption) (*Song, error)
+	// Полностью заменяет данные песни.
+	UpdateSong(ctx context.Context, in *UpdateSongRequest, opts ...grpc.CallOption) (*Song, error)
+	// Удаляет песню.
+	DeleteSong(ctx context.Context, in *DeleteSongRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type musicLibraryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMusicLibraryClient(cc grpc.ClientConnInterface) MusicLibraryClient {
+	return &musicLibraryClient{cc}
+}
+
+func (c *musicLibraryClient) ListSongs(ctx context.Context, in *ListSongsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Song], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MusicLibrary_ServiceDesc.Streams[0], MusicLibrary_ListSongs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListSongsRequest, Song]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MusicLibrary_ListSongsClient = grpc.ServerStreamingClient[Song]
+
+func (c *musicLibraryClient) GetSong(ctx context.Context, in *GetSongRequest, opts ...grpc.CallOption) (*Song, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Song)
+	err := c.cc.Invoke(ctx, MusicLibrary_GetSong_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *musicLibraryClient) GetSongText(ctx context.Context, in *GetSongTextRequest, opts ...grpc.CallOption) (*GetSongTextResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetSongTextResponse)
+	err := c.cc.Invoke(ctx, MusicLibrary_GetSongText_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *musicLibraryClient) AddSong(ctx context.Context, in *AddSongRequest, opts ...grpc.CallOption) (*Song, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Song)
+	err := c.cc.Invoke(ctx, MusicLibrary_AddSong_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *musicLibraryClient) UpdateSong(ctx context.Context, in *UpdateSongRequest, opts ...grpc.CallOption) (*Song, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Song)
+	err := c.cc.Invoke(ctx, MusicLibrary_UpdateSong_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *musicLibraryClient) DeleteSong(ctx context.Context, in *DeleteSongRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, MusicLibrary_DeleteSong_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MusicLibraryServer is the server API for MusicLibrary service.
+// All implementations must embed UnimplementedMusicLibraryServer
+// for forward compatibility.
+type MusicLibraryServer interface {
+	// Потоково передаёт песни, подходящие под фильтры, в порядке ID. Без limit
+	// передаются все подходящие песни.
+	ListSongs(*ListSongsRequest, grpc.ServerStreamingServer[Song]) error
+	// Возвращает песню по ID. NOT_FOUND, если песни нет.
+	GetSong(context.Context, *GetSongRequest) (*Song, error)
+	// Возвращает страницу куплетов текста песни.
+	GetSongText(context.Context, *GetSongTextRequest) (*GetSongTextResponse, error)
+	// Добавляет песню. Дата выхода, текст и ссылка запрашиваются во внешнем API.
+	AddSong(context.Context, *AddSongRequest) (*Song, error)
+	// Полностью заменяет данные песни.
+	UpdateSong(context.Context, *UpdateSongRequest) (*Song, error)
+	// Удаляет песню.
+	DeleteSong(context.Context, *DeleteSongRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedMusicLibraryServer()
+}
+
+// UnimplementedMusicLibraryServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMusicLibraryServer struct{}
+
+func (UnimplementedMusicLibraryServer) ListSongs(*ListSongsRequest, grpc.ServerStreamingServer[Song]) error {
+	return status.Errorf(codes.Unimplemented, "method ListSongs not implemented")
+}
+func (UnimplementedMusicLibraryServer) GetSong(context.Context, *GetSongRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSong not implemented")
+}
+func (UnimplementedMusicLibraryServer) GetSongText(context.Context, *GetSongTextRequest) (*GetSongTextResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSongText not implemented")
+}
+func (UnimplementedMusicLibraryServer) AddSong(context.Context, *AddSongRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddSong not implemented")
+}
+func (UnimplementedMusicLibraryServer) UpdateSong(context.Context, *UpdateSongRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateSong not implemented")
+}
+func (UnimplementedMusicLibraryServer) DeleteSong(context.Context, *DeleteSongRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSong not implemented")
+}
+func (UnimplementedMusicLibraryServer) mustEmbedUnimplementedMusicLibraryServer() {}
+func (UnimplementedMusicLibraryServer) testEmbeddedByValue()                      {}
+
+// UnsafeMusicLibraryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MusicLibraryServer will
+// result in compilation errors.
+type UnsafeMusicLibraryServer interface {
+	mustEmbedUnimplementedMusicLibraryServer()
+}
+
+func RegisterMusicLibraryServer(s grpc.ServiceRegistrar, srv MusicLibraryServer) {
+	// If the following call pancis, it indicates UnimplementedMusicLibraryServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MusicLibrary_ServiceDesc, srv)
+}
+
+func _MusicLibrary_ListSongs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListSongsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MusicLibraryServer).ListSongs(m, &grpc.GenericServerStream[ListSongsRequest, Song]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MusicLibrary_ListSongsServer = grpc.ServerStreamingServer[Song]
+
+func _MusicLibrary_GetSong_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSongRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MusicLibraryServer).GetSong(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MusicLibrary_GetSong_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MusicLibraryServer).GetSong(ctx, req.(*GetSongRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MusicLibrary_GetSongText_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSongTextRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MusicLibraryServer).GetSongText(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MusicLibrary_GetSongText_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MusicLibraryServer).GetSongText(ctx, req.(*GetSongTextRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MusicLibrary_AddSong_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddSongRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MusicLibraryServer).AddSong(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MusicLibrary_AddSong_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MusicLibraryServer).AddSong(ctx, req.(*AddSongRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MusicLibrary_UpdateSong_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateSongRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MusicLibraryServer).UpdateSong(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MusicLibrary_UpdateSong_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MusicLibraryServer).UpdateSong(ctx, req.(*UpdateSongRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MusicLibrary_DeleteSong_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSongRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MusicLibraryServer).DeleteSong(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MusicLibrary_DeleteSong_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MusicLibraryServer).DeleteSong(ctx, req.(*DeleteSongRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MusicLibrary_ServiceDesc is the grpc.ServiceDesc for MusicLibrary service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MusicLibrary_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "musiclibrary.v1.MusicLibrary",
+	HandlerType: (*MusicLibraryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetSong",
+			Handler:    _MusicLibrary_GetSong_Handler,
+		},
+		{
+			MethodName: "GetSongText",
+			Handler:    _MusicLibrary_GetSongText_Handler,
+		},
+		{
+			MethodName: "AddSong",
+			Handler:    _MusicLibrary_AddSong_Handler,
+		},
+		{
+			MethodName: "UpdateSong",
+			Handler:    _MusicLibrary_UpdateSong_Handler,
+		},
+		{
+			MethodName: "DeleteSong",
+			Handler:    _MusicLibrary_DeleteSong_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListSongs",
+			Handler:       _MusicLibrary_ListSongs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "musiclibrary/v1/music_library.proto",
+}
@@ -1,69 +1,76 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"os"
 
-	"github.com/gin-gonic/gin"
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	"github.com/inanmasov/music-service/internal/handlers"
-	"github.com/inanmasov/music-service/internal/logger"
-	"github.com/joho/godotenv"
-	swaggerfiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
-	_ "github.com/zhashkevych/todo-app/docs"
+	"github.com/inanmasov/music-service/internal/config"
 )
 
+const usage = `Usage: music-service [--config file] [--print-config] [command]
+
+Commands:
+  serve [--migrate] [--require-migrations]   Start the HTTP server (default)
+  migrate up [N]                             Apply all or N pending migrations
+  migrate down N | --all                     Roll back N or all migrations
+  migrate goto V                             Migrate up or down to version V
+  migrate version                            Print the current schema version
+  migrate force V                            Set the version without running migrations, clearing the dirty flag
+  migrate status                             List migrations and whether they are applied
+
+Global flags:
+`
+
 // @title Music Service API
 // @version 1.0
 // @description This is a service to manage songs in a library.
 // @host localhost:8080
 // @BasePath /
 func main() {
-	log := logger.GetLogger()
-
-	err := godotenv.Load()
-	if err != nil {
-		log.Error("Loading .env file")
+	configPath := flag.String("config", "", "Path to a YAML configuration file (default $CONFIG_FILE)")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration with secrets masked and exit")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
 	}
+	flag.Parse()
 
-	m, err := migrate.New(
-		"file://migrations",
-		"postgres://"+os.Getenv("DB_USER")+":"+os.Getenv("DB_PASSWORD")+"@"+os.Getenv("DB_HOST")+":"+os.Getenv("DB_PORT")+"/"+os.Getenv("DB_NAME")+"?sslmode=disable",
-	)
+	// Загружаем настройки до создания логгера: при ошибках выводим их все сразу
+	cfg, err := config.Load(*configPath)
+	if *printConfig {
+		out, yamlErr := cfg.Masked().YAML()
+		if yamlErr != nil {
+			fmt.Fprintln(os.Stderr, yamlErr)
+			os.Exit(1)
+		}
+		os.Stdout.Write(out)
+	}
 	if err != nil {
-		log.Errorf("Initializing migrations: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-
-	err = m.Up()
-	if err != nil && err != migrate.ErrNoChange {
-		log.Errorf("Applying migrations: %v", err)
+	if *printConfig {
+		return
 	}
 
-	log.Info("Migrations applied successfully!")
-
-	// Инициализация роутера
-	r := gin.Default()
-	log.Info("Gin router initialized")
-
-	// Добавляем статические файлы для swagger
-	r.Static("/docs", "./docs")
-
-	// Маршруты для работы с песнями
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler,
-		ginSwagger.URL("/docs/swagger.json"))) // swagger
-	r.GET("/songs", handlers.GetSongs)             // Получение списка песен с фильтрацией и пагинацией
-	r.GET("/songs/:id/text", handlers.GetSongText) // Получение текста песни с пагинацией по куплетам
-	r.POST("/songs", handlers.AddSong)             // Добавление новой песни
-	r.PUT("/songs/:id", handlers.UpdateSong)       // Изменение данных песни
-	r.DELETE("/songs/:id", handlers.DeleteSong)    // Удаление песни
+	command, args := "serve", flag.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
 
-	// Запуск сервера
-	port := os.Getenv("SERVER_PORT")
-	log.Info("Starting server on :" + port)
-	if err := r.Run(":" + port); err != nil {
-		log.Errorf("Failed to start server: %v", err)
+	switch command {
+	case "serve":
+		err = runServe(cfg, args)
+	case "migrate":
+		err = runMigrate(cfg, args)
+	default:
+		flag.Usage()
+		err = fmt.Errorf("unknown command %q", command)
+	}
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	log.Info("Server started successfully")
 }
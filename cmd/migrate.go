@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/migrations"
+)
+
+// runMigrate выполняет подкоманду migrate
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("migrate: expected up, down, goto, version, force or status")
+	}
+	command, args := args[0], args[1:]
+
+	flags := flag.NewFlagSet("migrate "+command, flag.ContinueOnError)
+	all := flags.Bool("all", false, "Roll back all migrations (migrate down)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+
+	m, err := newMigrate(cfg.Database.DSN())
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch command {
+	case "up":
+		if len(args) == 0 {
+			return noChange(m.Up())
+		}
+		steps, err := positiveArg(args, "up")
+		if err != nil {
+			return err
+		}
+		return noChange(m.Steps(steps))
+	case "down":
+		if *all {
+			return noChange(m.Down())
+		}
+		steps, err := positiveArg(args, "down")
+		if err != nil {
+			return errors.New("migrate down: expected the number of migrations to roll back or --all")
+		}
+		return noChange(m.Steps(-steps))
+	case "goto":
+		if len(args) != 1 {
+			return errors.New("migrate goto: expected a version")
+		}
+		version, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("migrate goto: invalid version %q", args[0])
+		}
+		return noChange(m.Migrate(uint(version)))
+	case "version":
+		version, dirty, err := m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			fmt.Println("no migrations applied")
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if dirty {
+			fmt.Printf("%d (dirty)\n", version)
+		} else {
+			fmt.Println(version)
+		}
+		return nil
+	case "force":
+		// -1 означает, что ни одна миграция не применена
+		if len(args) != 1 {
+			return errors.New("migrate force: expected a version")
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < -1 {
+			return fmt.Errorf("migrate force: invalid version %q", args[0])
+		}
+		return m.Force(version)
+	case "status":
+		return printStatus(m)
+	default:
+		return fmt.Errorf("migrate: unknown command %q", command)
+	}
+}
+
+// newMigrate создаёт golang-migrate, пишущий о каждой применённой миграции в лог
+func newMigrate(dsn string) (*migrate.Migrate, error) {
+	m, err := migrations.New(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("initializing migrations: %w", err)
+	}
+	m.Log = migrateLogger{}
+	return m, nil
+}
+
+// migrateUp применяет все ещё не применённые миграции
+func migrateUp(dsn string) error {
+	m, err := newMigrate(dsn)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := noChange(m.Up()); err != nil {
+		return fmt.Errorf("applying migrations: %w", err)
+	}
+	logger.GetLogger().Info("Migrations applied successfully!")
+	return nil
+}
+
+// checkMigrations возвращает ошибку, если схема не в состоянии latest:
+// последняя миграция завершилась с ошибкой или применены не все миграции
+func checkMigrations(dsn string, latest uint) error {
+	m, err := newMigrate(dsn)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		version, err = 0, nil
+	}
+	switch {
+	case err != nil:
+		return fmt.Errorf("reading schema version: %w", err)
+	case dirty:
+		return fmt.Errorf("schema version %d is dirty: fix the database and run migrate force", version)
+	case version < latest:
+		return fmt.Errorf("schema version %d is behind %d: run migrate up", version, latest)
+	case version > latest:
+		logger.GetLogger().Warnf("Schema version %d is ahead of the latest embedded migration %d", version, latest)
+	}
+	return nil
+}
+
+// printStatus выводит список миграций с отметкой о применении
+func printStatus(m *migrate.Migrate) error {
+	list, err := migrations.List()
+	if err != nil {
+		return err
+	}
+
+	current, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		current, err = 0, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "VERSION\tNAME\tSTATUS")
+	for _, migration := range list {
+		status := "pending"
+		switch {
+		case migration.Version == current && dirty:
+			status = "dirty"
+		case migration.Version <= current:
+			status = "applied"
+		}
+		fmt.Fprintf(out, "%d\t%s\t%s\n", migration.Version, migration.Name, status)
+	}
+	return out.Flush()
+}
+
+// positiveArg разбирает единственный аргумент команды как положительное число
+func positiveArg(args []string, command string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("migrate %s: expected a single number of migrations", command)
+	}
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps < 1 {
+		return 0, fmt.Errorf("migrate %s: invalid number of migrations %q", command, args[0])
+	}
+	return steps, nil
+}
+
+// noChange считает отсутствие изменений успешным выполнением
+func noChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		logger.GetLogger().Info("No migrations to apply")
+		return nil
+	}
+	return err
+}
+
+// migrateLogger передаёт сообщения golang-migrate в лог сервиса
+type migrateLogger struct{}
+
+func (migrateLogger) Printf(format string, v ...interface{}) {
+	logger.GetLogger().Info(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (migrateLogger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/inanmasov/music-service/docs"
+	"github.com/inanmasov/music-service/internal/cache"
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/db"
+	"github.com/inanmasov/music-service/internal/handlers"
+	"github.com/inanmasov/music-service/internal/health"
+	"github.com/inanmasov/music-service/internal/importer"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/metrics"
+	"github.com/inanmasov/music-service/internal/outbox"
+	"github.com/inanmasov/music-service/internal/tracing"
+	"github.com/inanmasov/music-service/migrations"
+	swaggerfiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"google.golang.org/grpc"
+)
+
+// runServe запускает HTTP-сервер и фоновые обработчики до сигнала остановки
+func runServe(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	applyMigrations := flags.Bool("migrate", false, "Apply pending migrations before serving")
+	requireMigrations := flags.Bool("require-migrations", false, "Refuse to serve if the schema is dirty or behind the embedded migrations")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	log := logger.GetLogger()
+
+	// Миграции применяются только с флагом --migrate, иначе схемой управляет
+	// команда migrate. С --require-migrations сервис не запускается, если схема
+	// отстаёт от встроенных миграций или осталась в состоянии dirty
+	if *applyMigrations {
+		if err := migrateUp(cfg.Database.DSN()); err != nil {
+			if *requireMigrations {
+				return err
+			}
+			log.Errorf("Applying migrations: %v", err)
+		}
+	}
+	latest, err := migrations.Latest()
+	if err != nil {
+		return fmt.Errorf("reading embedded migrations: %w", err)
+	}
+	health.SetExpectedMigration(latest)
+	if *requireMigrations {
+		if err := checkMigrations(cfg.Database.DSN(), latest); err != nil {
+			return err
+		}
+	}
+
+	// Трассировка запросов, SQL и обращений к внешнему API
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.ServiceName)
+	if err != nil {
+		log.Errorf("Failed to initialize tracing: %v", err)
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+
+	// Фоновые обработчики останавливаются отменой workers при остановке сервиса
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	dispatcherDone := make(chan struct{})
+
+	// Общий пул подключений открывается при первом обращении, поэтому метрики базы
+	// и доставка событий из outbox вебхукам начинают работать, как только она станет доступна
+	defer db.Close()
+	metrics.RegisterDB(db.Initialize)
+	dispatcher := outbox.NewDispatcher(db.Initialize, cfg.Webhooks.DispatchInterval.Std(),
+		cfg.Webhooks.Timeout.Std(), cfg.Webhooks.MaxAttempts, cfg.Webhooks.AllowPrivateNetworks)
+	go func() {
+		defer close(dispatcherDone)
+		dispatcher.Run(workers)
+	}()
+	metrics.RegisterQueueDepth("import", importer.GetManager().QueueDepth)
+
+	// Инициализация роутера
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(otelgin.Middleware(cfg.Tracing.ServiceName))
+	r.Use(logger.RequestLogger())
+	r.Use(metrics.Middleware())
+	r.Use(handlers.Idempotency())
+	log.Info("Gin router initialized")
+
+	// Проверки состояния сервиса
+	r.GET("/healthz", handlers.Healthz) // Процесс жив
+	r.GET("/readyz", handlers.Readyz)   // Готовность принимать запросы
+	r.GET("/status", handlers.Status)   // Сборка, время работы и состояние зависимостей
+
+	// Метрики в формате Prometheus
+	r.GET("/metrics", metrics.Handler())
+
+	// Добавляем статические файлы и интерфейс swagger
+	r.Static("/docs", "./docs")
+	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler,
+		ginSwagger.URL("/docs/swagger.json"))) // swagger
+
+	// GraphQL: песни, группы и куплеты с пакетной загрузкой связанных данных
+	r.GET("/graphql", handlers.GraphQL)
+	r.POST("/graphql", handlers.GraphQL)
+
+	// Маршруты API: текущая версия и, для совместимости, устаревшие маршруты без префикса
+	registerAPIRoutes(r.Group(handlers.APIPrefix))
+	if cfg.API.LegacyRoutes {
+		registerLegacyRoutes(r.Group("", handlers.Deprecated(cfg.API.LegacySunsetTime())))
+	}
+
+	// Запуск сервера
+	port := strconv.Itoa(cfg.Server.Port)
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout.Std(),
+		ReadTimeout:       cfg.Server.ReadTimeout.Std(),
+		WriteTimeout:      cfg.Server.WriteTimeout.Std(),
+		IdleTimeout:       cfg.Server.IdleTimeout.Std(),
+	}
+
+	// Сервер gRPC на отдельном порту с теми же операциями, что и GraphQL
+	grpcSrv, err := startGRPC(cfg.GRPC.Port)
+	if err != nil {
+		return err
+	}
+
+	// При SIGINT или SIGTERM проверка готовности перестаёт проходить, через
+	// SERVER_SHUTDOWN_DELAY серверы HTTP и gRPC перестают принимать подключения
+	// и дожидаются текущих запросов, затем останавливаются фоновые обработчики и закрываются пул и кеш.
+	// На всё отводится SERVER_SHUTDOWN_TIMEOUT
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		// Повторный сигнал завершает процесс сразу
+		stop()
+
+		health.SetShuttingDown()
+		log.Infof("Shutting down: readiness is failing, waiting %s before closing listeners", cfg.Server.ShutdownDelay)
+		time.Sleep(cfg.Server.ShutdownDelay.Std())
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
+		defer cancel()
+
+		grpcStopped := make(chan struct{})
+		go func() {
+			defer close(grpcStopped)
+			if err := stopGRPC(shutdownCtx, grpcSrv); err != nil {
+				log.Errorf("In-flight gRPC calls did not finish in time and were cancelled: %v", err)
+			}
+		}()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Errorf("In-flight requests did not finish in time, closing connections: %v", err)
+			srv.Close()
+		} else {
+			log.Info("In-flight requests drained")
+		}
+		<-grpcStopped
+
+		stopWorkers()
+		select {
+		case <-dispatcherDone:
+		case <-shutdownCtx.Done():
+			log.Error("Webhook dispatcher did not stop in time")
+		}
+		if err := importer.GetManager().Shutdown(shutdownCtx); err != nil {
+			log.Errorf("Import job did not finish in time and was cancelled: %v", err)
+		} else {
+			log.Info("Import manager stopped")
+		}
+
+		if err := db.Close(); err != nil {
+			log.Errorf("Failed to close database pool: %v", err)
+		} else {
+			log.Info("Database pool closed")
+		}
+		if err := cache.Close(); err != nil {
+			log.Errorf("Failed to close read cache: %v", err)
+		}
+	}()
+
+	log.Info("Starting server on :" + port)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("starting server: %w", err)
+	}
+	<-stopped
+	log.Info("Server stopped")
+	return nil
+}
+
+// startGRPC запускает сервер gRPC с сервисом MusicLibrary и отражением на порту port.
+// При порте 0 сервер не запускается и возвращается nil
+func startGRPC(port int) (*grpc.Server, error) {
+	if port == 0 {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return nil, fmt.Errorf("starting gRPC server: %w", err)
+	}
+
+	srv := handlers.NewGRPCServer()
+
+	log := logger.GetLogger()
+	go func() {
+		log.Infof("Starting gRPC server on :%d", port)
+		if err := srv.Serve(listener); err != nil {
+			log.Errorf("gRPC server stopped: %v", err)
+		}
+	}()
+	return srv, nil
+}
+
+// stopGRPC дожидается завершения текущих вызовов gRPC, а по истечении ctx прерывает их
+func stopGRPC(ctx context.Context, srv *grpc.Server) error {
+	if srv == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.GracefulStop()
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		srv.Stop()
+		return ctx.Err()
+	}
+}
+
+// registerLegacyRoutes подключает к группе api маршруты, которые были до появления
+// версий API. Остальные маршруты доступны только с префиксом версии
+func registerLegacyRoutes(api *gin.RouterGroup) {
+	api.GET("/songs", handlers.GetSongs)             // Получение списка песен с фильтрацией и пагинацией
+	api.GET("/songs/:id/text", handlers.GetSongText) // Получение текста песни с пагинацией по куплетам
+	api.POST("/songs", handlers.AddSong)             // Добавление новой песни
+	api.PUT("/songs/:id", handlers.UpdateSong)       // Полная замена данных песни
+	api.DELETE("/songs/:id", handlers.DeleteSong)    // Удаление песни
+}
+
+// registerAPIRoutes подключает маршруты API к группе api
+func registerAPIRoutes(api *gin.RouterGroup) {
+	// Маршруты для работы с песнями
+	api.GET("/songs", handlers.GetSongs)             // Получение списка песен с фильтрацией и пагинацией
+	api.GET("/songs/:id", handlers.GetSong)          // Получение песни по ID
+	api.GET("/songs/:id/text", handlers.GetSongText) // Получение текста песни с пагинацией по куплетам
+	api.POST("/songs", handlers.AddSong)             // Добавление новой песни
+	api.PUT("/songs/:id", handlers.UpdateSong)       // Полная замена данных песни
+	api.PATCH("/songs/:id", handlers.PatchSong)      // Частичное изменение данных песни
+	api.DELETE("/songs/:id", handlers.DeleteSong)    // Удаление песни
+
+	// Маршруты для поиска и слияния дубликатов
+	api.GET("/songs/duplicates", handlers.GetDuplicates) // Отчёт о возможных дубликатах
+	api.POST("/songs/:id/merge", handlers.MergeSongs)    // Слияние дубликатов с песней
+
+	// Маршруты для пакетных и массовых операций
+	api.POST("/songs/batch", handlers.SongBatch) // Пакет операций добавления, изменения и удаления
+	api.PATCH("/songs", handlers.UpdateSongs)    // Изменение песен по фильтрам
+	api.DELETE("/songs", handlers.DeleteSongs)   // Удаление песен по фильтрам
+
+	// Маршруты для импорта и выгрузки песен
+	api.POST("/songs/import", handlers.ImportSongs)        // Запуск импорта песен из CSV или NDJSON
+	api.GET("/songs/import/:jobId", handlers.GetImportJob) // Получение состояния задания импорта
+	api.GET("/songs/export", handlers.ExportSongs)         // Потоковая выгрузка песен
+
+	// Маршруты для обмена плейлистами
+	api.GET("/songs/playlist", handlers.ExportPlaylist)         // Выгрузка песен в виде плейлиста M3U8, XSPF или JSPF
+	api.POST("/songs/playlist/import", handlers.ImportPlaylist) // Сопоставление плейлиста с песнями библиотеки
+
+	// Маршруты для управления вебхуками
+	api.POST("/webhooks", handlers.RegisterWebhook)                        // Регистрация вебхука
+	api.GET("/webhooks", handlers.GetWebhooks)                             // Получение списка вебхуков
+	api.POST("/webhooks/:id/test", handlers.TestWebhook)                   // Отправка тестового события
+	api.POST("/webhooks/:id/disable", handlers.DisableWebhook)             // Отключение вебхука
+	api.GET("/webhooks/dead-letters", handlers.GetDeadLetters)             // Доставки, исчерпавшие попытки
+	api.POST("/webhooks/dead-letters/:id/retry", handlers.RetryDeadLetter) // Повторная доставка
+
+	// Маршруты для управления группами
+	api.PATCH("/groups/:id", handlers.RenameGroup) // Переименование группы
+
+	// Поток событий изменения библиотеки
+	api.GET("/events", handlers.StreamEvents) // Server-Sent Events с возобновлением по Last-Event-ID
+}
@@ -15,7 +15,130 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/songs": {
+        "/api/v1/events": {
+            "get": {
+                "description": "Streams song and group change events (song.created, song.updated, song.deleted, song.merged, group.created, group.updated, group.deleted) as Server-Sent Events. Every event has the SSE id of its position in the persisted change log, which increases in commit order, the event type as the SSE event name and the event envelope as JSON data. Send Last-Event-ID (or the lastEventId query parameter) to resume after a reconnect and receive every event after that position; without it only new events are streamed. Events can be filtered by type and by group name (case-insensitive).",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Stream library change events",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "SSE id (change log position) of the last received event",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "SSE id of the last received event, for clients that cannot set headers",
+                        "name": "lastEventId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated event types to stream",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events of songs of this group and of the group itself",
+                        "name": "group",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream",
+                        "schema": {
+                            "$ref": "#/definitions/models.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid Last-Event-ID or unknown event type",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database or read the change log",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/groups/{id}": {
+            "patch": {
+                "description": "Renames the group. The group name is part of every song of the group, so the versions of its songs are incremented and a song.updated event is recorded for each of them along with group.updated. A name that matches another group after normalization (case, punctuation and spacing are ignored) is rejected. Groups are created with their first song and deleted with their last one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Rename a group",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New group name",
+                        "name": "group",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.GroupInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Renamed group",
+                        "schema": {
+                            "$ref": "#/definitions/models.Group"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid group ID or group data",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Group not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Another group with the same name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database or update group",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/songs": {
             "get": {
                 "description": "Retrieves a paginated list of songs with optional filtering based on group, song name, release date, text, and link",
                 "tags": [
@@ -72,14 +195,17 @@ const docTemplate = `{
                     "200": {
                         "description": "Songs retrieved successfully",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "$ref": "#/definitions/models.Page-models_Song"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "no-cache, or public with max-age from CACHE_MAX_AGE"
                             }
                         }
                     },
                     "400": {
-                        "description": "Invalid page or limit number",
+                        "description": "Invalid page, limit or release date",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -93,7 +219,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Adds a new song with details like group, song name, release date, text, and link",
+                "description": "Adds a new song with details like group, song name, release date, text, and link. A song whose group and title match an existing song after normalization (case, punctuation and spacing are ignored) is handled by SONGS_DUPLICATE_POLICY: reject answers 409 with Location of the existing song, return answers 200 with the existing song, allow adds another song",
                 "consumes": [
                     "application/json"
                 ],
@@ -106,89 +232,72 @@ const docTemplate = `{
                 "summary": "Add a new song to the library",
                 "parameters": [
                     {
-                        "description": "Song details",
+                        "description": "Group and song name",
                         "name": "song",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Song"
+                            "$ref": "#/definitions/models.NewSong"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the first response instead of repeating it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
+                    "200": {
+                        "description": "Song already exists and is returned as is",
+                        "schema": {
+                            "$ref": "#/definitions/models.Song"
+                        }
+                    },
                     "201": {
                         "description": "Song created successfully",
                         "schema": {
                             "$ref": "#/definitions/models.Song"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version tag of the created song"
+                            }
                         }
                     },
                     "400": {
                         "description": "Invalid input data",
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/models.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Failed to call external API or insert data into database",
+                    "409": {
+                        "description": "Song already exists or a request with the same Idempotency-Key is still in progress",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/songs/{id}": {
-            "put": {
-                "description": "Updates the song information by its ID. Only provided fields will be updated.",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "songs"
-                ],
-                "summary": "Update song details",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Song ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
                     },
-                    {
-                        "description": "Updated song data",
-                        "name": "song",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Song"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Song updated successfully",
+                    "413": {
+                        "description": "Request body is too large",
                         "schema": {
-                            "$ref": "#/definitions/models.Song"
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Invalid JSON data",
+                    "422": {
+                        "description": "Idempotency-Key has already been used with a different request",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Song not found",
+                    "500": {
+                        "description": "Failed to insert data into database",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to connect to database or update song",
+                    "503": {
+                        "description": "Failed to call external API",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -196,113 +305,2420 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Deletes a song from the database by its ID",
+                "description": "Deletes every song matching the filters (the same as in GET /songs, at least one is required) in one transaction. With dryRun=true nothing is deleted and the IDs of the songs that would be deleted are returned. At most 1000 songs",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
                     "songs"
                 ],
-                "summary": "Delete a song by ID",
+                "summary": "Delete songs matching filters",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Song ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "Group name for filtering",
+                        "name": "groupName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Song name for filtering",
+                        "name": "song",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Release date for filtering",
+                        "name": "releaseDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Text for filtering",
+                        "name": "text",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Link for filtering",
+                        "name": "link",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Only return the IDs of the songs that would be deleted",
+                        "name": "dryRun",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Song deleted successfully",
+                        "description": "Songs deleted, or would be deleted with dryRun",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.BulkResult"
                         }
                     },
-                    "404": {
-                        "description": "Song not found",
+                    "400": {
+                        "description": "No filters, invalid filter or too many songs",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to delete song or connect to database",
+                        "description": "Failed to connect to database or delete songs",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/songs/{id}/text": {
-            "get": {
-                "description": "Retrieves the song's text, paginated by verses, based on the song's ID",
+            },
+            "patch": {
+                "description": "Applies a JSON Merge Patch (application/merge-patch+json or application/json) or a JSON Patch (application/json-patch+json) to every song matching the filters (the same as in GET /songs, at least one is required) in one transaction: if the patch cannot be applied to a song or makes it invalid, no song is changed. With dryRun=true the changes are checked and rolled back, and the IDs of the songs that would change are returned. At most 1000 songs",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
                     "songs"
                 ],
-                "summary": "Get song text by verses with pagination",
+                "summary": "Update songs matching filters",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Song ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "Group name for filtering",
+                        "name": "groupName",
+                        "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
+                        "type": "string",
+                        "description": "Song name for filtering",
+                        "name": "song",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "default": 2,
-                        "description": "Number of verses per page",
-                        "name": "limit",
+                        "type": "string",
+                        "description": "Release date for filtering",
+                        "name": "releaseDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Text for filtering",
+                        "name": "text",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Link for filtering",
+                        "name": "link",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Only check the changes and return the affected IDs",
+                        "name": "dryRun",
                         "in": "query"
+                    },
+                    {
+                        "description": "Merge patch object or JSON Patch operations",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Song text retrieved successfully",
+                        "description": "Songs updated, or would be updated with dryRun",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.BulkResult"
                         }
                     },
                     "400": {
-                        "description": "Invalid page or limit number",
+                        "description": "No filters, invalid filter, malformed patch, invalid patched song or too many songs",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A patched song has the same group and title as another song",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Song not found or no verses on this page",
+                    "415": {
+                        "description": "Unsupported patch format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Patch cannot be applied to a song",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to connect to database or retrieve song text",
+                        "description": "Failed to connect to database or update songs",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
+        },
+        "/api/v1/songs/batch": {
+            "post": {
+                "description": "Runs create, update and delete operations in the given order. In atomic mode (default) all operations run in one transaction: if one fails, none is applied and the response status is the status of the failed operation, other operations get 424. In best-effort mode every operation runs in its own transaction and the response status is 200 whatever the results. Every result has the status a single request would get: create - like POST /songs with the full song, update - like PUT /songs/{id}, delete - like DELETE /songs/{id}. version replaces If-Match. At most 1000 operations",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "songs"
+                ],
+                "summary": "Run a batch of song operations",
+                "parameters": [
+                    {
+                        "description": "Mode and operations",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SongBatch"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the first response instead of repeating it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Results of the operations",
+                        "schema": {
+                            "$ref": "#/definitions/models.SongBatchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid batch, or an operation of an atomic batch has invalid data",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "A song of an atomic batch not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.SongBatchResult"
+                        }
+                    },
+                    "409": {
+                        "description": "An operation of an atomic batch conflicts with existing songs",
+                        "schema": {
+                            "$ref": "#/definitions/models.SongBatchResult"
+                        }
+                    },
+                    "412": {
+                        "description": "A song of an atomic batch has been modified",
+                        "schema": {
+                            "$ref": "#/definitions/models.SongBatchResult"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key has already been used with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database or run the batch",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/songs/duplicates": {
+            "get": {
+                "description": "Finds sets of songs of the same group (group names are compared after normalization) that are likely duplicates: their titles match after normalization (case, punctuation and spacing are ignored) or the share of common words in their lyrics is at least minSimilarity. Sets are ordered by the lowest song ID, songs in a set by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "songs"
+                ],
+                "summary": "Report likely duplicate songs",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "default": 0.8,
+                        "description": "Minimum lyric similarity from 0 (exclusive) to 1",
+                        "name": "minSimilarity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of sets per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Duplicate sets retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_DuplicateSet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page, limit or minSimilarity",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database or retrieve songs",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/songs/export": {
+            "get": {
+                "description": "Streams all songs matching the same filters as the songs list, ordered by ID, as CSV (columns id, group, song, releaseDate, text, link), NDJSON or a JSON array. CSV and NDJSON exports can be imported back via /songs/import.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/json",
+                    "application/gzip"
+                ],
+                "tags": [
+                    "import"
+                ],
+                "summary": "Export songs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Export format: csv, ndjson or json",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Compress the export with gzip",
+                        "name": "gzip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Group name for filtering",
+                        "name": "groupName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Song name for filtering",
+                        "name": "song",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Release date for filtering",
+                        "name": "releaseDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Text for filtering",
+                        "name": "text",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Link for filtering",
+                        "name": "link",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Songs export",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported format, invalid gzip parameter or release date",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database or retrieve songs",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/songs/import": {
+            "post": {
+                "description": "Uploads a CSV file (header with group, song and optional releaseDate, text, link columns) or NDJSON (one song object per line) and starts a background import job. Rows are validated and deduplicated against existing songs by group and song name; with enrich=true missing fields are requested from the external API.",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "import"
+                ],
+                "summary": "Import songs from CSV or NDJSON",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File format: csv or ndjson. Detected from Content-Type if omitted",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Fill in missing fields from the external API",
+                        "name": "enrich",
+                        "in": "query"
+                    },
+                    {
+                        "description": "CSV or NDJSON file contents",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the first response instead of repeating it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Import job accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportJob"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the import job status"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported format or empty file",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "File is too large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key has already been used with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to store the uploaded file",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Import queue is full",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/songs/import/{jobId}": {
+            "get": {
+                "description": "Returns the import job status, counters of created, duplicate and failed rows and of rows that could not be enriched, and a page of row results in file order. Failed rows and rows with a warning carry the group, the song and the error details; other rows carry only the row number, the status and the song ID.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "import"
+                ],
+                "summary": "Get import job status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Import job ID",
+                        "name": "jobId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "created",
+                            "duplicate",
+                            "failed",
+                            "warning"
+                        ],
+                        "type": "string",
+                        "description": "Row status filter",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Number of rows per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import job status",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid status filter or pagination parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Import job not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/songs/playlist": {
+            "get": {
+                "description": "Streams the songs matching the songs list filters, or the songs with the given IDs in the given order, as an extended M3U8, XSPF or JSPF playlist. Each track has the group as creator, the song name as title and the song link as location. M3U8 requires a location for every track, so songs without a link get the about:blank placeholder in that format.",
+                "produces": [
+                    "audio/x-mpegurl",
+                    "application/xspf+xml",
+                    "application/jspf+json"
+                ],
+                "tags": [
+                    "playlists"
+                ],
+                "summary": "Export songs as a playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "m3u8",
+                        "description": "Playlist format: m3u8, xspf or jspf",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Playlist title",
+                        "name": "title",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Playlist creator",
+                        "name": "creator",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated song IDs; the playlist keeps this order",
+                        "name": "ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Group name for filtering",
+                        "name": "groupName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Song name for filtering",
+                        "name": "song",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Release date for filtering",
+                        "name": "releaseDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Text for filtering",
+                        "name": "text",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Link for filtering",
+                        "name": "link",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Playlist",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported format, invalid song IDs or release date",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database or retrieve songs",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/songs/playlist/import": {
+            "post": {
+                "description": "Reads an M3U8, XSPF or JSPF playlist and matches each track to an existing song by group (creator) and song name (title), case-insensitively, falling back to the song link (location). With create=true tracks that have a creator and a title but no matching song are added to the library. The report lists the result of every track.",
+                "consumes": [
+                    "audio/x-mpegurl",
+                    "application/xspf+xml",
+                    "application/jspf+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlists"
+                ],
+                "summary": "Import a playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist format: m3u8, xspf or jspf. Detected from Content-Type if omitted",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Create songs for unmatched tracks",
+                        "name": "create",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Playlist file contents",
+                        "name": "playlist",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the first response instead of repeating it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import report",
+                        "schema": {
+                            "$ref": "#/definitions/models.PlaylistImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported format or malformed playlist",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key has already been used with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/songs/{id}": {
+            "get": {
+                "description": "Retrieves the full song resource including group, release date, text, link and version",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "songs"
+                ],
+                "summary": "Get a song by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached version of the song",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Song retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.Song"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "no-cache, or public with max-age from CACHE_MAX_AGE"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version tag of the song"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Song has not been modified"
+                    },
+                    "308": {
+                        "description": "Song was merged into another song, Location points to the same resource of that song",
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Path of the song the requested song was merged into"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid song ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Song not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database or retrieve song",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replaces the whole song resource by its ID. All required fields must be provided, omitted optional fields are cleared.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "songs"
+                ],
+                "summary": "Replace song details",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Full song data",
+                        "name": "song",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SongInput"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the song version being updated",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Song updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.Song"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version tag of the updated song"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid song ID or song data",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Song not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Another song with the same group and title already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Song has been modified",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is required",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database or update song",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a song from the database by its ID",
+                "tags": [
+                    "songs"
+                ],
+                "summary": "Delete a song by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the song version being deleted",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Song deleted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid song ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Song not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Song has been modified",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is required",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete song or connect to database",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Applies a JSON Merge Patch (RFC 7396, application/merge-patch+json or application/json) or a JSON Patch (RFC 6902, application/json-patch+json) to the song. The patched song must remain valid.",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "songs"
+                ],
+                "summary": "Partially update song details",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch object or JSON Patch operations",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the song version being updated",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Song updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.Song"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version tag of the updated song"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid song ID, malformed patch or invalid patched song",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Song not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Another song with the same group and title already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Song has been modified",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body is too large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported patch format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Patch cannot be applied to the song",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is required",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database or update song",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/songs/{id}/merge": {
+            "post": {
+                "description": "Keeps the song with the given ID as the canonical one and deletes the duplicates in one transaction. Empty release date, text and link of the canonical song are filled from the duplicates in the given order. Requests for a deleted duplicate (GET /songs/{id} and /songs/{id}/text) are redirected to the canonical song with 308 Permanent Redirect. A song.deleted event is recorded for every duplicate and a song.merged event for the merge",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "songs"
+                ],
+                "summary": "Merge duplicate songs into a song",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID of the song to keep",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "IDs of the duplicates to merge",
+                        "name": "merge",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MergeSongs"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version of the song to keep",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the first response instead of repeating it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Songs merged successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.Song"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version tag of the song"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid song ID or duplicate IDs",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Song or duplicate not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Song has been modified",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key has already been used with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match header is required",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database or merge songs",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/songs/{id}/text": {
+            "get": {
+                "description": "Retrieves the song's text, paginated by verses, based on the song's ID",
+                "tags": [
+                    "songs"
+                ],
+                "summary": "Get song text by verses with pagination",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Song ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 2,
+                        "description": "Number of verses per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached version of the song",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Song text retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_Verse"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "no-cache, or public with max-age from CACHE_MAX_AGE"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version tag of the song"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Song has not been modified"
+                    },
+                    "308": {
+                        "description": "Song was merged into another song, Location points to the same resource of that song",
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Path of the song the requested song was merged into"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid song ID, page or limit number",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Song not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database or retrieve song text",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks": {
+            "get": {
+                "description": "Returns all registered webhooks without their secrets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhooks",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Number of webhooks per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhooks",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page or limit number",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to retrieve webhooks or connect to database",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Subscribes a URL to library change events: song.created, song.updated, song.deleted, song.merged, group.created, group.updated and group.deleted. An empty eventTypes list subscribes to all events. Every delivery is a POST with the event as JSON body, signed with HMAC-SHA256 of the body using the webhook secret in the X-Webhook-Signature-256 header (\"sha256=\u003chex\u003e\"). If no secret is given one is generated; the secret is returned only in this response. Idempotency-Key is ignored, so that the response with the secret is never stored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Register a webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook details",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Webhook registered",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input data",
+                        "schema": {
+                            "$ref": "#/definitions/models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to save webhook or connect to database",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/dead-letters": {
+            "get": {
+                "description": "Returns webhook deliveries that failed after the maximum number of attempts, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List dead-lettered deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Only deliveries of this webhook",
+                        "name": "webhookId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Number of deliveries per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Dead-lettered deliveries",
+                        "schema": {
+                            "$ref": "#/definitions/models.Page-models_WebhookDelivery"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook ID, page or limit number",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to retrieve deliveries or connect to database",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/dead-letters/{id}/retry": {
+            "post": {
+                "description": "Resets the attempt counter of a dead-lettered delivery and schedules it for immediate delivery",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Retry a dead-lettered delivery",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the first response instead of repeating it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Delivery scheduled",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid delivery ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Dead-lettered delivery not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key has already been used with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update delivery or connect to database",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}/disable": {
+            "post": {
+                "description": "Stops delivering events to the webhook. Pending deliveries are kept but not sent while the webhook is disabled.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Disable a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the first response instead of repeating it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Disabled webhook",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key has already been used with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update webhook or connect to database",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}/test": {
+            "post": {
+                "description": "Synchronously sends a signed webhook.test event to the webhook URL and reports the result. The test event is not stored and not retried.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Send a test event to a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the first response instead of repeating it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Test delivery result",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookTestResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key has already been used with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to connect to database",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/graphql": {
+            "get": {
+                "description": "Executes a GraphQL query or mutation over songs, groups and verse pages. Queries may be sent with GET (query, operationName and variables parameters) or POST, mutations only with POST. Queries deeper or more complex than the configured limits are rejected with 400 before execution.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "Execute a GraphQL query",
+                "parameters": [
+                    {
+                        "description": "GraphQL request",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GraphQLRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "GraphQL query for GET requests",
+                        "name": "query",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operation to execute",
+                        "name": "operationName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON-encoded variables",
+                        "name": "variables",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the first response instead of repeating it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Query executed; field errors are reported in errors",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GraphQLResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed query or query limits exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GraphQLResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Mutation sent with GET",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GraphQLResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key has already been used with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Executes a GraphQL query or mutation over songs, groups and verse pages. Queries may be sent with GET (query, operationName and variables parameters) or POST, mutations only with POST. Queries deeper or more complex than the configured limits are rejected with 400 before execution.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "Execute a GraphQL query",
+                "parameters": [
+                    {
+                        "description": "GraphQL request",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GraphQLRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "GraphQL query for GET requests",
+                        "name": "query",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operation to execute",
+                        "name": "operationName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON-encoded variables",
+                        "name": "variables",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key that makes retries of this request return the first response instead of repeating it",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Query executed; field errors are reported in errors",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GraphQLResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed query or query limits exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GraphQLResponse"
+                        }
+                    },
+                    "405": {
+                        "description": "Mutation sent with GET",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GraphQLResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A request with the same Idempotency-Key is still in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key has already been used with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Returns 200 while the process is running and able to serve HTTP. Dependencies are not checked, so a database outage does not restart the service",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "Service is alive",
+                        "schema": {
+                            "$ref": "#/definitions/models.Liveness"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Checks that the database is reachable and the schema is at the expected migration version. An unreachable music API only degrades the service and keeps it ready. Returns 503 when a required dependency fails or the service is shutting down",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "Service is ready (status ok or degraded)",
+                        "schema": {
+                            "$ref": "#/definitions/models.Readiness"
+                        }
+                    },
+                    "503": {
+                        "description": "Service is not ready or is shutting down",
+                        "schema": {
+                            "$ref": "#/definitions/models.Readiness"
+                        }
+                    }
+                }
+            }
+        },
+        "/status": {
+            "get": {
+                "description": "Reports build info, uptime, the current and expected migration versions and the latency of every dependency check. Always returns 200; the overall status is in the body",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Service status",
+                "responses": {
+                    "200": {
+                        "description": "Service status",
+                        "schema": {
+                            "$ref": "#/definitions/models.ServiceStatus"
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
+        "handlers.GraphQLRequest": {
+            "type": "object",
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string",
+                    "example": "{ songs(limit: 5) { items { id song group { name } } } }"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "handlers.GraphQLResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "object"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "object"
+                    }
+                }
+            }
+        },
+        "models.BuildInfo": {
+            "type": "object",
+            "properties": {
+                "buildTime": {
+                    "type": "string",
+                    "example": "2024-05-01T12:00:00Z"
+                },
+                "commit": {
+                    "type": "string",
+                    "example": "91843fd"
+                },
+                "goVersion": {
+                    "type": "string",
+                    "example": "go1.22.9"
+                },
+                "version": {
+                    "type": "string",
+                    "example": "v1.4.0"
+                }
+            }
+        },
+        "models.BulkResult": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "dryRun": {
+                    "description": "DryRun - изменения только проверены и не сохранены",
+                    "type": "boolean",
+                    "example": false
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        3,
+                        8
+                    ]
+                }
+            }
+        },
+        "models.CheckResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "latencyMs": {
+                    "type": "number",
+                    "example": 1.25
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "models.DuplicateSet": {
+            "type": "object",
+            "properties": {
+                "reasons": {
+                    "description": "Reasons - признаки сходства: title - совпадают нормализованные названия,\nlyrics - тексты похожи не меньше порога minSimilarity",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "title",
+                        "lyrics"
+                    ]
+                },
+                "similarity": {
+                    "description": "Similarity - наибольшее сходство текстов песен набора от 0 до 1",
+                    "type": "number",
+                    "example": 0.93
+                },
+                "songs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Song"
+                    }
+                }
+            }
+        },
         "models.ErrorResponse": {
             "type": "object",
             "properties": {
-                "error": {
+                "code": {
+                    "type": "string",
+                    "example": "not_found"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Song not found"
+                },
+                "requestId": {
+                    "type": "string",
+                    "example": "4bf92f3577b34da6a3ce929d0e0e4736"
+                }
+            }
+        },
+        "models.Event": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Group": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.GroupInput": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "Muse"
+                }
+            }
+        },
+        "models.ImportJob": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "duplicates": {
+                    "type": "integer"
+                },
+                "enrich": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "rows": {
+                    "$ref": "#/definitions/models.Page-models_ImportRowResult"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "warnings": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ImportRowResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "group": {
+                    "type": "string"
+                },
+                "row": {
+                    "type": "integer"
+                },
+                "song": {
+                    "type": "string"
+                },
+                "songId": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "warning": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Liveness": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "models.MergeSongs": {
+            "type": "object",
+            "properties": {
+                "duplicateIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        12,
+                        31
+                    ]
+                }
+            }
+        },
+        "models.Message": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Song deleted successfully"
+                }
+            }
+        },
+        "models.MigrationStatus": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "dirty": {
+                    "type": "boolean"
+                },
+                "expected": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "models.NewSong": {
+            "type": "object",
+            "properties": {
+                "group": {
+                    "type": "string",
+                    "example": "Muse"
+                },
+                "song": {
+                    "type": "string",
+                    "example": "Supermassive Black Hole"
+                }
+            }
+        },
+        "models.Page-models_DuplicateSet": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DuplicateSet"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/models.PageLinks"
+                },
+                "pagination": {
+                    "$ref": "#/definitions/models.Pagination"
+                }
+            }
+        },
+        "models.Page-models_ImportRowResult": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportRowResult"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/models.PageLinks"
+                },
+                "pagination": {
+                    "$ref": "#/definitions/models.Pagination"
+                }
+            }
+        },
+        "models.Page-models_Song": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Song"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/models.PageLinks"
+                },
+                "pagination": {
+                    "$ref": "#/definitions/models.Pagination"
+                }
+            }
+        },
+        "models.Page-models_Verse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/models.PageLinks"
+                },
+                "pagination": {
+                    "$ref": "#/definitions/models.Pagination"
+                }
+            }
+        },
+        "models.Page-models_Webhook": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Webhook"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/models.PageLinks"
+                },
+                "pagination": {
+                    "$ref": "#/definitions/models.Pagination"
+                }
+            }
+        },
+        "models.Page-models_WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookDelivery"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/models.PageLinks"
+                },
+                "pagination": {
+                    "$ref": "#/definitions/models.Pagination"
+                }
+            }
+        },
+        "models.PageLinks": {
+            "type": "object",
+            "properties": {
+                "first": {
+                    "type": "string",
+                    "example": "/api/v1/songs?limit=10\u0026page=1"
+                },
+                "last": {
+                    "type": "string",
+                    "example": "/api/v1/songs?limit=10\u0026page=5"
+                },
+                "next": {
+                    "type": "string",
+                    "example": "/api/v1/songs?limit=10\u0026page=3"
+                },
+                "prev": {
+                    "type": "string",
+                    "example": "/api/v1/songs?limit=10\u0026page=1"
+                },
+                "self": {
+                    "type": "string",
+                    "example": "/api/v1/songs?limit=10\u0026page=2"
+                }
+            }
+        },
+        "models.Pagination": {
+            "type": "object",
+            "properties": {
+                "hasMore": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "models.PlaylistImportReport": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "creator": {
+                    "type": "string"
+                },
+                "matched": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "tracks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PlaylistTrackResult"
+                    }
+                },
+                "unmatched": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.PlaylistTrackResult": {
+            "type": "object",
+            "properties": {
+                "creator": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "location": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "songId": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Readiness": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.CheckResult"
+                    }
+                },
+                "shuttingDown": {
+                    "type": "boolean"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "models.ServiceStatus": {
+            "type": "object",
+            "properties": {
+                "build": {
+                    "$ref": "#/definitions/models.BuildInfo"
+                },
+                "dependencies": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.CheckResult"
+                    }
+                },
+                "migrations": {
+                    "$ref": "#/definitions/models.MigrationStatus"
+                },
+                "shuttingDown": {
+                    "type": "boolean"
+                },
+                "startedAt": {
                     "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                },
+                "uptimeSeconds": {
+                    "type": "number",
+                    "example": 3600
                 }
             }
         },
@@ -326,6 +2742,221 @@ const docTemplate = `{
                 },
                 "text": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SongBatch": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "description": "Mode - atomic (по умолчанию) или best-effort",
+                    "type": "string",
+                    "enum": [
+                        "atomic",
+                        "best-effort"
+                    ],
+                    "example": "atomic"
+                },
+                "operations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SongOperation"
+                    }
+                }
+            }
+        },
+        "models.SongBatchResult": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "mode": {
+                    "type": "string",
+                    "example": "atomic"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SongOperationResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "models.SongInput": {
+            "type": "object",
+            "properties": {
+                "group": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "releaseDate": {
+                    "type": "string"
+                },
+                "song": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SongOperation": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ],
+                    "example": "update"
+                },
+                "song": {
+                    "type": "object"
+                },
+                "version": {
+                    "description": "Version - ожидаемая версия песни для update и delete, заменяет If-Match",
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "models.SongOperationResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/models.ErrorResponse"
+                },
+                "errors": {
+                    "description": "Errors - некорректные поля песни",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "op": {
+                    "type": "string",
+                    "example": "update"
+                },
+                "song": {
+                    "$ref": "#/definitions/models.Song"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 200
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "eventTypes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "Secret возвращается только при регистрации вебхука",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "eventId": {
+                    "type": "integer"
+                },
+                "eventType": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "lastStatusCode": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "webhookId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WebhookInput": {
+            "type": "object",
+            "properties": {
+                "eventTypes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookTestResult": {
+            "type": "object",
+            "properties": {
+                "delivered": {
+                    "type": "boolean"
+                },
+                "durationMs": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "statusCode": {
+                    "type": "integer"
                 }
             }
         }
@@ -0,0 +1,71 @@
+// Package cache - хранилища кеша чтения: LRU в памяти процесса с временем жизни
+// записей и Redis-совместимый сервер, общий для всех экземпляров сервиса.
+// Хранилище выбирается настройкой CACHE_BACKEND
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/logger"
+)
+
+// Cache - хранилище записей кеша. Значения хранятся как байты, чтобы их можно
+// было передать внешнему хранилищу
+type Cache interface {
+	// Get возвращает значение ключа key и true или false, если ключа нет или он просрочен
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set сохраняет значение ключа key на время ttl. При ttl 0 ключ не просрочивается
+	// и удаляется только при вытеснении
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete удаляет ключи keys. Отсутствующие ключи пропускаются
+	Delete(ctx context.Context, keys ...string) error
+	// Close освобождает ресурсы хранилища
+	Close() error
+}
+
+var (
+	shared   Cache
+	sharedMu sync.Mutex
+	opened   bool
+)
+
+// Get возвращает общее хранилище кеша из настроек, создавая его при первом вызове,
+// или nil, если кеш отключён (CACHE_BACKEND=none). Хранилище закрывается один раз
+// при остановке сервиса через Close
+func Get() Cache {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if opened {
+		return shared
+	}
+
+	cfg := config.Get().Cache
+	switch cfg.Backend {
+	case config.CacheMemory:
+		shared = NewMemory(cfg.Size)
+	case config.CacheRedis:
+		shared = NewRedis(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+	}
+	opened = true
+
+	logger.GetLogger().Infof("Read cache backend: %s", cfg.Backend)
+	return shared
+}
+
+// Close закрывает общее хранилище кеша, если оно было создано
+func Close() error {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if shared == nil {
+		return nil
+	}
+	err := shared.Close()
+	shared = nil
+	opened = false
+	return err
+}
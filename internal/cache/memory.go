@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory - LRU-кеш в памяти процесса. Когда записей становится больше size,
+// вытесняется запись, которая дольше всех не читалась и не сохранялась.
+// Просроченные записи удаляются при чтении или вытеснении
+type Memory struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// memoryEntry - запись LRU-кеша. Нулевой expires означает запись без срока
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemory создаёт LRU-кеш не больше чем на size записей
+func NewMemory(size int) *Memory {
+	return &Memory{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && !time.Now().Before(entry.expires) {
+		m.remove(element)
+		return nil, false, nil
+	}
+	m.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+
+	if element, ok := m.entries[key]; ok {
+		entry := element.Value.(*memoryEntry)
+		entry.value, entry.expires = value, expires
+		m.order.MoveToFront(element)
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryEntry{key: key, value: value, expires: expires})
+	for m.order.Len() > m.size {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := m.entries[key]; ok {
+			m.remove(element)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.order.Init()
+	m.entries = make(map[string]*list.Element)
+	return nil
+}
+
+// remove удаляет запись element. Вызывается под m.mu
+func (m *Memory) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.entries, element.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// keyPrefix отделяет ключи сервиса от чужих ключей в общей базе Redis
+	keyPrefix = "music-service:"
+	// redisTimeout - таймаут подключения и одной команды. Он короче обычного:
+	// недоступный кеш не должен задерживать чтение из базы
+	redisTimeout = 500 * time.Millisecond
+)
+
+// Redis - кеш на Redis-совместимом сервере, общий для всех экземпляров сервиса.
+// Время жизни записей и вытеснение обеспечивает сам сервер
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis создаёт кеш на сервере addr с базой db. Подключение устанавливается
+// при первом обращении, поэтому недоступный при запуске сервер не мешает запуску
+func NewRedis(addr, password string, db int) *Redis {
+	return &Redis{client: redis.NewClient(&redis.Options{
+		Addr:         addr,
+		Password:     password,
+		DB:           db,
+		DialTimeout:  redisTimeout,
+		ReadTimeout:  redisTimeout,
+		WriteTimeout: redisTimeout,
+		MaxRetries:   -1,
+	})}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, keyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, keyPrefix+key, value, ttl).Err()
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = keyPrefix + key
+	}
+	return r.client.Del(ctx, prefixed...).Err()
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,371 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config - настройки сервиса. Значения берутся по порядку из значений по умолчанию,
+// YAML-файла, файла .env и переменных окружения: каждый следующий источник
+// переопределяет предыдущий. Тег env задаёт имя переменной окружения (и ключа .env),
+// тег yaml - ключ в YAML-файле, тег secret помечает значения, скрываемые при выводе
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	API         APIConfig         `yaml:"api"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Log         LogConfig         `yaml:"log"`
+	Songs       SongsConfig       `yaml:"songs"`
+	MusicAPI    MusicAPIConfig    `yaml:"musicApi"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Cache       CacheConfig       `yaml:"cache"`
+	GraphQL     GraphQLConfig     `yaml:"graphql"`
+	GRPC        GRPCConfig        `yaml:"grpc"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+}
+
+// ServerConfig - настройки HTTP-сервера. Таймаут записи не действует на потоковые
+// ответы (выгрузку песен и плейлистов, поток событий)
+type ServerConfig struct {
+	Port              int      `yaml:"port" env:"SERVER_PORT"`
+	ReadHeaderTimeout Duration `yaml:"readHeaderTimeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       Duration `yaml:"readTimeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout      Duration `yaml:"writeTimeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       Duration `yaml:"idleTimeout" env:"SERVER_IDLE_TIMEOUT"`
+	// ShutdownDelay - сколько после сигнала остановки сервер продолжает принимать запросы,
+	// чтобы балансировщик успел заметить непрошедшую проверку готовности
+	ShutdownDelay Duration `yaml:"shutdownDelay" env:"SERVER_SHUTDOWN_DELAY"`
+	// ShutdownTimeout - сколько ждать завершения текущих запросов и фоновых заданий
+	ShutdownTimeout Duration `yaml:"shutdownTimeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+// APIConfig - версии API. Маршруты без префикса /api/v1 оставлены для совместимости
+// и отвечают с заголовками Deprecation и Sunset
+type APIConfig struct {
+	LegacyRoutes bool `yaml:"legacyRoutes" env:"API_LEGACY_ROUTES"`
+	// LegacySunset - дата отключения маршрутов без префикса в формате 2006-01-02
+	LegacySunset string `yaml:"legacySunset" env:"API_LEGACY_SUNSET"`
+}
+
+// LegacySunsetTime возвращает дату отключения маршрутов без префикса
+func (a APIConfig) LegacySunsetTime() time.Time {
+	sunset, _ := time.Parse(time.DateOnly, a.LegacySunset)
+	return sunset
+}
+
+// DatabaseConfig - подключение к PostgreSQL. Если задан URL, он используется
+// целиком, иначе строка подключения собирается из отдельных параметров
+type DatabaseConfig struct {
+	URL          string `yaml:"url" env:"DATABASE_URL" secret:"true"`
+	Host         string `yaml:"host" env:"DB_HOST"`
+	Port         int    `yaml:"port" env:"DB_PORT"`
+	User         string `yaml:"user" env:"DB_USER"`
+	Password     string `yaml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name         string `yaml:"name" env:"DB_NAME"`
+	SSLMode      string `yaml:"sslMode" env:"DB_SSLMODE"`
+	MaxOpenConns int    `yaml:"maxOpenConns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns int    `yaml:"maxIdleConns" env:"DB_MAX_IDLE_CONNS"`
+	// TxIsolation - уровень изоляции транзакций: read committed, repeatable read или serializable
+	TxIsolation string `yaml:"txIsolation" env:"DB_TX_ISOLATION"`
+	// TxMaxRetries - сколько раз повторяется транзакция после ошибки сериализации или взаимоблокировки
+	TxMaxRetries int `yaml:"txMaxRetries" env:"DB_TX_MAX_RETRIES"`
+}
+
+// LogConfig - настройки лога
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+}
+
+// SongsConfig - настройки работы с песнями
+type SongsConfig struct {
+	RequireIfMatch bool  `yaml:"requireIfMatch" env:"REQUIRE_IF_MATCH"`
+	ImportMaxBytes int64 `yaml:"importMaxBytes" env:"IMPORT_MAX_BYTES"`
+	// DuplicatePolicy - что делать с песней, группа и название которой после нормализации
+	// совпадают с уже добавленной: reject - отклонить, return - вернуть существующую,
+	// allow - добавить ещё одну
+	DuplicatePolicy string `yaml:"duplicatePolicy" env:"SONGS_DUPLICATE_POLICY"`
+}
+
+// Политики добавления дубликатов песен
+const (
+	DuplicateReject = "reject"
+	DuplicateReturn = "return"
+	DuplicateAllow  = "allow"
+)
+
+// MusicAPIConfig - внешний API с информацией о песнях
+type MusicAPIConfig struct {
+	URL string `yaml:"url" env:"MUSIC_API_URL"`
+}
+
+// WebhooksConfig - доставка событий вебхукам
+type WebhooksConfig struct {
+	DispatchInterval Duration `yaml:"dispatchInterval" env:"WEBHOOK_DISPATCH_INTERVAL"`
+	Timeout          Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT"`
+	MaxAttempts      int      `yaml:"maxAttempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	// AllowPrivateNetworks разрешает доставку на адреса внутренних сетей: закрытые,
+	// loopback и link-local. По умолчанию такие адреса отклоняются при доставке
+	AllowPrivateNetworks bool `yaml:"allowPrivateNetworks" env:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"`
+}
+
+// IdempotencyConfig - ключи идемпотентности POST-запросов (заголовок Idempotency-Key).
+// TTL - сколько хранится ответ на первый запрос с ключом
+type IdempotencyConfig struct {
+	TTL Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL"`
+}
+
+// CacheConfig - кеш чтения песен и страниц списка песен
+type CacheConfig struct {
+	// Backend - memory (LRU в памяти процесса), redis (Redis-совместимый сервер,
+	// общий для всех экземпляров сервиса) или none
+	Backend string `yaml:"backend" env:"CACHE_BACKEND"`
+	// TTL - сколько хранится запись кеша. Он же ограничивает, как долго другой экземпляр
+	// сервиса с кешем в памяти может отдавать устаревшие данные
+	TTL Duration `yaml:"ttl" env:"CACHE_TTL"`
+	// Size - наибольшее число записей кеша в памяти
+	Size          int    `yaml:"size" env:"CACHE_SIZE"`
+	RedisAddr     string `yaml:"redisAddr" env:"CACHE_REDIS_ADDR"`
+	RedisPassword string `yaml:"redisPassword" env:"CACHE_REDIS_PASSWORD" secret:"true"`
+	RedisDB       int    `yaml:"redisDb" env:"CACHE_REDIS_DB"`
+	// MaxAge - max-age заголовка Cache-Control ответов на чтение песен. 0 - no-cache:
+	// клиент должен проверять актуальность ответа по ETag
+	MaxAge Duration `yaml:"maxAge" env:"CACHE_MAX_AGE"`
+}
+
+// Хранилища кеша
+const (
+	CacheMemory = "memory"
+	CacheRedis  = "redis"
+	CacheNone   = "none"
+)
+
+// GraphQLConfig - ограничения запросов /graphql. Сложность запроса - число
+// запрашиваемых полей, где поля списков умножаются на размер страницы
+type GraphQLConfig struct {
+	MaxDepth      int `yaml:"maxDepth" env:"GRAPHQL_MAX_DEPTH"`
+	MaxComplexity int `yaml:"maxComplexity" env:"GRAPHQL_MAX_COMPLEXITY"`
+}
+
+// GRPCConfig - сервер gRPC, работающий рядом с HTTP-сервером. Порт 0 отключает его
+type GRPCConfig struct {
+	Port int `yaml:"port" env:"GRPC_PORT"`
+}
+
+// TracingConfig - экспорт трассировок OpenTelemetry. Адрес коллектора и сэмплирование
+// задаются стандартными переменными OTEL_EXPORTER_OTLP_* и OTEL_TRACES_SAMPLER*
+type TracingConfig struct {
+	Exporter    string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	ServiceName string `yaml:"serviceName" env:"OTEL_SERVICE_NAME"`
+}
+
+// Default возвращает настройки по умолчанию
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadHeaderTimeout: Duration(10 * time.Second),
+			ReadTimeout:       Duration(5 * time.Minute),
+			WriteTimeout:      Duration(time.Minute),
+			IdleTimeout:       Duration(2 * time.Minute),
+			ShutdownTimeout:   Duration(20 * time.Second),
+		},
+		API: APIConfig{LegacyRoutes: true, LegacySunset: "2027-06-30"},
+		Database: DatabaseConfig{
+			Port:         5432,
+			SSLMode:      "disable",
+			MaxOpenConns: 25,
+			MaxIdleConns: 5,
+			TxIsolation:  "read committed",
+			TxMaxRetries: 3,
+		},
+		Log:      LogConfig{Level: "info", Format: "text"},
+		Songs:    SongsConfig{ImportMaxBytes: 100 << 20, DuplicatePolicy: DuplicateAllow},
+		MusicAPI: MusicAPIConfig{URL: "http://music-api:8080"},
+		Webhooks: WebhooksConfig{
+			DispatchInterval: Duration(2 * time.Second),
+			Timeout:          Duration(10 * time.Second),
+			MaxAttempts:      8,
+		},
+		Idempotency: IdempotencyConfig{TTL: Duration(24 * time.Hour)},
+		Cache:       CacheConfig{Backend: CacheMemory, TTL: Duration(time.Minute), Size: 10000},
+		GraphQL:     GraphQLConfig{MaxDepth: 8, MaxComplexity: 5000},
+		GRPC:        GRPCConfig{Port: 9090},
+		Tracing:     TracingConfig{Exporter: "none", ServiceName: "music-service"},
+	}
+}
+
+var (
+	current   *Config
+	currentMu sync.Mutex
+)
+
+// Load загружает настройки из всех источников и проверяет их. path - путь к
+// необязательному YAML-файлу; если он пуст, берётся из CONFIG_FILE. При ошибках
+// проверки возвращаются загруженные настройки и Errors со всеми ошибками сразу.
+// Успешно загруженные настройки становятся текущими и доступны через Get
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	var errs Errors
+
+	dotenv, err := godotenv.Read()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		errs = append(errs, FieldError{Name: ".env", Message: err.Error()})
+	}
+	lookup := func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		value, ok := dotenv[name]
+		return value, ok
+	}
+
+	if path == "" {
+		path, _ = lookup("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadYAML(cfg, path); err != nil {
+			errs = append(errs, FieldError{Name: path, Message: err.Error()})
+		}
+	}
+
+	errs = append(errs, applyEnv(reflect.ValueOf(cfg).Elem(), lookup)...)
+	errs = append(errs, cfg.validate()...)
+
+	if len(errs) > 0 {
+		return cfg, errs
+	}
+
+	currentMu.Lock()
+	current = cfg
+	currentMu.Unlock()
+	return cfg, nil
+}
+
+// Get возвращает текущие настройки. Если Load ещё не вызывался, настройки
+// загружаются без YAML-файла из командной строки; при ошибках проверки
+// используются значения, которые удалось загрузить
+func Get() *Config {
+	currentMu.Lock()
+	cfg := current
+	currentMu.Unlock()
+	if cfg != nil {
+		return cfg
+	}
+
+	cfg, _ = Load("")
+
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	if current == nil {
+		current = cfg
+	}
+	return current
+}
+
+// DSN возвращает строку подключения к базе данных в виде URL postgres://
+func (d DatabaseConfig) DSN() string {
+	if d.URL != "" {
+		return d.URL
+	}
+
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(d.User, d.Password),
+		Host:   d.Host + ":" + strconv.Itoa(d.Port),
+		Path:   "/" + d.Name,
+	}
+	query := url.Values{}
+	query.Set("sslmode", d.SSLMode)
+	dsn.RawQuery = query.Encode()
+	return dsn.String()
+}
+
+// loadYAML читает настройки из YAML-файла поверх текущих значений.
+// Неизвестные ключи считаются ошибкой, чтобы опечатки не проходили незамеченными
+func loadYAML(cfg *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	// Пустой файл (io.EOF) означает, что настройки не переопределяются
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// applyEnv заполняет поля с тегом env значениями из lookup
+func applyEnv(value reflect.Value, lookup func(string) (string, bool)) Errors {
+	var errs Errors
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		fieldType := value.Type().Field(i)
+
+		name := fieldType.Tag.Get("env")
+		if name == "" {
+			if field.Kind() == reflect.Struct {
+				errs = append(errs, applyEnv(field, lookup)...)
+			}
+			continue
+		}
+
+		raw, ok := lookup(name)
+		raw = strings.TrimSpace(raw)
+		// Пустое значение нестроковой настройки означает значение по умолчанию
+		if !ok || (raw == "" && field.Kind() != reflect.String) {
+			continue
+		}
+		if err := setField(field, raw); err != nil {
+			errs = append(errs, FieldError{Name: name, Message: err.Error()})
+		}
+	}
+
+	return errs
+}
+
+// setField разбирает строковое значение в поле соответствующего типа
+func setField(field reflect.Value, raw string) error {
+	switch field.Interface().(type) {
+	case Duration:
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, expected a value like 10s or 1m", raw)
+		}
+		field.Set(reflect.ValueOf(Duration(duration)))
+	case string:
+		field.SetString(raw)
+	case int, int64:
+		number, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		field.SetInt(number)
+	case bool:
+		flag, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q, expected true or false", raw)
+		}
+		field.SetBool(flag)
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
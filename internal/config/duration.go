@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration - длительность, которая в YAML записывается строкой вида "10s"
+type Duration time.Duration
+
+// Std возвращает значение как time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	duration, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q, expected a value like 10s or 1m", node.Line, node.Value)
+	}
+	*d = Duration(duration)
+	return nil
+}
@@ -0,0 +1,22 @@
+package config
+
+import "strings"
+
+// FieldError - ошибка одной настройки. Name - имя переменной окружения
+// или источника настроек (файла), к которому относится ошибка
+type FieldError struct {
+	Name    string
+	Message string
+}
+
+// Errors - все ошибки загрузки и проверки настроек
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, "invalid configuration:")
+	for _, fieldErr := range e {
+		lines = append(lines, "  "+fieldErr.Name+": "+fieldErr.Message)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"bytes"
+	"net/url"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maskedValue заменяет секреты при выводе настроек
+const maskedValue = "******"
+
+// Masked возвращает копию настроек, в которой значения с тегом secret скрыты.
+// В URL базы данных скрывается только пароль, чтобы были видны хост и имя базы
+func (c *Config) Masked() *Config {
+	masked := *c
+	maskSecrets(reflect.ValueOf(&masked).Elem())
+	if c.Database.URL != "" {
+		masked.Database.URL = maskURL(c.Database.URL)
+	}
+	return &masked
+}
+
+// YAML возвращает настройки в формате YAML-файла настроек
+func (c *Config) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// maskSecrets заменяет непустые строковые поля с тегом secret
+func maskSecrets(value reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if field.Kind() == reflect.Struct {
+			maskSecrets(field)
+			continue
+		}
+		if value.Type().Field(i).Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "" {
+			field.SetString(maskedValue)
+		}
+	}
+}
+
+// maskURL скрывает пароль в URL
+func maskURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return maskedValue
+	}
+	return parsed.Redacted()
+}
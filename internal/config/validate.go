@@ -0,0 +1,173 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Допустимые значения перечислимых настроек
+var (
+	logLevels     = []string{"panic", "fatal", "error", "warn", "warning", "info", "debug", "trace"}
+	logFormats    = []string{"text", "json"}
+	sslModes      = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	txIsolations  = []string{"read committed", "repeatable read", "serializable"}
+	dupPolicies   = []string{DuplicateReject, DuplicateReturn, DuplicateAllow}
+	traceExporter = []string{"none", "otlp", "stdout", "console"}
+	cacheBackends = []string{CacheMemory, CacheRedis, CacheNone}
+)
+
+// validate проверяет все настройки и возвращает все найденные ошибки
+func (c *Config) validate() Errors {
+	var errs Errors
+	add := func(name, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Name: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		add("SERVER_PORT", "must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	timeouts := []struct {
+		name  string
+		value Duration
+	}{
+		{"SERVER_READ_HEADER_TIMEOUT", c.Server.ReadHeaderTimeout},
+		{"SERVER_READ_TIMEOUT", c.Server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout},
+		{"SERVER_SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			add(timeout.name, "must be positive")
+		}
+	}
+	if c.Server.ShutdownDelay < 0 {
+		add("SERVER_SHUTDOWN_DELAY", "must not be negative")
+	}
+
+	if _, err := time.Parse(time.DateOnly, c.API.LegacySunset); err != nil {
+		add("API_LEGACY_SUNSET", "must be a date like 2027-06-30, got %q", c.API.LegacySunset)
+	}
+
+	db := c.Database
+	if db.URL != "" {
+		parsed, err := url.Parse(db.URL)
+		if err != nil || (parsed.Scheme != "postgres" && parsed.Scheme != "postgresql") || parsed.Host == "" {
+			add("DATABASE_URL", "must be a postgres:// URL with a host")
+		}
+	} else {
+		if db.Host == "" {
+			add("DB_HOST", "is required when DATABASE_URL is not set")
+		}
+		if db.User == "" {
+			add("DB_USER", "is required when DATABASE_URL is not set")
+		}
+		if db.Name == "" {
+			add("DB_NAME", "is required when DATABASE_URL is not set")
+		}
+		if db.Port < 1 || db.Port > 65535 {
+			add("DB_PORT", "must be between 1 and 65535, got %d", db.Port)
+		}
+		if !oneOf(db.SSLMode, sslModes) {
+			add("DB_SSLMODE", "must be one of %s", strings.Join(sslModes, ", "))
+		}
+	}
+	if db.MaxOpenConns < 1 {
+		add("DB_MAX_OPEN_CONNS", "must be positive, got %d", db.MaxOpenConns)
+	}
+	if db.MaxIdleConns < 0 || db.MaxIdleConns > db.MaxOpenConns {
+		add("DB_MAX_IDLE_CONNS", "must be between 0 and DB_MAX_OPEN_CONNS, got %d", db.MaxIdleConns)
+	}
+	if !oneOf(db.TxIsolation, txIsolations) {
+		add("DB_TX_ISOLATION", "must be one of %s", strings.Join(txIsolations, ", "))
+	}
+	if db.TxMaxRetries < 0 {
+		add("DB_TX_MAX_RETRIES", "must not be negative, got %d", db.TxMaxRetries)
+	}
+
+	if !oneOf(strings.ToLower(c.Log.Level), logLevels) {
+		add("LOG_LEVEL", "must be one of %s", strings.Join(logLevels, ", "))
+	}
+	if !oneOf(strings.ToLower(c.Log.Format), logFormats) {
+		add("LOG_FORMAT", "must be one of %s", strings.Join(logFormats, ", "))
+	}
+
+	if c.Songs.ImportMaxBytes < 1 {
+		add("IMPORT_MAX_BYTES", "must be positive, got %d", c.Songs.ImportMaxBytes)
+	}
+	if !oneOf(c.Songs.DuplicatePolicy, dupPolicies) {
+		add("SONGS_DUPLICATE_POLICY", "must be one of %s", strings.Join(dupPolicies, ", "))
+	}
+
+	if parsed, err := url.Parse(c.MusicAPI.URL); err != nil || parsed.Host == "" ||
+		(parsed.Scheme != "http" && parsed.Scheme != "https") {
+		add("MUSIC_API_URL", "must be an absolute http or https URL")
+	}
+
+	if c.Webhooks.DispatchInterval <= 0 {
+		add("WEBHOOK_DISPATCH_INTERVAL", "must be positive")
+	}
+	if c.Webhooks.Timeout <= 0 {
+		add("WEBHOOK_TIMEOUT", "must be positive")
+	}
+	if c.Webhooks.MaxAttempts < 1 {
+		add("WEBHOOK_MAX_ATTEMPTS", "must be positive, got %d", c.Webhooks.MaxAttempts)
+	}
+
+	if c.Idempotency.TTL <= 0 {
+		add("IDEMPOTENCY_TTL", "must be positive")
+	}
+
+	if !oneOf(c.Cache.Backend, cacheBackends) {
+		add("CACHE_BACKEND", "must be one of %s", strings.Join(cacheBackends, ", "))
+	}
+	if c.Cache.TTL <= 0 {
+		add("CACHE_TTL", "must be positive")
+	}
+	if c.Cache.Size < 1 {
+		add("CACHE_SIZE", "must be positive, got %d", c.Cache.Size)
+	}
+	if c.Cache.Backend == CacheRedis && c.Cache.RedisAddr == "" {
+		add("CACHE_REDIS_ADDR", "is required when CACHE_BACKEND is redis")
+	}
+	if c.Cache.RedisDB < 0 {
+		add("CACHE_REDIS_DB", "must not be negative, got %d", c.Cache.RedisDB)
+	}
+	if c.Cache.MaxAge < 0 {
+		add("CACHE_MAX_AGE", "must not be negative")
+	}
+
+	if c.GraphQL.MaxDepth < 1 {
+		add("GRAPHQL_MAX_DEPTH", "must be positive, got %d", c.GraphQL.MaxDepth)
+	}
+	if c.GraphQL.MaxComplexity < 1 {
+		add("GRAPHQL_MAX_COMPLEXITY", "must be positive, got %d", c.GraphQL.MaxComplexity)
+	}
+
+	if c.GRPC.Port < 0 || c.GRPC.Port > 65535 {
+		add("GRPC_PORT", "must be between 0 and 65535, got %d", c.GRPC.Port)
+	} else if c.GRPC.Port == c.Server.Port {
+		add("GRPC_PORT", "must differ from SERVER_PORT %d", c.Server.Port)
+	}
+
+	if !oneOf(strings.ToLower(c.Tracing.Exporter), traceExporter) {
+		add("OTEL_TRACES_EXPORTER", "must be one of %s", strings.Join(traceExporter, ", "))
+	}
+	if c.Tracing.ServiceName == "" {
+		add("OTEL_SERVICE_NAME", "must not be empty")
+	}
+
+	return errs
+}
+
+// oneOf сообщает, входит ли value в allowed
+func oneOf(value string, allowed []string) bool {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
@@ -1,47 +1,131 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	"os"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/inanmasov/music-service/internal/config"
 	"github.com/inanmasov/music-service/internal/logger"
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/inanmasov/music-service/internal/tracing"
+	"github.com/lib/pq"
 )
 
-// Подключение к базе данных
+var (
+	// pingTimeout - сколько ждать ответа базы при проверке подключения
+	pingTimeout = 3 * time.Second
+	// errClosed - пул закрыт через Close, пока проверялось подключение
+	errClosed = errors.New("database pool is closed")
+)
+
+var (
+	// pool - общий пул подключений, ready - подключение к базе проверено
+	pool   *sql.DB
+	ready  bool
+	poolMu sync.Mutex
+)
+
+// Initialize возвращает общий пул подключений к базе данных. Пул открывается
+// один раз, а подключение проверяется, пока проверка не пройдёт: если база
+// недоступна, вызов возвращает ошибку примерно через pingTimeout, и следующий
+// вызов повторит проверку. Проверка идёт без блокировки пула, поэтому вызовы не
+// ждут друг друга. Пул закрывается один раз при остановке сервиса через Close
 func Initialize() (*sql.DB, error) {
+	db, verified, err := open()
+	if err != nil || verified {
+		return db, err
+	}
+
 	log := logger.GetLogger()
 
-	err := godotenv.Load()
-	if err != nil {
-		log.Error("Error loading .env file")
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		log.Errorf("Database connection failed: %v", err)
 		return nil, err
 	}
 
-	log.Info("Loaded environment variables")
+	poolMu.Lock()
+	defer poolMu.Unlock()
+
+	if pool != db {
+		return nil, errClosed
+	}
+	if !ready {
+		ready = true
+		log.Info("Database ping successful")
+	}
+	return pool, nil
+}
+
+// open открывает общий пул при первом вызове и сообщает, проверено ли подключение
+func open() (*sql.DB, bool, error) {
+	poolMu.Lock()
+	defer poolMu.Unlock()
 
-	connect_db := "user=" + os.Getenv("DB_USER") +
-		" password=" + os.Getenv("DB_PASSWORD") +
-		" dbname=" + os.Getenv("DB_NAME") +
-		" host=" + os.Getenv("DB_HOST") +
-		" port=" + os.Getenv("DB_PORT") +
-		" sslmode=disable"
+	if pool != nil {
+		return pool, ready, nil
+	}
+
+	cfg := config.Get().Database
 
-	db, err := sql.Open("postgres", connect_db)
+	connector, err := pq.NewConnector(withConnectTimeout(cfg.DSN()))
 	if err != nil {
-		log.Errorf("Failed to open database connection: %v", err)
-		return nil, err
+		logger.GetLogger().Errorf("Failed to open database connection: %v", err)
+		return nil, false, err
 	}
 
-	log.Info("Database connection established")
+	// Каждый SQL-запрос получает спан трассировки
+	pool = sql.OpenDB(tracing.WrapConnector(connector))
 
-	if err = db.Ping(); err != nil {
-		log.Errorf("Database connection failed: %v", err)
-		return nil, err
+	pool.SetMaxOpenConns(cfg.MaxOpenConns)
+	pool.SetMaxIdleConns(cfg.MaxIdleConns)
+	pool.SetConnMaxLifetime(30 * time.Minute)
+
+	logger.GetLogger().Info("Database connection pool opened")
+	return pool, false, nil
+}
+
+// withConnectTimeout добавляет к строке подключения dsn параметр connect_timeout,
+// равный pingTimeout, если он не задан. lib/pq не прерывает установку соединения
+// по отмене контекста, и без него проверка подключения к зависшей базе не завершилась бы
+func withConnectTimeout(dsn string) string {
+	seconds := strconv.Itoa(max(1, int(pingTimeout.Round(time.Second)/time.Second)))
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		parsed, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		query := parsed.Query()
+		if query.Get("connect_timeout") == "" {
+			query.Set("connect_timeout", seconds)
+			parsed.RawQuery = query.Encode()
+		}
+		return parsed.String()
+	}
+
+	if strings.Contains(dsn, "connect_timeout=") {
+		return dsn
 	}
+	return dsn + " connect_timeout=" + seconds
+}
 
-	log.Info("Database ping successful")
+// Close закрывает общий пул подключений, если он был открыт
+func Close() error {
+	poolMu.Lock()
+	defer poolMu.Unlock()
 
-	return db, nil
+	if pool == nil {
+		return nil
+	}
+	err := pool.Close()
+	pool = nil
+	ready = false
+	return err
 }
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/lib/pq"
+)
+
+// retryBaseDelay - пауза перед первым повтором транзакции, дальше она удваивается
+const retryBaseDelay = 10 * time.Millisecond
+
+// isolationLevels - уровни изоляции по значениям DB_TX_ISOLATION
+var isolationLevels = map[string]sql.IsolationLevel{
+	"read committed":  sql.LevelReadCommitted,
+	"repeatable read": sql.LevelRepeatableRead,
+	"serializable":    sql.LevelSerializable,
+}
+
+// ErrTxRetriesExhausted - транзакция так и не прошла из-за ошибок сериализации
+// или взаимоблокировок после всех повторов
+var ErrTxRetriesExhausted = errors.New("transaction retries exhausted")
+
+// TxOptions - параметры транзакции RunInTx
+type TxOptions struct {
+	// Isolation - уровень изоляции, sql.LevelDefault - уровень по умолчанию сервера
+	Isolation sql.IsolationLevel
+	// ReadOnly - транзакция только читает данные
+	ReadOnly bool
+	// MaxRetries - сколько раз транзакция повторяется после ошибки сериализации
+	// или взаимоблокировки
+	MaxRetries int
+}
+
+// DefaultTxOptions возвращает параметры транзакций из настроек DB_TX_ISOLATION
+// и DB_TX_MAX_RETRIES
+func DefaultTxOptions() TxOptions {
+	cfg := config.Get().Database
+	return TxOptions{
+		Isolation:  isolationLevels[cfg.TxIsolation],
+		MaxRetries: cfg.TxMaxRetries,
+	}
+}
+
+// RunInTx выполняет fn как единицу работы в транзакции pool с параметрами opts.
+// Все запросы fn должны идти через tx. Если fn вернула ошибку или запаниковала,
+// транзакция откатывается, а ошибка возвращается как есть (паника передаётся
+// дальше), иначе транзакция фиксируется. Если fn или фиксация завершились ошибкой
+// сериализации или взаимоблокировкой, транзакция повторяется целиком, поэтому fn
+// не должна иметь побочных эффектов вне tx. Когда повторы исчерпаны, возвращается
+// ошибка, оборачивающая ErrTxRetriesExhausted и последнюю ошибку базы данных
+func RunInTx(ctx context.Context, pool *sql.DB, opts TxOptions, fn func(tx *sql.Tx) error) error {
+	log := logger.FromContext(ctx)
+
+	for attempt := 0; ; attempt++ {
+		err := runOnce(ctx, pool, opts, fn)
+		if err == nil || !retryable(err) {
+			return err
+		}
+		if attempt >= opts.MaxRetries {
+			return fmt.Errorf("%w after %d attempts: %w", ErrTxRetriesExhausted, attempt+1, err)
+		}
+
+		// Пауза со случайной добавкой, чтобы конкурирующие транзакции не столкнулись снова
+		delay := retryBaseDelay<<attempt + rand.N(retryBaseDelay)
+		log.Warnf("Retrying transaction in %s after attempt %d: %v", delay, attempt+1, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// runOnce выполняет одну попытку транзакции
+func runOnce(ctx context.Context, pool *sql.DB, opts TxOptions, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := pool.BeginTx(ctx, &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		// Откат после ошибки не меняет результат: транзакция всё равно не зафиксирована
+		tx.Rollback()
+		if value := recover(); value != nil {
+			panic(value)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+// retryable сообщает, что транзакцию можно повторить: PostgreSQL прервал её
+// из-за ошибки сериализации (40001) или взаимоблокировки (40P01)
+func retryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
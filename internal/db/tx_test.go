@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/lib/pq"
+)
+
+// fakeConnector - драйвер базы данных, который записывает вызовы транзакций
+// и возвращает заданные ошибки запросов и фиксаций по очереди
+type fakeConnector struct {
+	mu         sync.Mutex
+	calls      []string
+	options    []driver.TxOptions
+	execErrs   []error
+	commitErrs []error
+}
+
+func (f *fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeConnector) Driver() driver.Driver                        { return nil }
+
+func (f *fakeConnector) record(call string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+// next возвращает очередную ошибку из errs и убирает её из очереди
+func (f *fakeConnector) next(errs *[]error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(*errs) == 0 {
+		return nil
+	}
+	err := (*errs)[0]
+	*errs = (*errs)[1:]
+	return err
+}
+
+type fakeConn struct{ f *fakeConnector }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c fakeConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.f.mu.Lock()
+	c.f.options = append(c.f.options, opts)
+	c.f.mu.Unlock()
+	c.f.record("begin")
+	return fakeTx{c.f}, nil
+}
+
+func (c fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	c.f.record("exec")
+	if err := c.f.next(&c.f.execErrs); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{ f *fakeConnector }
+
+func (t fakeTx) Commit() error {
+	t.f.record("commit")
+	return t.f.next(&t.f.commitErrs)
+}
+
+func (t fakeTx) Rollback() error {
+	t.f.record("rollback")
+	return nil
+}
+
+// openFake возвращает пул поверх драйвера f
+func openFake(t *testing.T, f *fakeConnector) *sql.DB {
+	t.Helper()
+	pool := sql.OpenDB(f)
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+// execTwice - единица работы из двух запросов
+func execTwice(tx *sql.Tx) error {
+	for i := 0; i < 2; i++ {
+		if _, err := tx.Exec("UPDATE songs SET version = version + 1"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRunInTxCommits(t *testing.T) {
+	f := &fakeConnector{}
+	if err := RunInTx(context.Background(), openFake(t, f), TxOptions{}, execTwice); err != nil {
+		t.Fatalf("RunInTx: %v", err)
+	}
+	if want := []string{"begin", "exec", "exec", "commit"}; !reflect.DeepEqual(f.calls, want) {
+		t.Errorf("calls = %v, want %v", f.calls, want)
+	}
+}
+
+func TestRunInTxRollsBackOnError(t *testing.T) {
+	// Второй запрос единицы работы не проходит, первый не должен остаться зафиксированным
+	failure := &pq.Error{Code: "23505"}
+	f := &fakeConnector{execErrs: []error{nil, failure}}
+
+	err := RunInTx(context.Background(), openFake(t, f), TxOptions{MaxRetries: 3}, execTwice)
+	if err != failure {
+		t.Errorf("RunInTx = %v, want the error of fn as is", err)
+	}
+	if want := []string{"begin", "exec", "exec", "rollback"}; !reflect.DeepEqual(f.calls, want) {
+		t.Errorf("calls = %v, want %v: the error is not retryable", f.calls, want)
+	}
+}
+
+func TestRunInTxRollsBackOnPanic(t *testing.T) {
+	f := &fakeConnector{}
+	pool := openFake(t, f)
+
+	defer func() {
+		if value := recover(); value != "boom" {
+			t.Errorf("recovered %v, want the panic of fn", value)
+		}
+		if want := []string{"begin", "exec", "rollback"}; !reflect.DeepEqual(f.calls, want) {
+			t.Errorf("calls = %v, want %v", f.calls, want)
+		}
+	}()
+	RunInTx(context.Background(), pool, TxOptions{}, func(tx *sql.Tx) error {
+		tx.Exec("DELETE FROM songs")
+		panic("boom")
+	})
+	t.Error("RunInTx did not re-panic")
+}
+
+func TestRunInTxOptions(t *testing.T) {
+	f := &fakeConnector{}
+	opts := TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}
+	if err := RunInTx(context.Background(), openFake(t, f), opts, execTwice); err != nil {
+		t.Fatalf("RunInTx: %v", err)
+	}
+	want := driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelSerializable), ReadOnly: true}
+	if len(f.options) != 1 || f.options[0] != want {
+		t.Errorf("BeginTx options = %+v, want %+v", f.options, want)
+	}
+}
+
+func TestDefaultTxOptions(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://user@localhost/music")
+	t.Setenv("DB_TX_ISOLATION", "serializable")
+	t.Setenv("DB_TX_MAX_RETRIES", "5")
+	if _, err := config.Load(""); err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	if opts := DefaultTxOptions(); opts.Isolation != sql.LevelSerializable || opts.MaxRetries != 5 {
+		t.Errorf("DefaultTxOptions = %+v, want serializable with 5 retries", opts)
+	}
+}
+
+func TestRunInTxRetries(t *testing.T) {
+	for _, code := range []pq.ErrorCode{"40001", "40P01"} {
+		t.Run(string(code), func(t *testing.T) {
+			failure := &pq.Error{Code: code}
+
+			// Ошибка в запросе единицы работы
+			f := &fakeConnector{execErrs: []error{nil, failure}}
+			if err := RunInTx(context.Background(), openFake(t, f), TxOptions{MaxRetries: 1}, execTwice); err != nil {
+				t.Fatalf("RunInTx: %v", err)
+			}
+			want := []string{"begin", "exec", "exec", "rollback", "begin", "exec", "exec", "commit"}
+			if !reflect.DeepEqual(f.calls, want) {
+				t.Errorf("calls = %v, want %v", f.calls, want)
+			}
+
+			// Ошибка при фиксации
+			f = &fakeConnector{commitErrs: []error{failure}}
+			if err := RunInTx(context.Background(), openFake(t, f), TxOptions{MaxRetries: 1}, execTwice); err != nil {
+				t.Fatalf("RunInTx with failed commit: %v", err)
+			}
+			want = []string{"begin", "exec", "exec", "commit", "begin", "exec", "exec", "commit"}
+			if !reflect.DeepEqual(f.calls, want) {
+				t.Errorf("calls with failed commit = %v, want %v", f.calls, want)
+			}
+		})
+	}
+}
+
+func TestRunInTxRetriesExhausted(t *testing.T) {
+	failure := &pq.Error{Code: "40001"}
+	f := &fakeConnector{execErrs: []error{failure, failure, failure, failure}}
+
+	err := RunInTx(context.Background(), openFake(t, f), TxOptions{MaxRetries: 2}, execTwice)
+	if !errors.Is(err, ErrTxRetriesExhausted) {
+		t.Fatalf("RunInTx = %v, want ErrTxRetriesExhausted", err)
+	}
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr != failure {
+		t.Errorf("RunInTx = %v, want it to wrap the last database error", err)
+	}
+	if len(f.options) != 3 {
+		t.Errorf("%d attempts, want 3", len(f.options))
+	}
+}
+
+func TestRunInTxStopsRetryingOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &fakeConnector{execErrs: []error{&pq.Error{Code: "40P01"}}}
+
+	err := RunInTx(ctx, openFake(t, f), TxOptions{MaxRetries: 3}, func(tx *sql.Tx) error {
+		err := execTwice(tx)
+		cancel()
+		return err
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("RunInTx = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,163 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/importer"
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// Поддерживаемые форматы выгрузки. CSV и NDJSON совместимы с импортом
+const (
+	FormatCSV    = importer.FormatCSV
+	FormatNDJSON = importer.FormatNDJSON
+	FormatJSON   = "json"
+)
+
+// contentTypes - тип содержимого и расширение файла для каждого формата
+var contentTypes = map[string][2]string{
+	FormatCSV:    {"text/csv; charset=utf-8", "csv"},
+	FormatNDJSON: {"application/x-ndjson", "ndjson"},
+	FormatJSON:   {"application/json; charset=utf-8", "json"},
+}
+
+// Record - песня в выгрузке. Дата выхода записывается как YYYY-MM-DD,
+// пустые поля опускаются, чтобы выгрузку можно было импортировать повторно
+type Record struct {
+	ID          int    `json:"id"`
+	Group       string `json:"group"`
+	Song        string `json:"song"`
+	ReleaseDate string `json:"releaseDate,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Link        string `json:"link,omitempty"`
+}
+
+// NewRecord преобразует песню в запись выгрузки
+func NewRecord(song models.Song) Record {
+	record := Record{
+		ID:    song.ID,
+		Group: song.GroupName,
+		Song:  song.SongName,
+		Text:  song.Text,
+		Link:  song.Link,
+	}
+	if !song.ReleaseDate.IsZero() {
+		record.ReleaseDate = song.ReleaseDate.Format(time.DateOnly)
+	}
+	return record
+}
+
+// Writer последовательно записывает песни в выгрузку
+type Writer interface {
+	// Write записывает одну песню
+	Write(song models.Song) error
+	// Close дописывает окончание выгрузки. Нижележащий io.Writer не закрывается
+	Close() error
+}
+
+// ContentType возвращает тип содержимого и расширение файла для формата
+func ContentType(format string) (string, string, error) {
+	types, ok := contentTypes[format]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported export format %q", format)
+	}
+	return types[0], types[1], nil
+}
+
+// NewWriter создаёт Writer для указанного формата
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatJSON:
+		return &jsonWriter{w: w, encoder: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// csvWriter пишет заголовок со столбцами id и importer.Columns, затем по строке на песню
+type csvWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (w *csvWriter) writeHeader() error {
+	if w.headerWritten {
+		return nil
+	}
+	w.headerWritten = true
+	return w.writer.Write(append([]string{"id"}, importer.Columns...))
+}
+
+func (w *csvWriter) Write(song models.Song) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	record := NewRecord(song)
+	return w.writer.Write([]string{
+		strconv.Itoa(record.ID),
+		record.Group,
+		record.Song,
+		record.ReleaseDate,
+		record.Text,
+		record.Link,
+	})
+}
+
+func (w *csvWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// ndjsonWriter пишет по одному JSON-объекту на строку
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(song models.Song) error {
+	return w.encoder.Encode(NewRecord(song))
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+// jsonWriter пишет JSON-массив, не накапливая его в памяти
+type jsonWriter struct {
+	w       io.Writer
+	encoder *json.Encoder
+	count   int
+}
+
+func (w *jsonWriter) Write(song models.Song) error {
+	separator := ","
+	if w.count == 0 {
+		separator = "["
+	}
+	if _, err := io.WriteString(w.w, separator); err != nil {
+		return err
+	}
+	w.count++
+	return w.encoder.Encode(NewRecord(song))
+}
+
+func (w *jsonWriter) Close() error {
+	closing := "]\n"
+	if w.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(w.w, closing)
+	return err
+}
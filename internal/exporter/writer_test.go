@@ -0,0 +1,164 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/importer"
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// testSongs - песни для выгрузки: с заполненными полями, со спецсимволами
+// CSV и JSON в тексте и с пустыми необязательными полями
+var testSongs = []models.Song{
+	{
+		ID:          1,
+		GroupName:   "Muse",
+		SongName:    "Uprising",
+		ReleaseDate: time.Date(2009, 9, 7, 0, 0, 0, 0, time.UTC),
+		Text:        "They will not force us",
+		Link:        "https://example.com/uprising",
+		Version:     3,
+	},
+	{
+		ID:        2,
+		GroupName: "Кино",
+		SongName:  `Группа "крови"`,
+		Text:      "Тёплое место,\nно улицы ждут\n\nотпечатков наших ног",
+	},
+}
+
+// export записывает songs в формате format и возвращает результат
+func export(t *testing.T, format string, songs []models.Song) string {
+	t.Helper()
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, format)
+	if err != nil {
+		t.Fatalf("NewWriter(%s): %v", format, err)
+	}
+	for _, song := range songs {
+		if err := writer.Write(song); err != nil {
+			t.Fatalf("Write(%s): %v", format, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close(%s): %v", format, err)
+	}
+	return buf.String()
+}
+
+func TestWriterOutput(t *testing.T) {
+	tests := []struct {
+		format string
+		songs  []models.Song
+		want   string
+	}{
+		{FormatCSV, nil, "id,group,song,releaseDate,text,link\n"},
+		{FormatCSV, testSongs, "id,group,song,releaseDate,text,link\n" +
+			"1,Muse,Uprising,2009-09-07,They will not force us,https://example.com/uprising\n" +
+			"2,Кино,\"Группа \"\"крови\"\"\",,\"Тёплое место,\nно улицы ждут\n\nотпечатков наших ног\",\n"},
+		{FormatNDJSON, nil, ""},
+		{FormatNDJSON, testSongs,
+			`{"id":1,"group":"Muse","song":"Uprising","releaseDate":"2009-09-07","text":"They will not force us","link":"https://example.com/uprising"}` + "\n" +
+				`{"id":2,"group":"Кино","song":"Группа \"крови\"","text":"Тёплое место,\nно улицы ждут\n\nотпечатков наших ног"}` + "\n"},
+		{FormatJSON, nil, "[]\n"},
+		{FormatJSON, testSongs[:1],
+			`[{"id":1,"group":"Muse","song":"Uprising","releaseDate":"2009-09-07","text":"They will not force us","link":"https://example.com/uprising"}` + "\n]\n"},
+	}
+	for _, tt := range tests {
+		if got := export(t, tt.format, tt.songs); got != tt.want {
+			t.Errorf("%s export of %d songs:\n got %q\nwant %q", tt.format, len(tt.songs), got, tt.want)
+		}
+	}
+}
+
+func TestJSONExportIsArray(t *testing.T) {
+	var records []Record
+	if err := json.Unmarshal([]byte(export(t, FormatJSON, testSongs)), &records); err != nil {
+		t.Fatalf("JSON export is not an array: %v", err)
+	}
+	if len(records) != len(testSongs) {
+		t.Fatalf("%d records, want %d", len(records), len(testSongs))
+	}
+	for i, record := range records {
+		if record != NewRecord(testSongs[i]) {
+			t.Errorf("record %d = %+v, want %+v", i, record, NewRecord(testSongs[i]))
+		}
+	}
+}
+
+// TestExportImportRoundTrip проверяет, что выгрузки CSV и NDJSON импортируются
+// обратно без потерь
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatNDJSON} {
+		var inputs []models.SongInput
+		err := importer.ReadRows(strings.NewReader(export(t, format, testSongs)), format, func(row importer.Row) error {
+			if row.Err != nil {
+				t.Errorf("%s row %d: %v", format, row.Number, row.Err)
+			}
+			inputs = append(inputs, row.Input)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ReadRows(%s): %v", format, err)
+		}
+
+		if len(inputs) != len(testSongs) {
+			t.Fatalf("%s: imported %d songs, want %d", format, len(inputs), len(testSongs))
+		}
+		for i, song := range testSongs {
+			want := models.SongInput{Group: song.GroupName, Song: song.SongName, ReleaseDate: song.ReleaseDate, Text: song.Text, Link: song.Link}
+			if !inputs[i].ReleaseDate.Equal(want.ReleaseDate) {
+				t.Errorf("%s song %d release date %v, want %v", format, i, inputs[i].ReleaseDate, want.ReleaseDate)
+			}
+			inputs[i].ReleaseDate, want.ReleaseDate = time.Time{}, time.Time{}
+			if inputs[i] != want {
+				t.Errorf("%s song %d = %+v, want %+v", format, i, inputs[i], want)
+			}
+		}
+	}
+}
+
+// TestWriterStreams проверяет, что выгрузка попадает в io.Writer по мере записи
+// песен, а не накапливается до Close
+func TestWriterStreams(t *testing.T) {
+	song := testSongs[0]
+	for _, format := range []string{FormatCSV, FormatNDJSON, FormatJSON} {
+		var buf bytes.Buffer
+		writer, err := NewWriter(&buf, format)
+		if err != nil {
+			t.Fatalf("NewWriter(%s): %v", format, err)
+		}
+
+		// Буфер CSV - 4 КБ, поэтому достаточно выгрузить больше
+		for i := 0; i < 100; i++ {
+			song.ID = i + 1
+			if err := writer.Write(song); err != nil {
+				t.Fatalf("Write(%s): %v", format, err)
+			}
+		}
+		if buf.Len() < 4096 {
+			t.Errorf("%s: %d bytes written before Close, want the songs streamed", format, buf.Len())
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Close(%s): %v", format, err)
+		}
+	}
+}
+
+func TestContentType(t *testing.T) {
+	for format, want := range map[string]string{FormatCSV: "csv", FormatNDJSON: "ndjson", FormatJSON: "json"} {
+		if _, extension, err := ContentType(format); err != nil || extension != want {
+			t.Errorf("ContentType(%s) = %q, %v, want %q", format, extension, err, want)
+		}
+	}
+	if _, _, err := ContentType("xml"); err == nil {
+		t.Error("ContentType(xml) accepted an unsupported format")
+	}
+	if _, err := NewWriter(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("NewWriter(xml) accepted an unsupported format")
+	}
+}
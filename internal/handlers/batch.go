@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/service"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+// SongBatch выполняет пакет операций с песнями
+// @Summary Run a batch of song operations
+// @Description Runs create, update and delete operations in the given order. In atomic mode (default) all operations run in one transaction: if one fails, none is applied and the response status is the status of the failed operation, other operations get 424. In best-effort mode every operation runs in its own transaction and the response status is 200 whatever the results. Every result has the status a single request would get: create - like POST /songs with the full song, update - like PUT /songs/{id}, delete - like DELETE /songs/{id}. version replaces If-Match. At most 1000 operations
+// @Tags songs
+// @Accept json
+// @Produce json
+// @Param batch body models.SongBatch true "Mode and operations"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the first response instead of repeating it"
+// @Success 200 {object} models.SongBatchResult "Results of the operations"
+// @Failure 400 {object} models.Problem "Invalid batch, or an operation of an atomic batch has invalid data"
+// @Failure 404 {object} models.SongBatchResult "A song of an atomic batch not found"
+// @Failure 409 {object} models.SongBatchResult "An operation of an atomic batch conflicts with existing songs"
+// @Failure 412 {object} models.SongBatchResult "A song of an atomic batch has been modified"
+// @Failure 422 {object} models.ErrorResponse "Idempotency-Key has already been used with a different request"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database or run the batch"
+// @Router /api/v1/songs/batch [post]
+func SongBatch(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting SongBatch handler")
+
+	var input models.SongBatch
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Errorf("Failed to bind JSON: %v", err)
+		respondInvalid(c, "Invalid batch", validation.DecodeError(err))
+		return
+	}
+
+	if input.Mode == "" {
+		input.Mode = models.BatchAtomic
+	}
+	if input.Mode != models.BatchAtomic && input.Mode != models.BatchBestEffort {
+		log.Errorf("Invalid batch mode: %s", input.Mode)
+		respondError(c, http.StatusBadRequest, "Invalid mode, use "+models.BatchAtomic+" or "+models.BatchBestEffort)
+		return
+	}
+
+	log.Debugf("Request to run a batch of %d operations in %s mode", len(input.Operations), input.Mode)
+
+	ops := make([]service.BatchOp, len(input.Operations))
+	for i, op := range input.Operations {
+		ops[i] = batchOp(op)
+	}
+
+	results, err := library.RunBatch(c.Request.Context(), ops, input.Mode == models.BatchAtomic)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	response := models.SongBatchResult{Mode: input.Mode, Results: make([]models.SongOperationResult, len(results))}
+	status := http.StatusOK
+	for i, result := range results {
+		response.Results[i] = operationResult(i, input.Operations[i].Op, result)
+		if result.Err != nil {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+		// Атомарный пакет завершается статусом операции, из-за которой он отменён
+		if input.Mode == models.BatchAtomic && result.Err != nil && !errors.Is(result.Err, service.ErrBatchAborted) {
+			status = response.Results[i].Status
+		}
+	}
+
+	log.Infof("Batch completed: %d succeeded, %d failed", response.Succeeded, response.Failed)
+	c.JSON(status, response)
+}
+
+// batchOp преобразует операцию из запроса в операцию пакета. Ошибки разбора
+// сохраняются в операции, чтобы завершить ей только эту операцию
+func batchOp(op models.SongOperation) service.BatchOp {
+	result := service.BatchOp{Kind: op.Op, ID: op.ID}
+	if op.Version != nil {
+		result.Precondition = service.ExpectVersion(*op.Version)
+	}
+
+	field := func(name, code, message string) error {
+		return service.Invalid("Invalid operation", validation.Errors{{Field: name, Code: code, Message: message}})
+	}
+
+	switch op.Op {
+	case service.BatchCreate, service.BatchUpdate:
+		if op.Op == service.BatchUpdate && op.ID <= 0 {
+			result.Err = field("id", validation.CodeOutOfRange, "must be a positive song ID")
+			return result
+		}
+		if len(op.Song) == 0 {
+			result.Err = field("song", validation.CodeRequired, "is required")
+			return result
+		}
+
+		input, err := validation.DecodeSongInput(op.Song)
+		if err != nil {
+			result.Err = service.Invalid("Invalid song data", err)
+			return result
+		}
+		result.Input = input
+	case service.BatchDelete:
+		if op.ID <= 0 {
+			result.Err = field("id", validation.CodeOutOfRange, "must be a positive song ID")
+		}
+	case "":
+		result.Err = field("op", validation.CodeRequired, "is required")
+	default:
+		result.Err = field("op", validation.CodeOutOfRange, "must be one of create, update, delete")
+	}
+	return result
+}
+
+// operationResult описывает результат операции index пакета
+func operationResult(index int, op string, result service.BatchResult) models.SongOperationResult {
+	response := models.SongOperationResult{Index: index, Op: op}
+
+	if result.Err != nil {
+		response.Status = serviceStatus(result.Err)
+		response.Error = &models.ErrorResponse{Error: serviceMessage(result.Err), Code: errorCode(response.Status)}
+
+		var svcErr *service.Error
+		if errors.As(result.Err, &svcErr) {
+			response.Errors = svcErr.Fields()
+			// Синтаксическую ошибку в данных песни не описать ошибками полей
+			if len(response.Errors) == 0 && response.Status == http.StatusBadRequest && svcErr.Err != nil {
+				response.Error.Error += ": " + svcErr.Err.Error()
+			}
+		}
+		return response
+	}
+
+	response.Status = http.StatusOK
+	if op == service.BatchCreate && result.Created {
+		response.Status = http.StatusCreated
+	}
+	song := result.Song.Song
+	response.Song = &song
+	return response
+}
+
+// UpdateSongs изменяет все песни, подходящие под фильтры
+// @Summary Update songs matching filters
+// @Description Applies a JSON Merge Patch (application/merge-patch+json or application/json) or a JSON Patch (application/json-patch+json) to every song matching the filters (the same as in GET /songs, at least one is required) in one transaction: if the patch cannot be applied to a song or makes it invalid, no song is changed. With dryRun=true the changes are checked and rolled back, and the IDs of the songs that would change are returned. At most 1000 songs
+// @Tags songs
+// @Accept json
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param groupName query string false "Group name for filtering"
+// @Param song query string false "Song name for filtering"
+// @Param releaseDate query string false "Release date for filtering"
+// @Param text query string false "Text for filtering"
+// @Param link query string false "Link for filtering"
+// @Param dryRun query bool false "Only check the changes and return the affected IDs" default(false)
+// @Param patch body object true "Merge patch object or JSON Patch operations"
+// @Success 200 {object} models.BulkResult "Songs updated, or would be updated with dryRun"
+// @Failure 400 {object} models.Problem "No filters, invalid filter, malformed patch, invalid patched song or too many songs"
+// @Failure 409 {object} models.ErrorResponse "A patched song has the same group and title as another song"
+// @Failure 415 {object} models.ErrorResponse "Unsupported patch format"
+// @Failure 422 {object} models.ErrorResponse "Patch cannot be applied to a song"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database or update songs"
+// @Router /api/v1/songs [patch]
+func UpdateSongs(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting UpdateSongs handler")
+
+	dryRun, ok := parseDryRun(c)
+	if !ok {
+		return
+	}
+	change, ok := songPatch(c)
+	if !ok {
+		return
+	}
+
+	ids, err := library.BulkModifySongs(c.Request.Context(), songFilter(c), dryRun, change)
+
+	var applyErr *patchError
+	switch {
+	case errors.As(err, &applyErr):
+		respondError(c, http.StatusUnprocessableEntity,
+			"Failed to apply patch to song "+strconv.Itoa(applyErr.songID)+": "+applyErr.err.Error())
+	case err != nil:
+		respondServiceError(c, err)
+	default:
+		c.JSON(http.StatusOK, models.BulkResult{DryRun: dryRun, Count: len(ids), IDs: ids})
+	}
+}
+
+// DeleteSongs удаляет все песни, подходящие под фильтры
+// @Summary Delete songs matching filters
+// @Description Deletes every song matching the filters (the same as in GET /songs, at least one is required) in one transaction. With dryRun=true nothing is deleted and the IDs of the songs that would be deleted are returned. At most 1000 songs
+// @Tags songs
+// @Produce json
+// @Param groupName query string false "Group name for filtering"
+// @Param song query string false "Song name for filtering"
+// @Param releaseDate query string false "Release date for filtering"
+// @Param text query string false "Text for filtering"
+// @Param link query string false "Link for filtering"
+// @Param dryRun query bool false "Only return the IDs of the songs that would be deleted" default(false)
+// @Success 200 {object} models.BulkResult "Songs deleted, or would be deleted with dryRun"
+// @Failure 400 {object} models.ErrorResponse "No filters, invalid filter or too many songs"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database or delete songs"
+// @Router /api/v1/songs [delete]
+func DeleteSongs(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting DeleteSongs handler")
+
+	dryRun, ok := parseDryRun(c)
+	if !ok {
+		return
+	}
+
+	ids, err := library.BulkDeleteSongs(c.Request.Context(), songFilter(c), dryRun)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.BulkResult{DryRun: dryRun, Count: len(ids), IDs: ids})
+}
+
+// parseDryRun разбирает параметр dryRun. Если он некорректен, отвечает 400 и возвращает false
+func parseDryRun(c *gin.Context) (dryRun, ok bool) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Errorf("Invalid dryRun: %s", c.Query("dryRun"))
+		respondError(c, http.StatusBadRequest, "Invalid dryRun value")
+		return false, false
+	}
+	return dryRun, true
+}
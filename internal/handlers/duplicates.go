@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+// defaultMinSimilarity - порог сходства текстов в отчёте о дубликатах по умолчанию
+const defaultMinSimilarity = 0.8
+
+// GetDuplicates возвращает отчёт о возможных дубликатах песен
+// @Summary Report likely duplicate songs
+// @Description Finds sets of songs of the same group (group names are compared after normalization) that are likely duplicates: their titles match after normalization (case, punctuation and spacing are ignored) or the share of common words in their lyrics is at least minSimilarity. Sets are ordered by the lowest song ID, songs in a set by ID
+// @Tags songs
+// @Produce json
+// @Param minSimilarity query number false "Minimum lyric similarity from 0 (exclusive) to 1" default(0.8)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of sets per page" default(10)
+// @Success 200 {object} models.Page[models.DuplicateSet] "Duplicate sets retrieved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid page, limit or minSimilarity"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database or retrieve songs"
+// @Router /api/v1/songs/duplicates [get]
+func GetDuplicates(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting GetDuplicates handler")
+
+	page, limit, ok := parsePagination(c, 10)
+	if !ok {
+		return
+	}
+
+	minSimilarity := defaultMinSimilarity
+	if value := c.Query("minSimilarity"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Errorf("Invalid minSimilarity: %s", value)
+			respondError(c, http.StatusBadRequest, "Invalid minSimilarity: must be a number")
+			return
+		}
+		minSimilarity = parsed
+	}
+
+	sets, total, err := library.FindDuplicates(c.Request.Context(), minSimilarity, page, limit)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	items := make([]models.DuplicateSet, len(sets))
+	for i, set := range sets {
+		items[i] = models.DuplicateSet{Reasons: set.Reasons, Similarity: set.Similarity, Songs: plainSongs(set.Songs)}
+	}
+
+	log.Infof("Retrieved %d sets of possible duplicates", len(items))
+	c.JSON(http.StatusOK, newPage(c, items, page, limit, total))
+}
+
+// MergeSongs сливает дубликаты с песней
+// @Summary Merge duplicate songs into a song
+// @Description Keeps the song with the given ID as the canonical one and deletes the duplicates in one transaction. Empty release date, text and link of the canonical song are filled from the duplicates in the given order. Requests for a deleted duplicate (GET /songs/{id} and /songs/{id}/text) are redirected to the canonical song with 308 Permanent Redirect. A song.deleted event is recorded for every duplicate and a song.merged event for the merge
+// @Tags songs
+// @Accept json
+// @Produce json
+// @Param id path int true "ID of the song to keep"
+// @Param merge body models.MergeSongs true "IDs of the duplicates to merge"
+// @Param If-Match header string false "ETag of the version of the song to keep"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the first response instead of repeating it"
+// @Success 200 {object} models.Song "Songs merged successfully"
+// @Header 200 {string} ETag "Version tag of the song"
+// @Failure 400 {object} models.Problem "Invalid song ID or duplicate IDs"
+// @Failure 404 {object} models.ErrorResponse "Song or duplicate not found"
+// @Failure 409 {object} models.ErrorResponse "A request with the same Idempotency-Key is still in progress"
+// @Failure 412 {object} models.ErrorResponse "Song has been modified"
+// @Failure 422 {object} models.ErrorResponse "Idempotency-Key has already been used with a different request"
+// @Failure 428 {object} models.ErrorResponse "If-Match header is required"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database or merge songs"
+// @Router /api/v1/songs/{id}/merge [post]
+func MergeSongs(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting MergeSongs handler")
+
+	id, ok := parseSongID(c)
+	if !ok {
+		return
+	}
+
+	var input models.MergeSongs
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Errorf("Failed to bind JSON: %v", err)
+		respondInvalid(c, "Invalid merge request", validation.DecodeError(err))
+		return
+	}
+
+	log.Debugf("Request to merge songs %v into song with ID %d", input.DuplicateIDs, id)
+
+	song, err := library.MergeSongs(c.Request.Context(), id, input.DuplicateIDs, ifMatch(c))
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.Header("ETag", songETag(song.ID, song.Version))
+	c.JSON(http.StatusOK, song.Song)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/service"
+)
+
+// songETag формирует ETag песни по её идентификатору и версии
+func songETag(id, version int) string {
+	return fmt.Sprintf(`"%d-%d"`, id, version)
+}
+
+// etagMatches проверяет, совпадает ли etag с одним из значений заголовка
+// If-Match или If-None-Match. Для If-Match используется строгое сравнение
+// (слабые ETag не совпадают никогда), для If-None-Match - слабое
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch возвращает условие на версию песни из заголовка If-Match или nil,
+// если заголовка нет. Несовпадение версии сервис сообщает ошибкой, на которую
+// отвечается 412, отсутствие обязательного заголовка - 428
+func ifMatch(c *gin.Context) service.Precondition {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return nil
+	}
+
+	log := logger.FromContext(c.Request.Context())
+	return func(current models.Song) bool {
+		etag := songETag(current.ID, current.Version)
+		if !etagMatches(header, etag, false) {
+			log.Infof("Precondition failed for song with ID %d: If-Match %s, current ETag %s", current.ID, header, etag)
+			return false
+		}
+		return true
+	}
+}
+
+// notModified проверяет заголовок If-None-Match и, если версия песни не изменилась,
+// отвечает 304 и возвращает true
+func notModified(c *gin.Context, id, version int) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" || !etagMatches(header, songETag(id, version), true) {
+		return false
+	}
+
+	logger.FromContext(c.Request.Context()).Debugf("Song with ID %d not modified", id)
+	c.Header("ETag", songETag(id, version))
+	cacheControl(c)
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// cacheControl задаёт заголовок Cache-Control ответа на чтение песен: max-age из
+// CACHE_MAX_AGE или no-cache, если он 0, - тогда клиент проверяет актуальность по ETag
+func cacheControl(c *gin.Context) {
+	maxAge := config.Get().Cache.MaxAge.Std()
+	if maxAge <= 0 {
+		c.Header("Cache-Control", "no-cache")
+		return
+	}
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+}
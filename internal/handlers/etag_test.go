@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+func TestETagMatches(t *testing.T) {
+	etag := songETag(7, 3)
+	tests := []struct {
+		name   string
+		header string
+		weak   bool
+		want   bool
+	}{
+		{"strong equal", `"7-3"`, false, true},
+		{"strong other version", `"7-2"`, false, false},
+		{"strong other song", `"8-3"`, false, false},
+		{"weak header in strong comparison", `W/"7-3"`, false, false},
+		{"weak header in weak comparison", `W/"7-3"`, true, true},
+		{"strong header in weak comparison", `"7-3"`, true, true},
+		{"any", `*`, false, true},
+		{"any weak", ` * `, true, true},
+		{"list", `"7-1", "7-2", "7-3"`, false, true},
+		{"list without spaces", `"7-1","7-3"`, true, true},
+		{"list without match", `"7-1", W/"7-2"`, true, false},
+		{"list with weak match in strong comparison", `"7-1", W/"7-3"`, false, false},
+		{"list with weak match in weak comparison", `"7-1", W/"7-3"`, true, true},
+		{"unquoted", `7-3`, false, false},
+		{"empty list elements", `, ,"7-3"`, false, true},
+		{"lowercase weak prefix", `w/"7-3"`, true, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, etag, tt.weak); got != tt.want {
+			t.Errorf("%s: etagMatches(%q, %q, %v) = %v, want %v", tt.name, tt.header, etag, tt.weak, got, tt.want)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{`"7-3"`, true},
+		{`W/"7-3"`, true},
+		{`"7-1", W/"7-3"`, true},
+		{`"7-2"`, false},
+		{`*`, true},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodGet, "/songs/7", nil)
+		if tt.header != "" {
+			c.Request.Header.Set("If-None-Match", tt.header)
+		}
+
+		got := notModified(c, 7, 3)
+		c.Writer.WriteHeaderNow()
+		if got != tt.want {
+			t.Errorf("If-None-Match %q: notModified = %v, want %v", tt.header, got, tt.want)
+			continue
+		}
+		if got && (rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != `"7-3"`) {
+			t.Errorf("If-None-Match %q: status %d, ETag %q, want 304 with the current ETag", tt.header, rec.Code, rec.Header().Get("ETag"))
+		}
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	current := models.Song{ID: 7, Version: 3}
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"7-3"`, true},
+		{`"7-2", "7-3"`, true},
+		{`W/"7-3"`, false},
+		{`"7-2"`, false},
+		{`*`, true},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPut, "/songs/7", nil)
+		c.Request.Header.Set("If-Match", tt.header)
+
+		if got := ifMatch(c)(current); got != tt.want {
+			t.Errorf("If-Match %q: precondition = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPut, "/songs/7", nil)
+	if ifMatch(c) != nil {
+		t.Error("precondition without If-Match, want nil")
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/health"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/service"
+)
+
+const (
+	// eventsBatchSize - сколько событий журнала читается за один запрос
+	eventsBatchSize = 500
+	// eventsPollInterval - пауза между проверками новых событий
+	eventsPollInterval = time.Second
+	// eventsHeartbeatInterval - период отправки комментария, удерживающего соединение
+	eventsHeartbeatInterval = 15 * time.Second
+	// eventsRetryMs - через сколько миллисекунд клиенту переподключаться после обрыва
+	eventsRetryMs = 3000
+)
+
+// StreamEvents отправляет события изменения библиотеки потоком Server-Sent Events
+// @Summary Stream library change events
+// @Description Streams song and group change events (song.created, song.updated, song.deleted, song.merged, group.created, group.updated, group.deleted) as Server-Sent Events. Every event has the SSE id of its position in the persisted change log, which increases in commit order, the event type as the SSE event name and the event envelope as JSON data. Send Last-Event-ID (or the lastEventId query parameter) to resume after a reconnect and receive every event after that position; without it only new events are streamed. Events can be filtered by type and by group name (case-insensitive).
+// @Tags events
+// @Produce text/event-stream
+// @Param Last-Event-ID header int false "SSE id (change log position) of the last received event"
+// @Param lastEventId query int false "SSE id of the last received event, for clients that cannot set headers"
+// @Param type query string false "Comma-separated event types to stream"
+// @Param group query string false "Only events of songs of this group and of the group itself"
+// @Success 200 {object} models.Event "Event stream"
+// @Failure 400 {object} models.ErrorResponse "Invalid Last-Event-ID or unknown event type"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database or read the change log"
+// @Router /api/v1/events [get]
+func StreamEvents(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting StreamEvents handler")
+
+	filter := service.EventFilter{Group: strings.TrimSpace(c.Query("group"))}
+	if value := c.Query("type"); value != "" {
+		for _, eventType := range strings.Split(value, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if !models.IsEventType(eventType) {
+				log.Errorf("Unknown event type: %s", eventType)
+				respondError(c, http.StatusBadRequest, "Unknown event type: "+eventType)
+				return
+			}
+			filter.Types = append(filter.Types, eventType)
+		}
+	}
+
+	// Позиция последнего полученного события: заголовок EventSource при переподключении
+	// или параметр запроса при первом подключении
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+	var lastID int64 = -1
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			log.Errorf("Invalid Last-Event-ID: %s", lastEventID)
+			respondError(c, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+		lastID = id
+	}
+
+	ctx := c.Request.Context()
+
+	// Без Last-Event-ID отправляем только события, появившиеся после подключения.
+	// События, которым позиция ещё не назначена, получат её позже и попадут в поток.
+	// Позиции назначает диспетчер outbox, поток их только читает
+	if lastID < 0 {
+		seq, err := library.LastEventSeq(ctx)
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+		lastID = seq
+	}
+
+	log.Debugf("Streaming events after %d: types=%v, group=%q", lastID, filter.Types, filter.Group)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	disableWriteTimeout(c)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", eventsRetryMs)
+	c.Writer.Flush()
+
+	poll := time.NewTicker(eventsPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(eventsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		// Отправляем все накопившиеся события, пачками
+		for {
+			events, err := library.ReadEvents(ctx, lastID, filter, eventsBatchSize)
+			if err != nil {
+				return
+			}
+
+			for _, event := range events {
+				if err := writeEvent(c.Writer, event); err != nil {
+					log.Debugf("Event stream closed: %v", err)
+					return
+				}
+				lastID = event.Seq
+			}
+			if len(events) > 0 {
+				c.Writer.Flush()
+			}
+			if len(events) < eventsBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Debugf("Client disconnected from event stream at event %d", lastID)
+			return
+		case <-health.Stopping():
+			// Клиент переподключится к другому экземпляру с Last-Event-ID
+			log.Debugf("Closing event stream at event %d: service is shutting down", lastID)
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-poll.C:
+		}
+	}
+}
+
+// writeEvent записывает событие в формате text/event-stream. id в потоке - позиция
+// события в журнале, по ней клиент продолжает поток после переподключения
+func writeEvent(w gin.ResponseWriter, item service.ChangeEvent) error {
+	data, err := json.Marshal(item.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", item.Seq, item.Event.Type, data)
+	return err
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/exporter"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/service"
+)
+
+// exportFlushEvery - через сколько песен выгрузка отправляется клиенту
+const exportFlushEvery = 100
+
+// ExportSongs выгружает песни библиотеки потоком
+// @Summary Export songs
+// @Description Streams all songs matching the same filters as the songs list, ordered by ID, as CSV (columns id, group, song, releaseDate, text, link), NDJSON or a JSON array. CSV and NDJSON exports can be imported back via /songs/import.
+// @Tags import
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce json
+// @Produce application/gzip
+// @Param format query string false "Export format: csv, ndjson or json" default(csv)
+// @Param gzip query bool false "Compress the export with gzip" default(false)
+// @Param groupName query string false "Group name for filtering"
+// @Param song query string false "Song name for filtering"
+// @Param releaseDate query string false "Release date for filtering"
+// @Param text query string false "Text for filtering"
+// @Param link query string false "Link for filtering"
+// @Success 200 {file} file "Songs export"
+// @Failure 400 {object} models.ErrorResponse "Unsupported format, invalid gzip parameter or release date"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database or retrieve songs"
+// @Router /api/v1/songs/export [get]
+func ExportSongs(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting ExportSongs handler")
+
+	format := c.DefaultQuery("format", exporter.FormatCSV)
+	contentType, extension, err := exporter.ContentType(format)
+	if err != nil {
+		log.Errorf("Invalid export format: %v", err)
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	compress, err := strconv.ParseBool(c.DefaultQuery("gzip", "false"))
+	if err != nil {
+		log.Errorf("Invalid gzip parameter: %v", err)
+		respondError(c, http.StatusBadRequest, "Invalid gzip parameter")
+		return
+	}
+
+	log.Debugf("Request to export songs: format=%s, gzip=%t", format, compress)
+
+	// Ответ начинается с первой песней, поэтому ошибка запроса ещё может быть
+	// передана статусом. После начала ответа изменить статус уже нельзя,
+	// поэтому ошибки только логируются, а выгрузка обрывается
+	filename := "songs." + extension
+	if compress {
+		contentType = "application/gzip"
+		filename += ".gz"
+	}
+
+	var writer exporter.Writer
+	var gz *gzip.Writer
+	started := false
+	start := func() error {
+		started = true
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Status(http.StatusOK)
+		disableWriteTimeout(c)
+
+		var out io.Writer = c.Writer
+		if compress {
+			gz = gzip.NewWriter(c.Writer)
+			out = gz
+		}
+		writer, err = exporter.NewWriter(out, format)
+		return err
+	}
+	defer func() {
+		if gz != nil {
+			gz.Close()
+		}
+	}()
+
+	count := 0
+	err = library.EachSong(c.Request.Context(), songFilter(c), 1, 0, func(song service.Song) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write(song.Song); err != nil {
+			return err
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			if gz != nil {
+				gz.Flush()
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err != nil {
+		if !started {
+			respondServiceError(c, err)
+			return
+		}
+		log.Errorf("Failed to export songs: %v", err)
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Errorf("Failed to finish export: %v", err)
+		return
+	}
+
+	log.Infof("Exported %d songs successfully", count)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/service"
+)
+
+func TestExportSongsErrors(t *testing.T) {
+	// Ошибка до первой песни ещё передаётся статусом ответа
+	useLibrary(t, service.NewLibrary(func() (*sql.DB, error) {
+		return nil, errors.New("no database")
+	}, nil))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/songs/export", ExportSongs)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"format=xml", http.StatusBadRequest},
+		{"gzip=maybe", http.StatusBadRequest},
+		{"format=json&releaseDate=yesterday", http.StatusBadRequest},
+		{"format=ndjson&gzip=true", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/songs/export?"+tt.query, nil))
+
+		if rec.Code != tt.want || rec.Header().Get("Content-Disposition") != "" {
+			t.Errorf("%s: status %d, Content-Disposition %q, want %d without an attachment",
+				tt.query, rec.Code, rec.Header().Get("Content-Disposition"), tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/service"
+)
+
+// GetSong возвращает песню по её ID
+// @Summary Get a song by ID
+// @Description Retrieves the full song resource including group, release date, text, link and version
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param If-None-Match header string false "ETag of a cached version of the song"
+// @Success 200 {object} models.Song "Song retrieved successfully"
+// @Header 200 {string} ETag "Version tag of the song"
+// @Header 200 {string} Cache-Control "no-cache, or public with max-age from CACHE_MAX_AGE"
+// @Success 304 "Song has not been modified"
+// @Success 308 "Song was merged into another song, Location points to the same resource of that song"
+// @Header 308 {string} Location "Path of the song the requested song was merged into"
+// @Failure 400 {object} models.ErrorResponse "Invalid song ID"
+// @Failure 404 {object} models.ErrorResponse "Song not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database or retrieve song"
+// @Router /api/v1/songs/{id} [get]
+func GetSong(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting GetSong handler")
+
+	id, ok := parseSongID(c)
+	if !ok {
+		return
+	}
+
+	log.Debugf("Request to get song with ID: %d", id)
+
+	song, err := library.GetSong(c.Request.Context(), id)
+	if errors.Is(err, service.ErrNotFound) && redirectMerged(c, id) {
+		return
+	} else if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	// Если у клиента актуальная версия песни, возвращаем 304
+	if notModified(c, song.ID, song.Version) {
+		return
+	}
+
+	log.Infof("Song with ID %d retrieved successfully", id)
+
+	c.Header("ETag", songETag(song.ID, song.Version))
+	cacheControl(c)
+	c.JSON(http.StatusOK, song.Song)
+}
@@ -1,15 +1,13 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
-	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/inanmasov/music-service/internal/db"
 	"github.com/inanmasov/music-service/internal/logger"
-	_ "github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/service"
 )
 
 // GetSongText возвращает текст песни с пагинацией по куплетам
@@ -19,94 +17,73 @@ import (
 // @Param id path int true "Song ID"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Number of verses per page" default(2)
-// @Success 200 {object} map[string]string "Song text retrieved successfully"
-// @Failure 400 {object} models.ErrorResponse "Invalid page or limit number"
-// @Failure 404 {object} models.ErrorResponse "Song not found or no verses on this page"
+// @Param If-None-Match header string false "ETag of a cached version of the song"
+// @Success 200 {object} models.Page[models.Verse] "Song text retrieved successfully"
+// @Header 200 {string} ETag "Version tag of the song"
+// @Header 200 {string} Cache-Control "no-cache, or public with max-age from CACHE_MAX_AGE"
+// @Success 304 "Song has not been modified"
+// @Success 308 "Song was merged into another song, Location points to the same resource of that song"
+// @Header 308 {string} Location "Path of the song the requested song was merged into"
+// @Failure 400 {object} models.ErrorResponse "Invalid song ID, page or limit number"
+// @Failure 404 {object} models.ErrorResponse "Song not found"
 // @Failure 500 {object} models.ErrorResponse "Failed to connect to database or retrieve song text"
-// @Router /songs/{id}/text [get]
+// @Router /api/v1/songs/{id}/text [get]
 func GetSongText(c *gin.Context) {
-	log := logger.GetLogger()
+	log := logger.FromContext(c.Request.Context())
 	log.Info("Starting GetSongText handler")
 
-	// Получаем ID песни из URL параметров
-	id := c.Param("id")
-
-	log.Debugf("Request to get song text with ID: %s", id)
-
-	// Получаем параметры пагинации из URL
-	pageParam := c.DefaultQuery("page", "1")
-	limitParam := c.DefaultQuery("limit", "2")
-
-	log.Debugf("Pagination params received: page = %s, limit = %s", pageParam, limitParam)
-
-	// Преобразуем параметры в числа
-	page, err := strconv.Atoi(pageParam)
-	if err != nil || page <= 0 {
-		log.Errorf("Invalid page number: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
-		return
-	}
-	limit, err := strconv.Atoi(limitParam)
-	if err != nil || limit <= 0 {
-		log.Errorf("Invalid limit number: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+	id, ok := parseSongID(c)
+	if !ok {
 		return
 	}
 
-	log.Debugf("Parsed pagination params: page = %d, limit = %d", page, limit)
+	log.Debugf("Request to get song text with ID: %d", id)
 
-	// Подключаемся к базе данных
-	db, err := db.Initialize()
-	if err != nil {
-		log.Errorf("Failed to connect to database: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to database"})
+	// Получаем параметры пагинации из URL, по умолчанию 2 куплета на страницу
+	page, limit, ok := parsePagination(c, service.VersesPerPage)
+	if !ok {
 		return
 	}
-	defer db.Close()
-
-	log.Info("Successfully connected to the database")
 
-	// Выполняем SQL-запрос для получения текста песни
-	var text string
-	err = db.QueryRow("SELECT text FROM songs WHERE id = $1", id).Scan(&text)
-	if err == sql.ErrNoRows {
-		log.Debugf("Song with ID %s not found", id)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+	song, err := library.GetSong(c.Request.Context(), id)
+	if errors.Is(err, service.ErrNotFound) && redirectMerged(c, id) {
 		return
 	} else if err != nil {
-		log.Errorf("Failed to retrieve song text for ID %s: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve song text"})
+		respondServiceError(c, err)
 		return
 	}
 
-	log.Info("Successfully retrieved song text from database")
-
-	// Разбиваем текст песни на куплеты по разделителю (например, "\n\n" между куплетами)
-	verses := strings.Split(text, "\n\n")
-
-	// Вычисляем срез куплетов для текущей страницы
-	start := (page - 1) * limit
-	if start >= len(verses) {
-		log.Debugf("No verses on page %d", page)
-		c.JSON(http.StatusNotFound, gin.H{"error": "No verses on this page"})
+	// Если у клиента актуальная версия песни, возвращаем 304
+	if notModified(c, song.ID, song.Version) {
 		return
 	}
 
-	end := start + limit
-	if end > len(verses) {
-		end = len(verses)
+	verses, total, err := service.Verses(song.Text, page, limit)
+	if err != nil {
+		respondServiceError(c, err)
+		return
 	}
 
-	paginatedVerses := verses[start:end]
+	// В /api/v1 страница за пределами текста возвращается пустой, маршрут
+	// без префикса по-прежнему отвечает 404
+	if len(verses) == 0 && legacyRoute(c) {
+		log.Debugf("No verses on page %d", page)
+		respondError(c, http.StatusNotFound, "No verses on this page")
+		return
+	}
 
-	log.Infof("Text song with ID %s get successfully", id)
+	log.Infof("Text song with ID %d get successfully", id)
 
 	// Возвращаем куплеты в ответе
-	c.JSON(http.StatusOK, gin.H{
-		"page":    page,
-		"limit":   limit,
-		"verses":  paginatedVerses,
-		"total":   len(verses),
-		"message": "Song text retrieved successfully",
-	})
+	c.Header("ETag", songETag(song.ID, song.Version))
+	cacheControl(c)
+	if legacyRoute(c) {
+		c.JSON(http.StatusOK, models.SongText{Page: page, Limit: limit, Total: total, Verses: verses})
+		return
+	}
+	items := make([]models.Verse, len(verses))
+	for i, verse := range verses {
+		items[i] = models.Verse(verse)
+	}
+	c.JSON(http.StatusOK, newPage(c, items, page, limit, total))
 }
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/logger"
+)
+
+// GraphQLRequest - запрос GraphQL в теле POST /graphql
+type GraphQLRequest struct {
+	Query         string                 `json:"query" example:"{ songs(limit: 5) { items { id song group { name } } } }"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLResponse - ответ GraphQL: данные и ошибки с кодом в extensions.code
+type GraphQLResponse struct {
+	Data   interface{}                `json:"data,omitempty" swaggertype:"object"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty" swaggertype:"array,object"`
+}
+
+// GraphQL выполняет запрос GraphQL к библиотеке песен
+// @Summary Execute a GraphQL query
+// @Description Executes a GraphQL query or mutation over songs, groups and verse pages. Queries may be sent with GET (query, operationName and variables parameters) or POST, mutations only with POST. Queries deeper or more complex than the configured limits are rejected with 400 before execution.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param request body GraphQLRequest false "GraphQL request"
+// @Param query query string false "GraphQL query for GET requests"
+// @Param operationName query string false "Operation to execute"
+// @Param variables query string false "JSON-encoded variables"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the first response instead of repeating it"
+// @Success 200 {object} GraphQLResponse "Query executed; field errors are reported in errors"
+// @Failure 400 {object} GraphQLResponse "Malformed query or query limits exceeded"
+// @Failure 405 {object} GraphQLResponse "Mutation sent with GET"
+// @Failure 409 {object} models.ErrorResponse "A request with the same Idempotency-Key is still in progress"
+// @Failure 422 {object} models.ErrorResponse "Idempotency-Key has already been used with a different request"
+// @Router /graphql [post]
+// @Router /graphql [get]
+func GraphQL(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting GraphQL handler")
+
+	var request GraphQLRequest
+	if c.Request.Method == http.MethodGet {
+		request.Query = c.Query("query")
+		request.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				respondGraphQLError(c, http.StatusBadRequest, "variables must be a JSON object")
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&request); err != nil {
+		log.Errorf("Failed to bind GraphQL request: %v", err)
+		respondGraphQLError(c, http.StatusBadRequest, "Malformed request body")
+		return
+	}
+
+	if request.Query == "" {
+		respondGraphQLError(c, http.StatusBadRequest, "query must not be empty")
+		return
+	}
+
+	// Разбираем запрос заранее, чтобы отклонить мутации через GET и слишком
+	// глубокие или сложные запросы до обращения к базе данных
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(request.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, GraphQLResponse{Errors: withErrorCode(gqlerrors.FormatErrors(err), http.StatusBadRequest)})
+		return
+	}
+
+	op, err := findOperation(doc, request.OperationName)
+	if err != nil {
+		respondGraphQLError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if op.Operation == ast.OperationTypeMutation && c.Request.Method == http.MethodGet {
+		c.Header("Allow", http.MethodPost)
+		respondGraphQLError(c, http.StatusMethodNotAllowed, "Mutations must be sent with POST")
+		return
+	}
+
+	schema, err := graphQLSchema()
+	if err != nil {
+		log.Errorf("Failed to build GraphQL schema: %v", err)
+		respondGraphQLError(c, http.StatusInternalServerError, "Failed to build GraphQL schema")
+		return
+	}
+
+	// Неизвестные поля, неверные аргументы и прочие ошибки документа
+	if validation := graphql.ValidateDocument(&schema, doc, nil); !validation.IsValid {
+		c.JSON(http.StatusBadRequest, GraphQLResponse{Errors: withErrorCode(validation.Errors, http.StatusBadRequest)})
+		return
+	}
+
+	limits := config.Get().GraphQL
+	if err := checkQueryLimits(doc, op, request.Variables, limits.MaxDepth, limits.MaxComplexity); err != nil {
+		log.Warnf("GraphQL query rejected: %v", err)
+		respondGraphQLError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Без базы данных запрос не выполнить, отвечаем сразу, а не ошибкой каждого поля
+	if err := library.CheckDatabase(c.Request.Context()); err != nil {
+		respondGraphQLError(c, serviceStatus(err), serviceMessage(err))
+		return
+	}
+
+	// Загрузчики живут один запрос: данные, загруженные для одних полей,
+	// не видны другим запросам
+	ctx := c.Request.Context()
+	result := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  request.Query,
+		VariableValues: request.Variables,
+		OperationName:  request.OperationName,
+		Context:        context.WithValue(ctx, gqlLoadersKey{}, newGQLLoaders(library)),
+	})
+
+	log.Infof("GraphQL %s executed with %d errors", op.Operation, len(result.Errors))
+
+	// Запрос не выполнялся, если нет данных и ни один резолвер не вернул
+	// ошибку с кодом: так graphql-go сообщает о неверных значениях переменных
+	status := http.StatusOK
+	if result.Data == nil && !hasErrorCode(result.Errors) {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, GraphQLResponse{Data: result.Data, Errors: withErrorCode(result.Errors, status)})
+}
+
+// respondGraphQLError отвечает ошибкой в формате GraphQL со статусом status
+func respondGraphQLError(c *gin.Context, status int, message string) {
+	c.JSON(status, GraphQLResponse{Errors: []gqlerrors.FormattedError{{
+		Message:    message,
+		Locations:  []location.SourceLocation{},
+		Extensions: map[string]interface{}{"code": errorCode(status)},
+	}}})
+}
+
+// withErrorCode добавляет код ошибкам, у которых его нет. Если запрос не
+// выполнялся (status 400), это ошибки документа или переменных, иначе -
+// непредвиденные ошибки выполнения
+func withErrorCode(errs []gqlerrors.FormattedError, status int) []gqlerrors.FormattedError {
+	if status == http.StatusOK {
+		status = http.StatusInternalServerError
+	}
+	for i := range errs {
+		if errs[i].Extensions == nil {
+			errs[i].Extensions = map[string]interface{}{"code": errorCode(status)}
+		}
+	}
+	return errs
+}
+
+// hasErrorCode сообщает, есть ли среди errs ошибки резолверов с кодом
+func hasErrorCode(errs []gqlerrors.FormattedError) bool {
+	for _, err := range errs {
+		if err.Extensions != nil {
+			return true
+		}
+	}
+	return false
+}
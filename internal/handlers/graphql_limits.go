@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/inanmasov/music-service/internal/service"
+)
+
+// gqlListSizes - размер страницы по умолчанию для полей-списков. Сложность
+// вложенных в такое поле полей умножается на аргумент limit или на это значение
+var gqlListSizes = map[string]int{
+	"songs":  10,
+	"groups": 10,
+	"verses": service.VersesPerPage,
+}
+
+// errNoOperation - в документе нет операции с запрошенным именем
+var errNoOperation = errors.New("operation not found")
+
+// queryCost вычисляет глубину и сложность операции запроса GraphQL
+type queryCost struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	visiting  map[string]bool
+	// ceiling - значение, выше которого сложность не вычисляется, чтобы большие
+	// limit не приводили к переполнению
+	ceiling int
+}
+
+// findOperation возвращает операцию документа с именем name, либо единственную
+// операцию, если имя не задано
+func findOperation(doc *ast.Document, name string) (*ast.OperationDefinition, error) {
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil, errors.New("operationName is required for a document with several operations")
+			}
+			found = op
+		} else if op.Name != nil && op.Name.Value == name {
+			return op, nil
+		}
+	}
+	if found == nil {
+		return nil, errNoOperation
+	}
+	return found, nil
+}
+
+// checkQueryLimits проверяет, что глубина и сложность операции op не превышают
+// maxDepth и maxComplexity. Служебные поля интроспекции (__schema, __type...) не учитываются
+func checkQueryLimits(doc *ast.Document, op *ast.OperationDefinition, variables map[string]interface{}, maxDepth, maxComplexity int) error {
+	cost := queryCost{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+		visiting:  make(map[string]bool),
+		ceiling:   maxComplexity + 1,
+	}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok && fragment.Name != nil {
+			cost.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	depth, complexity := cost.selectionSet(op.SelectionSet, 0)
+	if depth > maxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", depth, maxDepth)
+	}
+	if complexity > maxComplexity {
+		return fmt.Errorf("query complexity exceeds the limit of %d", maxComplexity)
+	}
+	return nil
+}
+
+// selectionSet возвращает наибольшую глубину и суммарную сложность полей set,
+// находящихся на глубине depth
+func (q *queryCost) selectionSet(set *ast.SelectionSet, depth int) (maxDepth, complexity int) {
+	if set == nil {
+		return depth, 0
+	}
+
+	maxDepth = depth
+	for _, selection := range set.Selections {
+		var childDepth, childComplexity int
+		switch node := selection.(type) {
+		case *ast.Field:
+			if node.Name == nil || strings.HasPrefix(node.Name.Value, "__") {
+				continue
+			}
+			childDepth, childComplexity = q.selectionSet(node.SelectionSet, depth+1)
+			childComplexity = min(1+q.listSize(node)*childComplexity, q.ceiling)
+		case *ast.InlineFragment:
+			childDepth, childComplexity = q.selectionSet(node.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			fragment := q.fragments[node.Name.Value]
+			// Циклы фрагментов отклонит проверка документа, здесь их достаточно не обходить
+			if fragment == nil || q.visiting[node.Name.Value] {
+				continue
+			}
+			q.visiting[node.Name.Value] = true
+			childDepth, childComplexity = q.selectionSet(fragment.SelectionSet, depth)
+			delete(q.visiting, node.Name.Value)
+		}
+
+		maxDepth = max(maxDepth, childDepth)
+		complexity = min(complexity+childComplexity, q.ceiling)
+	}
+	return maxDepth, complexity
+}
+
+// listSize возвращает множитель сложности вложенных полей field: 1 для
+// полей-значений, аргумент limit или размер страницы по умолчанию для списков
+func (q *queryCost) listSize(field *ast.Field) int {
+	size, ok := gqlListSizes[field.Name.Value]
+	if !ok {
+		return 1
+	}
+
+	for _, arg := range field.Arguments {
+		if arg.Name == nil || arg.Name.Value != "limit" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil {
+				size = n
+			}
+		case *ast.Variable:
+			// Числа из JSON переменных запроса разбираются как float64
+			switch n := q.variables[value.Name.Value].(type) {
+			case float64:
+				size = int(n)
+			case int:
+				size = n
+			}
+		}
+	}
+	return min(max(size, 1), q.ceiling)
+}
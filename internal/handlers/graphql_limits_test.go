@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/service"
+)
+
+// nestedQuery - запрос глубины 6: песни, их группы и песни групп
+const nestedQuery = `{ songs(limit: 3) { items { group { songs(limit: 4) { items { id } } } } } }`
+
+// parseOperation разбирает query и возвращает документ и операцию operationName
+func parseOperation(t *testing.T, query, operationName string) (*ast.Document, *ast.OperationDefinition) {
+	t.Helper()
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", query, err)
+	}
+	op, err := findOperation(doc, operationName)
+	if err != nil {
+		t.Fatalf("findOperation(%q): %v", query, err)
+	}
+	return doc, op
+}
+
+func TestQueryCost(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		variables      map[string]interface{}
+		wantDepth      int
+		wantComplexity int
+	}{
+		{"scalar fields", `{ song(id: 1) { id song } }`, nil, 2, 3},
+		{"list limit", `{ songs(limit: 2) { items { id song } } }`, nil, 3, 7},
+		{"default page size", `{ songs { items { id } } }`, nil, 3, 21},
+		{"variable limit", `query($n: Int) { songs(limit: $n) { items { id } } }`, map[string]interface{}{"n": float64(5)}, 3, 11},
+		{"missing variable", `query($n: Int) { songs(limit: $n) { items { id } } }`, nil, 3, 21},
+		{"limit below one", `{ songs(limit: 0) { items { id } } }`, nil, 3, 3},
+		{"fragment spread", `{ songs(limit: 2) { ...page } } fragment page on SongPage { items { id } }`, nil, 3, 5},
+		{"inline fragment", `{ songs(limit: 2) { ... on SongPage { items { id } } } }`, nil, 3, 5},
+		{"introspection", `{ __schema { types { name } } song(id: 1) { id } }`, nil, 2, 2},
+		{"nested lists", nestedQuery, nil, 6, 34},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, op := parseOperation(t, tt.query, "")
+			cost := queryCost{fragments: map[string]*ast.FragmentDefinition{}, variables: tt.variables, visiting: map[string]bool{}, ceiling: 1 << 30}
+			for _, def := range doc.Definitions {
+				if fragment, ok := def.(*ast.FragmentDefinition); ok {
+					cost.fragments[fragment.Name.Value] = fragment
+				}
+			}
+			depth, complexity := cost.selectionSet(op.SelectionSet, 0)
+			if depth != tt.wantDepth || complexity != tt.wantComplexity {
+				t.Errorf("depth %d, complexity %d, want %d and %d", depth, complexity, tt.wantDepth, tt.wantComplexity)
+			}
+		})
+	}
+}
+
+func TestCheckQueryLimits(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		maxDepth      int
+		maxComplexity int
+		wantErr       string
+	}{
+		{"normal query", nestedQuery, 8, 5000, ""},
+		{"at the limits", nestedQuery, 6, 34, ""},
+		{"too deep", nestedQuery, 5, 5000, "query depth 6 exceeds the limit of 5"},
+		{"too complex", nestedQuery, 8, 33, "query complexity exceeds the limit of 33"},
+		{
+			name:          "huge limits do not overflow",
+			query:         `{ songs(limit: 2147483647) { items { group { songs(limit: 2147483647) { items { group { songs(limit: 2147483647) { items { id } } } } } } } } }`,
+			maxDepth:      20,
+			maxComplexity: 5000,
+			wantErr:       "query complexity exceeds the limit of 5000",
+		},
+		{"recursive fragments", `{ songs { ...a } } fragment a on SongPage { ...a items { id } }`, 8, 5000, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, op := parseOperation(t, tt.query, "")
+			err := checkQueryLimits(doc, op, nil, tt.maxDepth, tt.maxComplexity)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindOperation(t *testing.T) {
+	doc, err := parser.Parse(parser.ParseParams{Source: `query A { songs { items { id } } } query B { groups { items { id } } }`})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if op, err := findOperation(doc, "B"); err != nil || op.Name.Value != "B" {
+		t.Errorf("findOperation(B) = %v, %v", op, err)
+	}
+	if _, err := findOperation(doc, ""); err == nil {
+		t.Error("operation without a name in a document with several operations was accepted")
+	}
+	if _, err := findOperation(doc, "C"); !errors.Is(err, errNoOperation) {
+		t.Errorf("findOperation(C) error = %v, want errNoOperation", err)
+	}
+}
+
+func TestGraphQLQueryLimits(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/music")
+	t.Setenv("GRAPHQL_MAX_DEPTH", "5")
+	t.Setenv("GRAPHQL_MAX_COMPLEXITY", "100")
+	if _, err := config.Load(""); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	// Запрос в пределах ограничений доходит до базы данных, которой нет
+	useLibrary(t, service.NewLibrary(func() (*sql.DB, error) {
+		return nil, errors.New("no database")
+	}, nil))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/graphql", GraphQL)
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{"too deep", nestedQuery, "query depth 6 exceeds the limit of 5"},
+		{"too complex", `{ songs(limit: 50) { items { id song text } } }`, "query complexity exceeds the limit of 100"},
+		{"normal", `{ songs(limit: 5) { items { id song } } }`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(GraphQLRequest{Query: tt.query})
+			req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			var response GraphQLResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response %s: %v", rec.Body, err)
+			}
+			if tt.wantErr != "" {
+				if rec.Code != http.StatusBadRequest || len(response.Errors) != 1 || response.Errors[0].Message != tt.wantErr {
+					t.Errorf("status %d, body %s, want 400 with %q", rec.Code, rec.Body, tt.wantErr)
+				}
+				return
+			}
+			if rec.Code == http.StatusBadRequest || strings.Contains(rec.Body.String(), "exceeds the limit") {
+				t.Errorf("normal query rejected: status %d, body %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
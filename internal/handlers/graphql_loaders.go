@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/service"
+)
+
+// batchLoader откладывает загрузку по ключам, запрошенным резолверами одного уровня
+// запроса GraphQL, и загружает все накопленные ключи одним запросом при первом
+// обращении к результату. Резолвер возвращает исполнителю функцию из Load, а
+// исполнитель вызывает такие функции после обхода всех полей уровня. Результаты
+// кэшируются до конца запроса
+type batchLoader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	loaded  map[K]V
+	failed  map[K]error
+}
+
+func newBatchLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{
+		fetch:  fetch,
+		queued: make(map[K]bool),
+		loaded: make(map[K]V),
+		failed: make(map[K]error),
+	}
+}
+
+// Load ставит key в очередь и возвращает функцию, получающую значение по ключу.
+// Для ключа, которого нет в базе, возвращается нулевое значение V
+func (l *batchLoader[K, V]) Load(ctx context.Context, key K) func() (V, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if _, ok := l.loaded[key]; !ok && l.failed[key] == nil {
+			l.dispatch(ctx)
+		}
+		return l.loaded[key], l.failed[key]
+	}
+}
+
+// dispatch загружает все ключи из очереди. Вызывается под l.mu
+func (l *batchLoader[K, V]) dispatch(ctx context.Context) {
+	keys := l.pending
+	l.pending = nil
+
+	values, err := l.fetch(ctx, keys)
+	for _, key := range keys {
+		if err != nil {
+			l.failed[key] = err
+			continue
+		}
+		l.loaded[key] = values[key]
+	}
+}
+
+// groupSongsKey - страница песен группы
+type groupSongsKey struct {
+	GroupID int
+	Page    int
+	Limit   int
+}
+
+// gqlLoaders - загрузчики одного запроса GraphQL
+type gqlLoaders struct {
+	groups     *batchLoader[int, *models.Group]
+	songCounts *batchLoader[int, int]
+	groupSongs *batchLoader[groupSongsKey, []service.Song]
+}
+
+// gqlLoadersKey - ключ контекста с загрузчиками запроса
+type gqlLoadersKey struct{}
+
+// newGQLLoaders создаёт загрузчики, читающие данные библиотеки lib
+func newGQLLoaders(lib *service.Library) *gqlLoaders {
+	return &gqlLoaders{
+		groups:     newBatchLoader(lib.GroupsByID),
+		songCounts: newBatchLoader(lib.GroupSongCounts),
+		groupSongs: newBatchLoader(func(ctx context.Context, keys []groupSongsKey) (map[groupSongsKey][]service.Song, error) {
+			return loadGroupSongs(ctx, lib, keys)
+		}),
+	}
+}
+
+// loadersFrom возвращает загрузчики запроса из контекста
+func loadersFrom(ctx context.Context) *gqlLoaders {
+	return ctx.Value(gqlLoadersKey{}).(*gqlLoaders)
+}
+
+// loadGroupSongs загружает страницы песен групп: по одному запросу на каждый
+// размер и номер страницы, а не на каждую группу
+func loadGroupSongs(ctx context.Context, lib *service.Library, keys []groupSongsKey) (map[groupSongsKey][]service.Song, error) {
+	type window struct{ page, limit int }
+	groupIDs := make(map[window][]int)
+	for _, key := range keys {
+		w := window{key.Page, key.Limit}
+		groupIDs[w] = append(groupIDs[w], key.GroupID)
+	}
+
+	result := make(map[groupSongsKey][]service.Song, len(keys))
+	for w, ids := range groupIDs {
+		songs, err := lib.GroupSongs(ctx, ids, w.page, w.limit)
+		if err != nil {
+			return nil, err
+		}
+		for groupID, page := range songs {
+			result[groupSongsKey{GroupID: groupID, Page: w.page, Limit: w.limit}] = page
+		}
+	}
+	return result, nil
+}
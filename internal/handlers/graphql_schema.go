@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/service"
+)
+
+// gqlError - ошибка операции в ответе GraphQL. Код в extensions.code
+// определяется по HTTP-статусу ошибки: not_found, bad_request...
+type gqlError struct {
+	err error
+}
+
+func (e *gqlError) Error() string {
+	return serviceMessage(e.err)
+}
+
+// Extensions возвращает расширения ошибки для ответа GraphQL
+func (e *gqlError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": errorCode(serviceStatus(e.err))}
+	var svcErr *service.Error
+	if errors.As(e.err, &svcErr) {
+		if fields := svcErr.Fields(); len(fields) > 0 {
+			extensions["fields"] = fields
+		}
+	}
+	return extensions
+}
+
+// gqlPage - страница списка в ответе GraphQL
+type gqlPage struct {
+	Items      interface{}       `json:"items"`
+	Pagination models.Pagination `json:"pagination"`
+}
+
+// newGQLPage собирает страницу списка так же, как newPage для REST
+func newGQLPage(items interface{}, page, limit, total int) gqlPage {
+	return gqlPage{
+		Items: items,
+		Pagination: models.Pagination{
+			Page:    page,
+			Limit:   limit,
+			Total:   total,
+			HasMore: page*limit < total,
+		},
+	}
+}
+
+// graphQLSchema возвращает схему GraphQL, построенную при первом обращении
+var graphQLSchema = sync.OnceValues(buildGraphQLSchema)
+
+// buildGraphQLSchema строит схему: песни, группы и страницы куплетов, запросы
+// с теми же фильтрами и пагинацией, что у REST API, и мутации песен
+func buildGraphQLSchema() (graphql.Schema, error) {
+	paginationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Pagination",
+		Description: "Position of a page in a list",
+		Fields: graphql.Fields{
+			"page":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"limit":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"total":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"hasMore": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+	pageType := func(name string, item graphql.Type) *graphql.Object {
+		return graphql.NewObject(graphql.ObjectConfig{
+			Name: name,
+			Fields: graphql.Fields{
+				"items":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(item)))},
+				"pagination": &graphql.Field{Type: graphql.NewNonNull(paginationType)},
+			},
+		})
+	}
+	pageFieldArgs := func(limit int) graphql.FieldConfigArgument {
+		return graphql.FieldConfigArgument{
+			"page":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+			"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: limit},
+		}
+	}
+
+	versePageType := pageType("VersePage", graphql.String)
+
+	var songPageType *graphql.Object
+	groupType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Group",
+		Description: "Music group",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"name":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"songCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: gqlResolve(resolveGroupSongCount)},
+				"songs": &graphql.Field{
+					Type:    graphql.NewNonNull(songPageType),
+					Args:    pageFieldArgs(gqlListSizes["songs"]),
+					Resolve: gqlResolve(resolveGroupSongs),
+				},
+			}
+		}),
+	})
+
+	songType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Song",
+		Description: "Song of the library",
+		Fields: graphql.Fields{
+			"id":          songField(graphql.NewNonNull(graphql.Int), func(s service.Song) interface{} { return s.ID }),
+			"song":        songField(graphql.NewNonNull(graphql.String), func(s service.Song) interface{} { return s.SongName }),
+			"groupName":   songField(graphql.NewNonNull(graphql.String), func(s service.Song) interface{} { return s.GroupName }),
+			"releaseDate": songField(graphql.String, func(s service.Song) interface{} { return formatReleaseDate(s.ReleaseDate) }),
+			"text":        songField(graphql.NewNonNull(graphql.String), func(s service.Song) interface{} { return s.Text }),
+			"link":        songField(graphql.NewNonNull(graphql.String), func(s service.Song) interface{} { return s.Link }),
+			"version":     songField(graphql.NewNonNull(graphql.Int), func(s service.Song) interface{} { return s.Version }),
+			"group":       &graphql.Field{Type: graphql.NewNonNull(groupType), Resolve: gqlResolve(resolveSongGroup)},
+			"verses": &graphql.Field{
+				Type:    graphql.NewNonNull(versePageType),
+				Args:    pageFieldArgs(gqlListSizes["verses"]),
+				Resolve: gqlResolve(resolveSongVerses),
+			},
+		},
+	})
+	songPageType = pageType("SongPage", songType)
+	groupPageType := pageType("GroupPage", groupType)
+
+	songsArgs := pageFieldArgs(gqlListSizes["songs"])
+	for _, name := range []string{"groupName", "song", "releaseDate", "text", "link"} {
+		songsArgs[name] = &graphql.ArgumentConfig{Type: graphql.String}
+	}
+	groupsArgs := pageFieldArgs(gqlListSizes["groups"])
+	groupsArgs["name"] = &graphql.ArgumentConfig{Type: graphql.String}
+	idArgs := graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"songs":  &graphql.Field{Type: graphql.NewNonNull(songPageType), Args: songsArgs, Resolve: gqlResolve(resolveSongs)},
+			"song":   &graphql.Field{Type: songType, Args: idArgs, Resolve: gqlResolve(resolveSong)},
+			"groups": &graphql.Field{Type: graphql.NewNonNull(groupPageType), Args: groupsArgs, Resolve: gqlResolve(resolveGroups)},
+			"group":  &graphql.Field{Type: groupType, Args: idArgs, Resolve: gqlResolve(resolveGroup)},
+		},
+	})
+
+	songInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "SongInput",
+		Description: "Full song data; omitted optional fields (releaseDate, text, link) are cleared",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"group":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"song":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"releaseDate": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "YYYY-MM-DD"},
+			"text":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"link":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+	versionArgConfig := &graphql.ArgumentConfig{
+		Type:        graphql.Int,
+		Description: "Expected song version, the counterpart of If-Match",
+	}
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"addSong": &graphql.Field{
+				Type: graphql.NewNonNull(songType),
+				Args: graphql.FieldConfigArgument{
+					"group": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"song":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: gqlResolve(resolveAddSong),
+			},
+			"updateSong": &graphql.Field{
+				Type: graphql.NewNonNull(songType),
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(songInputType)},
+					"version": versionArgConfig,
+				},
+				Resolve: gqlResolve(resolveUpdateSong),
+			},
+			"deleteSong": &graphql.Field{
+				Type: graphql.NewNonNull(songType),
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"version": versionArgConfig,
+				},
+				Resolve: gqlResolve(resolveDeleteSong),
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// songField - поле песни, значение которого берётся из service.Song функцией value
+func songField(fieldType graphql.Output, value func(service.Song) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: fieldType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return value(p.Source.(service.Song)), nil
+		},
+	}
+}
+
+// gqlResolve переводит ошибки операций резолвера resolve, в том числе
+// отложенных, в ошибки GraphQL с кодом
+func gqlResolve(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		result, err := resolve(p)
+		if err != nil {
+			return nil, &gqlError{err: err}
+		}
+		if thunk, ok := result.(func() (interface{}, error)); ok {
+			return func() (interface{}, error) {
+				value, err := thunk()
+				if err != nil {
+					return nil, &gqlError{err: err}
+				}
+				return value, nil
+			}, nil
+		}
+		return result, nil
+	}
+}
+
+// formatReleaseDate возвращает дату выхода в формате YYYY-MM-DD или nil, если она не задана
+func formatReleaseDate(date time.Time) interface{} {
+	if date.IsZero() {
+		return nil
+	}
+	return date.Format(time.DateOnly)
+}
+
+// pageArgs возвращает номер и размер страницы из аргументов поля
+func pageArgs(p graphql.ResolveParams) (page, limit int) {
+	page, _ = p.Args["page"].(int)
+	limit, _ = p.Args["limit"].(int)
+	return page, limit
+}
+
+// stringArg возвращает строковый аргумент поля или пустую строку, если он не задан
+func stringArg(p graphql.ResolveParams, name string) string {
+	value, _ := p.Args[name].(string)
+	return value
+}
+
+// versionArg возвращает условие на ожидаемую версию песни или nil, если она не задана
+func versionArg(p graphql.ResolveParams) service.Precondition {
+	if version, ok := p.Args["version"].(int); ok {
+		return service.ExpectVersion(version)
+	}
+	return nil
+}
+
+// resolveSongs возвращает страницу песен с теми же фильтрами, что у GET /songs
+func resolveSongs(p graphql.ResolveParams) (interface{}, error) {
+	page, limit := pageArgs(p)
+	songs, total, err := library.ListSongs(p.Context, service.SongFilter{
+		Group:       stringArg(p, "groupName"),
+		Song:        stringArg(p, "song"),
+		ReleaseDate: stringArg(p, "releaseDate"),
+		Text:        stringArg(p, "text"),
+		Link:        stringArg(p, "link"),
+	}, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newGQLPage(songs, page, limit, total), nil
+}
+
+// resolveSong возвращает песню по ID или null, если её нет
+func resolveSong(p graphql.ResolveParams) (interface{}, error) {
+	song, err := library.GetSong(p.Context, p.Args["id"].(int))
+	if errors.Is(err, service.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return song, nil
+}
+
+// resolveGroups возвращает страницу групп, отфильтрованных по части названия
+func resolveGroups(p graphql.ResolveParams) (interface{}, error) {
+	page, limit := pageArgs(p)
+	groups, total, err := library.ListGroups(p.Context, stringArg(p, "name"), page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newGQLPage(groups, page, limit, total), nil
+}
+
+// resolveGroup возвращает группу по ID или null, если её нет
+func resolveGroup(p graphql.ResolveParams) (interface{}, error) {
+	load := loadersFrom(p.Context).groups.Load(p.Context, p.Args["id"].(int))
+	return func() (interface{}, error) {
+		group, err := load()
+		if err != nil || group == nil {
+			return nil, err
+		}
+		return group, nil
+	}, nil
+}
+
+// resolveSongGroup возвращает группу песни. Группы всех песен страницы загружаются одним запросом
+func resolveSongGroup(p graphql.ResolveParams) (interface{}, error) {
+	song := p.Source.(service.Song)
+	load := loadersFrom(p.Context).groups.Load(p.Context, song.GroupID)
+	return func() (interface{}, error) {
+		group, err := load()
+		if err != nil {
+			return nil, err
+		}
+		if group == nil {
+			return nil, &service.Error{Kind: service.ErrNotFound, Message: "Group not found"}
+		}
+		return group, nil
+	}, nil
+}
+
+// resolveGroupSongCount возвращает число песен группы. Счётчики всех групп
+// страницы загружаются одним запросом
+func resolveGroupSongCount(p graphql.ResolveParams) (interface{}, error) {
+	group := p.Source.(*models.Group)
+	load := loadersFrom(p.Context).songCounts.Load(p.Context, group.ID)
+	return func() (interface{}, error) {
+		return load()
+	}, nil
+}
+
+// resolveGroupSongs возвращает страницу песен группы. Страницы всех групп
+// загружаются одним запросом на каждый размер и номер страницы
+func resolveGroupSongs(p graphql.ResolveParams) (interface{}, error) {
+	page, limit := pageArgs(p)
+	if err := service.CheckPage(page, limit, false); err != nil {
+		return nil, err
+	}
+
+	group := p.Source.(*models.Group)
+	loaders := loadersFrom(p.Context)
+	loadSongs := loaders.groupSongs.Load(p.Context, groupSongsKey{GroupID: group.ID, Page: page, Limit: limit})
+	loadCount := loaders.songCounts.Load(p.Context, group.ID)
+	return func() (interface{}, error) {
+		songs, err := loadSongs()
+		if err != nil {
+			return nil, err
+		}
+		total, err := loadCount()
+		if err != nil {
+			return nil, err
+		}
+		if songs == nil {
+			songs = []service.Song{}
+		}
+		return newGQLPage(songs, page, limit, total), nil
+	}, nil
+}
+
+// resolveSongVerses возвращает страницу куплетов песни
+func resolveSongVerses(p graphql.ResolveParams) (interface{}, error) {
+	page, limit := pageArgs(p)
+	verses, total, err := service.Verses(p.Source.(service.Song).Text, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newGQLPage(verses, page, limit, total), nil
+}
+
+// resolveAddSong добавляет песню. При политике дубликатов return возвращается
+// уже добавленная песня
+func resolveAddSong(p graphql.ResolveParams) (interface{}, error) {
+	song, _, err := library.AddSong(p.Context, p.Args["group"].(string), p.Args["song"].(string))
+	return song, err
+}
+
+// resolveUpdateSong полностью заменяет данные песни
+func resolveUpdateSong(p graphql.ResolveParams) (interface{}, error) {
+	fields := p.Args["input"].(map[string]interface{})
+	releaseDate, _ := fields["releaseDate"].(string)
+	text, _ := fields["text"].(string)
+	link, _ := fields["link"].(string)
+	input, err := service.NewSongInput(fields["group"].(string), fields["song"].(string), releaseDate, text, link)
+	if err != nil {
+		return nil, err
+	}
+	return library.UpdateSong(p.Context, p.Args["id"].(int), input, versionArg(p))
+}
+
+// resolveDeleteSong удаляет песню и возвращает её последнее состояние
+func resolveDeleteSong(p graphql.ResolveParams) (interface{}, error) {
+	return library.DeleteSong(p.Context, p.Args["id"].(int), versionArg(p))
+}
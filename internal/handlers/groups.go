@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+// RenameGroup переименовывает группу
+// @Summary Rename a group
+// @Description Renames the group. The group name is part of every song of the group, so the versions of its songs are incremented and a song.updated event is recorded for each of them along with group.updated. A name that matches another group after normalization (case, punctuation and spacing are ignored) is rejected. Groups are created with their first song and deleted with their last one.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param id path int true "Group ID"
+// @Param group body models.GroupInput true "New group name"
+// @Success 200 {object} models.Group "Renamed group"
+// @Failure 400 {object} models.Problem "Invalid group ID or group data"
+// @Failure 404 {object} models.ErrorResponse "Group not found"
+// @Failure 409 {object} models.ErrorResponse "Another group with the same name already exists"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database or update group"
+// @Router /api/v1/groups/{id} [patch]
+func RenameGroup(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting RenameGroup handler")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		log.Errorf("Invalid group ID: %s", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+
+	var input models.GroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Errorf("Failed to bind JSON: %v", err)
+		respondInvalid(c, "Invalid group data", validation.DecodeError(err))
+		return
+	}
+
+	log.Debugf("Request to rename group with ID %d to %s", id, input.Name)
+
+	group, err := library.RenameGroup(c.Request.Context(), id, input.Name)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	musiclibraryv1 "github.com/inanmasov/music-service/api/musiclibrary/v1"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/service"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// grpcCodes - коды статуса gRPC, соответствующие HTTP-статусам ошибок операций (serviceStatus)
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:           codes.InvalidArgument,
+	http.StatusNotFound:             codes.NotFound,
+	http.StatusConflict:             codes.AlreadyExists,
+	http.StatusPreconditionFailed:   codes.FailedPrecondition,
+	http.StatusPreconditionRequired: codes.FailedPrecondition,
+	http.StatusServiceUnavailable:   codes.Unavailable,
+}
+
+// MusicLibraryServer реализует сервис gRPC MusicLibrary поверх той же
+// бизнес-логики, что REST API и GraphQL
+type MusicLibraryServer struct {
+	musiclibraryv1.UnimplementedMusicLibraryServer
+}
+
+// NewMusicLibraryServer создаёт сервис gRPC MusicLibrary
+func NewMusicLibraryServer() *MusicLibraryServer {
+	return &MusicLibraryServer{}
+}
+
+// ListSongs потоково передаёт песни, подходящие под фильтры
+func (s *MusicLibraryServer) ListSongs(req *musiclibraryv1.ListSongsRequest, stream grpc.ServerStreamingServer[musiclibraryv1.Song]) error {
+	ctx := stream.Context()
+	page := int(req.GetPage())
+	if page == 0 {
+		page = 1
+	}
+	limit := int(req.GetLimit())
+	filter := service.SongFilter{
+		Group:       req.GetGroupName(),
+		Song:        req.GetSong(),
+		ReleaseDate: req.GetReleaseDate(),
+		Text:        req.GetText(),
+		Link:        req.GetLink(),
+	}
+	err := library.EachSong(ctx, filter, page, limit, func(song service.Song) error {
+		return stream.Send(songMessage(song))
+	})
+	return grpcError(err)
+}
+
+// GetSong возвращает песню по ID
+func (s *MusicLibraryServer) GetSong(ctx context.Context, req *musiclibraryv1.GetSongRequest) (*musiclibraryv1.Song, error) {
+	song, err := library.GetSong(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return songMessage(song), nil
+}
+
+// GetSongText возвращает страницу куплетов текста песни. По умолчанию на странице
+// service.VersesPerPage куплетов
+func (s *MusicLibraryServer) GetSongText(ctx context.Context, req *musiclibraryv1.GetSongTextRequest) (*musiclibraryv1.GetSongTextResponse, error) {
+	page, limit := int(req.GetPage()), int(req.GetLimit())
+	if page == 0 {
+		page = 1
+	}
+	if limit == 0 {
+		limit = service.VersesPerPage
+	}
+	if err := service.CheckPage(page, limit, false); err != nil {
+		return nil, grpcError(err)
+	}
+
+	song, err := library.GetSong(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	verses, total, err := service.Verses(song.Text, page, limit)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &musiclibraryv1.GetSongTextResponse{
+		Verses: verses,
+		Pagination: &musiclibraryv1.Pagination{
+			Page:    int32(page),
+			Limit:   int32(limit),
+			Total:   int32(total),
+			HasMore: page*limit < total,
+		},
+	}, nil
+}
+
+// AddSong добавляет песню, запрашивая её данные во внешнем API
+func (s *MusicLibraryServer) AddSong(ctx context.Context, req *musiclibraryv1.AddSongRequest) (*musiclibraryv1.Song, error) {
+	song, _, err := library.AddSong(ctx, req.GetGroup(), req.GetSong())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return songMessage(song), nil
+}
+
+// UpdateSong полностью заменяет данные песни
+func (s *MusicLibraryServer) UpdateSong(ctx context.Context, req *musiclibraryv1.UpdateSongRequest) (*musiclibraryv1.Song, error) {
+	fields := req.GetSong()
+	input, err := service.NewSongInput(fields.GetGroup(), fields.GetSong(), fields.GetReleaseDate(), fields.GetText(), fields.GetLink())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	var version service.Precondition
+	if req.ExpectedVersion != nil {
+		version = service.ExpectVersion(int(req.GetExpectedVersion()))
+	}
+
+	song, err := library.UpdateSong(ctx, int(req.GetId()), input, version)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return songMessage(song), nil
+}
+
+// DeleteSong удаляет песню
+func (s *MusicLibraryServer) DeleteSong(ctx context.Context, req *musiclibraryv1.DeleteSongRequest) (*emptypb.Empty, error) {
+	var version service.Precondition
+	if req.ExpectedVersion != nil {
+		version = service.ExpectVersion(int(req.GetExpectedVersion()))
+	}
+
+	if _, err := library.DeleteSong(ctx, int(req.GetId()), version); err != nil {
+		return nil, grpcError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// songMessage преобразует песню в сообщение gRPC
+func songMessage(song service.Song) *musiclibraryv1.Song {
+	message := &musiclibraryv1.Song{
+		Id:      int64(song.ID),
+		Group:   song.GroupName,
+		Song:    song.SongName,
+		Text:    song.Text,
+		Link:    song.Link,
+		Version: int64(song.Version),
+	}
+	if !song.ReleaseDate.IsZero() {
+		message.ReleaseDate = song.ReleaseDate.Format(time.DateOnly)
+	}
+	return message
+}
+
+// grpcError преобразует ошибку операции в статус gRPC. Ошибки полей передаются
+// в деталях BadRequest. Ошибки не из сервиса (например, обрыв потока клиентом)
+// возвращаются как есть
+func grpcError(err error) error {
+	var svcErr *service.Error
+	if !errors.As(err, &svcErr) {
+		return err
+	}
+
+	code, ok := grpcCodes[serviceStatus(err)]
+	if !ok {
+		code = codes.Internal
+	}
+	st := status.New(code, svcErr.Message)
+	fields := svcErr.Fields()
+	if len(fields) == 0 {
+		return st.Err()
+	}
+
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(fields))
+	for _, field := range fields {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field.Field,
+			Description: field.Message,
+		})
+	}
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		return detailed.Err()
+	}
+	return st.Err()
+}
+
+// NewGRPCServer создаёт сервер gRPC с сервисом MusicLibrary, отражением,
+// трассировкой, журналированием вызовов и перехватом паник
+func NewGRPCServer() *grpc.Server {
+	recoverUnary, recoverStream := GRPCRecovery()
+	srv := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(logger.UnaryServerInterceptor(), recoverUnary),
+		grpc.ChainStreamInterceptor(logger.StreamServerInterceptor(), recoverStream),
+	)
+	musiclibraryv1.RegisterMusicLibraryServer(srv, NewMusicLibraryServer())
+	reflection.Register(srv)
+	return srv
+}
+
+// GRPCRecovery возвращает перехватчики, которые, как gin.Recovery, превращают
+// панику обработчика в ошибку INTERNAL вместо завершения процесса
+func GRPCRecovery() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	recovered := func(ctx context.Context, value interface{}) error {
+		logger.FromContext(ctx).Errorf("Panic in gRPC handler: %v\n%s", value, debug.Stack())
+		return status.Error(codes.Internal, "Internal error")
+	}
+
+	unary := func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if value := recover(); value != nil {
+				err = recovered(ctx, value)
+			}
+		}()
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if value := recover(); value != nil {
+				err = recovered(ss.Context(), value)
+			}
+		}()
+		return handler(srv, ss)
+	}
+	return unary, stream
+}
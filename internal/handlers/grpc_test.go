@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	musiclibraryv1 "github.com/inanmasov/music-service/api/musiclibrary/v1"
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/service"
+	"github.com/inanmasov/music-service/internal/testdb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC запускает NewGRPCServer в памяти и возвращает подключение к нему.
+// Сервер и подключение закрываются по завершении теста
+func dialGRPC(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	srv := NewGRPCServer()
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to connect to gRPC server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// useLibrary подменяет бизнес-логику обработчиков на l до конца теста
+func useLibrary(t *testing.T, l *service.Library) {
+	t.Helper()
+	previous := library
+	library = l
+	t.Cleanup(func() { library = previous })
+}
+
+// checkCode проверяет, что вызов gRPC завершился ошибкой с кодом want
+func checkCode(t *testing.T, call string, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Errorf("%s: code %s (%v), want %s", call, got, err, want)
+	}
+}
+
+func TestGRPCReflection(t *testing.T) {
+	client := reflectionpb.NewServerReflectionClient(dialGRPC(t))
+	stream, err := client.ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatalf("ServerReflectionInfo: %v", err)
+	}
+	request := &reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}
+	if err := stream.Send(request); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	response, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+
+	for _, svc := range response.GetListServicesResponse().GetService() {
+		if svc.GetName() == musiclibraryv1.MusicLibrary_ServiceDesc.ServiceName {
+			return
+		}
+	}
+	t.Errorf("services %v, want %s", response.GetListServicesResponse().GetService(), musiclibraryv1.MusicLibrary_ServiceDesc.ServiceName)
+}
+
+func TestGRPCErrorCodes(t *testing.T) {
+	notService := errors.New("stream closed")
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{&service.Error{Kind: service.ErrNotFound, Message: "Song not found"}, codes.NotFound},
+		{&service.Error{Kind: service.ErrDuplicateSong, Message: "Song already exists"}, codes.AlreadyExists},
+		{&service.Error{Kind: service.ErrConflict, Message: "Conflicting concurrent changes, try again"}, codes.AlreadyExists},
+		{&service.Error{Kind: service.ErrVersionMismatch, Message: "Song has been modified"}, codes.FailedPrecondition},
+		{&service.Error{Kind: service.ErrVersionRequired, Message: "Expected version is required"}, codes.FailedPrecondition},
+		{&service.Error{Kind: service.ErrValidation, Message: "Invalid song data"}, codes.InvalidArgument},
+		{&service.Error{Kind: service.ErrUpstreamUnavailable, Message: "Failed to call external API"}, codes.Unavailable},
+		{&service.Error{Message: "Failed to retrieve song"}, codes.Internal},
+	}
+	for _, tt := range tests {
+		err := grpcError(tt.err)
+		st, _ := status.FromError(err)
+		if st.Code() != tt.want || st.Message() != tt.err.Error() {
+			t.Errorf("grpcError(%v) = %s %q, want %s with the same message", tt.err, st.Code(), st.Message(), tt.want)
+		}
+	}
+
+	if err := grpcError(notService); err != notService {
+		t.Errorf("grpcError(%v) = %v, want the error as is", notService, err)
+	}
+	if err := grpcError(nil); err != nil {
+		t.Errorf("grpcError(nil) = %v, want nil", err)
+	}
+}
+
+func TestGRPCErrorsOverTransport(t *testing.T) {
+	useLibrary(t, service.NewLibrary(func() (*sql.DB, error) {
+		return nil, errors.New("connection refused")
+	}, nil))
+	client := musiclibraryv1.NewMusicLibraryClient(dialGRPC(t))
+	ctx := context.Background()
+
+	_, err := client.GetSong(ctx, &musiclibraryv1.GetSongRequest{Id: 1})
+	checkCode(t, "GetSong without database", err, codes.Internal)
+
+	_, err = client.GetSongText(ctx, &musiclibraryv1.GetSongTextRequest{Id: 1, Page: -1})
+	checkCode(t, "GetSongText with page -1", err, codes.InvalidArgument)
+
+	// Ошибки полей передаются в деталях BadRequest
+	_, err = client.UpdateSong(ctx, &musiclibraryv1.UpdateSongRequest{
+		Id:   1,
+		Song: &musiclibraryv1.SongInput{Song: "Uprising", ReleaseDate: "16.07.2006"},
+	})
+	checkCode(t, "UpdateSong with invalid data", err, codes.InvalidArgument)
+	var fields []string
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+	if want := []string{"releaseDate"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("field violations %v, want %v", fields, want)
+	}
+
+	stream, err := client.ListSongs(ctx, &musiclibraryv1.ListSongsRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	checkCode(t, "ListSongs without database", err, codes.Internal)
+}
+
+func TestGRPCSongs(t *testing.T) {
+	db := testdb.Open(t)
+	useLibrary(t, service.NewLibrary(testdb.Pool(db), nil))
+
+	musicAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"releaseDate":"2006-07-16T00:00:00Z","text":"Verse 1\n\nVerse 2\n\nVerse 3","link":"https://example.com/`+r.URL.Query().Get("song")+`"}`)
+	}))
+	defer musicAPI.Close()
+	t.Setenv("DATABASE_URL", os.Getenv(testdb.EnvVar))
+	t.Setenv("MUSIC_API_URL", musicAPI.URL)
+	t.Setenv("SONGS_DUPLICATE_POLICY", config.DuplicateReject)
+	if _, err := config.Load(""); err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+
+	client := musiclibraryv1.NewMusicLibraryClient(dialGRPC(t))
+	ctx := context.Background()
+
+	added, err := client.AddSong(ctx, &musiclibraryv1.AddSongRequest{Group: "Muse", Song: "Uprising"})
+	if err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+	if added.GetGroup() != "Muse" || added.GetReleaseDate() != "2006-07-16" || added.GetLink() != "https://example.com/Uprising" || added.GetVersion() != 1 {
+		t.Errorf("added song %v, want the data from the external API", added)
+	}
+	_, err = client.AddSong(ctx, &musiclibraryv1.AddSongRequest{Group: "Muse", Song: "Uprising"})
+	checkCode(t, "AddSong of a duplicate", err, codes.AlreadyExists)
+	if _, err := client.AddSong(ctx, &musiclibraryv1.AddSongRequest{Group: "Queen", Song: "Bohemian Rhapsody"}); err != nil {
+		t.Fatalf("AddSong: %v", err)
+	}
+
+	song, err := client.GetSong(ctx, &musiclibraryv1.GetSongRequest{Id: added.GetId()})
+	if err != nil || song.GetSong() != "Uprising" {
+		t.Errorf("GetSong = %v, %v; want the added song", song, err)
+	}
+
+	text, err := client.GetSongText(ctx, &musiclibraryv1.GetSongTextRequest{Id: added.GetId(), Page: 2})
+	if err != nil {
+		t.Fatalf("GetSongText: %v", err)
+	}
+	if want := []string{"Verse 3"}; !reflect.DeepEqual(text.GetVerses(), want) ||
+		text.GetPagination().GetLimit() != service.VersesPerPage || text.GetPagination().GetTotal() != 3 || text.GetPagination().GetHasMore() {
+		t.Errorf("GetSongText page 2 = %v, want the last verse of 3", text)
+	}
+
+	stream, err := client.ListSongs(ctx, &musiclibraryv1.ListSongsRequest{GroupName: "Muse"})
+	if err != nil {
+		t.Fatalf("ListSongs: %v", err)
+	}
+	var listed []string
+	for {
+		song, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ListSongs: %v", err)
+		}
+		listed = append(listed, song.GetSong())
+	}
+	if want := []string{"Uprising"}; !reflect.DeepEqual(listed, want) {
+		t.Errorf("ListSongs streamed %v, want %v", listed, want)
+	}
+
+	staleVersion := added.GetVersion()
+	updated, err := client.UpdateSong(ctx, &musiclibraryv1.UpdateSongRequest{
+		Id:              added.GetId(),
+		Song:            &musiclibraryv1.SongInput{Group: "Muse", Song: "Uprising", ReleaseDate: "2009-09-07"},
+		ExpectedVersion: &staleVersion,
+	})
+	if err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	if updated.GetReleaseDate() != "2009-09-07" || updated.GetText() != "" || updated.GetVersion() != 2 {
+		t.Errorf("updated song %v, want the new data with version 2", updated)
+	}
+
+	_, err = client.DeleteSong(ctx, &musiclibraryv1.DeleteSongRequest{Id: added.GetId(), ExpectedVersion: &staleVersion})
+	checkCode(t, "DeleteSong with a stale version", err, codes.FailedPrecondition)
+	version := updated.GetVersion()
+	if _, err := client.DeleteSong(ctx, &musiclibraryv1.DeleteSongRequest{Id: added.GetId(), ExpectedVersion: &version}); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	_, err = client.GetSong(ctx, &musiclibraryv1.GetSongRequest{Id: added.GetId()})
+	checkCode(t, "GetSong of a deleted song", err, codes.NotFound)
+}
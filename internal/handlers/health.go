@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/health"
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// Healthz сообщает, что процесс жив
+// @Summary Liveness probe
+// @Description Returns 200 while the process is running and able to serve HTTP. Dependencies are not checked, so a database outage does not restart the service
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.Liveness "Service is alive"
+// @Router /healthz [get]
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, models.Liveness{Status: models.HealthOK})
+}
+
+// Readyz сообщает, готов ли сервис принимать запросы
+// @Summary Readiness probe
+// @Description Checks that the database is reachable and the schema is at the expected migration version. An unreachable music API only degrades the service and keeps it ready. Returns 503 when a required dependency fails or the service is shutting down
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.Readiness "Service is ready (status ok or degraded)"
+// @Failure 503 {object} models.Readiness "Service is not ready or is shutting down"
+// @Router /readyz [get]
+func Readyz(c *gin.Context) {
+	report := health.Check(c.Request.Context())
+
+	readiness := models.Readiness{
+		Status:       report.Status,
+		ShuttingDown: health.ShuttingDown(),
+		Checks:       report.Checks,
+	}
+	if readiness.ShuttingDown {
+		readiness.Status = models.HealthFail
+	}
+
+	status := http.StatusOK
+	if readiness.Status == models.HealthFail {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, readiness)
+}
+
+// Status возвращает диагностические сведения о сервисе
+// @Summary Service status
+// @Description Reports build info, uptime, the current and expected migration versions and the latency of every dependency check. Always returns 200; the overall status is in the body
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.ServiceStatus "Service status"
+// @Router /status [get]
+func Status(c *gin.Context) {
+	report := health.Check(c.Request.Context())
+
+	status := models.ServiceStatus{
+		Status:        report.Status,
+		ShuttingDown:  health.ShuttingDown(),
+		Build:         health.Build(),
+		StartedAt:     health.StartedAt().UTC(),
+		UptimeSeconds: time.Since(health.StartedAt()).Round(time.Second).Seconds(),
+		Migrations:    report.Migrations,
+		Dependencies:  report.Checks,
+	}
+	if status.ShuttingDown {
+		status.Status = models.HealthFail
+	}
+	c.JSON(http.StatusOK, status)
+}
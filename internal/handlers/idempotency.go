@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/db"
+	"github.com/inanmasov/music-service/internal/idempotency"
+	"github.com/inanmasov/music-service/internal/logger"
+)
+
+const (
+	// IdempotencyKeyHeader - заголовок с ключом идемпотентности POST-запроса
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader отмечает ответ, повторённый по ключу идемпотентности
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength - наибольшая длина ключа идемпотентности
+	maxIdempotencyKeyLength = 255
+	// idempotencyLockTimeout - через сколько освобождается ключ, ответ на который
+	// так и не был сохранён, например, из-за остановки сервиса
+	idempotencyLockTimeout = 5 * time.Minute
+	// maxIdempotentResponse - ответы больше этого размера не сохраняются
+	maxIdempotentResponse = 1 << 20
+	// maxIdempotentMemoryBody - тело запроса больше этого размера на время
+	// обработки сохраняется во временный файл, а не в память
+	maxIdempotentMemoryBody = 1 << 20
+	// maxIdempotentBody - наибольшее тело запроса, для которого учитывается
+	// Idempotency-Key, у маршрутов, которых нет в idempotencyBodyLimits
+	maxIdempotentBody = 1 << 20
+	// idempotencyStoreTimeout - таймаут сохранения ответа
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+// idempotencyExcluded - маршруты (без префикса версии API), ответы которых нельзя
+// хранить: ответ на регистрацию вебхука содержит его секрет. Idempotency-Key
+// у этих маршрутов не учитывается
+var idempotencyExcluded = map[string]bool{
+	"/webhooks": true,
+}
+
+// idempotencyBodyLimits - наибольшие тела запросов маршрутов импорта (без префикса
+// версии API), для которых учитывается Idempotency-Key. Тело читается до обработчика,
+// поэтому большой предел действует только там, где сервис принимает большие файлы
+var idempotencyBodyLimits = map[string]func() int64{
+	"/songs/import":          func() int64 { return config.Get().Songs.ImportMaxBytes },
+	"/songs/playlist/import": func() int64 { return playlistMaxBytes },
+}
+
+// idempotencyBodyLimit возвращает наибольшее тело запроса к маршруту route,
+// для которого учитывается Idempotency-Key
+func idempotencyBodyLimit(route string) int64 {
+	if limit, ok := idempotencyBodyLimits[unversionedRoute(route)]; ok {
+		return limit()
+	}
+	return maxIdempotentBody
+}
+
+// Idempotency обрабатывает заголовок Idempotency-Key POST-запросов. Первый запрос
+// с ключом выполняется, и его ответ сохраняется на IDEMPOTENCY_TTL. Повтор с тем же
+// ключом и тем же запросом (метод, путь, параметры, Content-Type и тело) получает
+// сохранённый ответ с заголовком Idempotent-Replayed, не выполняясь снова. Тот же
+// ключ с другим запросом отклоняется с 422, в том числе пока первый запрос
+// выполняется, а тот же запрос, пока первый выполняется, - с 409. Ключи действуют
+// в пределах маршрута и клиента: одинаковые ключи разных маршрутов или клиентов
+// не мешают друг другу. Ответы 5xx не сохраняются, чтобы запрос можно было повторить
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		route := c.FullPath()
+		if c.Request.Method != http.MethodPost || key == "" || route == "" || idempotencyExcluded[unversionedRoute(route)] {
+			c.Next()
+			return
+		}
+
+		log := logger.FromContext(c.Request.Context())
+		if !validIdempotencyKey(key) {
+			log.Errorf("Invalid Idempotency-Key header: %q", key)
+			respondError(c, http.StatusBadRequest, "Invalid Idempotency-Key header")
+			c.Abort()
+			return
+		}
+
+		// Тело запроса читается до обработчика, чтобы сравнить отпечаток с первым
+		// запросом ещё до его завершения
+		fingerprint, err := fingerprintRequest(c.Request, idempotencyBodyLimit(route))
+		if err != nil {
+			log.Errorf("Failed to read request body: %v", err)
+			respondError(c, http.StatusBadRequest, "Failed to read request body")
+			c.Abort()
+			return
+		}
+		defer fingerprint.body.Close()
+		if fingerprint.tooLarge {
+			log.Warnf("Ignoring Idempotency-Key %q: request is too large", key)
+			c.Next()
+			return
+		}
+
+		pool, err := db.Initialize()
+		if err != nil {
+			log.Errorf("Failed to connect to database: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to connect to database")
+			c.Abort()
+			return
+		}
+
+		storedKey := scopedIdempotencyKey(c, route, key)
+		acquired, err := idempotency.Acquire(c.Request.Context(), pool, storedKey, fingerprint.sum, idempotencyLockTimeout)
+		if err != nil {
+			log.Errorf("Failed to acquire Idempotency-Key: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to check Idempotency-Key")
+			c.Abort()
+			return
+		}
+		if !acquired {
+			replayIdempotent(c, pool, key, storedKey, fingerprint.sum)
+			c.Abort()
+			return
+		}
+
+		// Ответ сохраняется и тогда, когда клиент не дождался его: именно такой клиент
+		// и повторит запрос
+		storeCtx := context.WithoutCancel(c.Request.Context())
+		stored := false
+		defer func() {
+			if !stored {
+				releaseIdempotencyKey(storeCtx, pool, storedKey)
+			}
+		}()
+
+		writer := &idempotentWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		switch {
+		case writer.Status() >= http.StatusInternalServerError:
+			log.Debugf("Not storing response %d for Idempotency-Key %q", writer.Status(), key)
+			return
+		case writer.tooLarge:
+			log.Warnf("Not storing response for Idempotency-Key %q: response is too large", key)
+			return
+		}
+
+		header := writer.Header().Clone()
+		header.Del(logger.RequestIDHeader)
+		response := idempotency.Response{Status: writer.Status(), Header: header, Body: writer.body.Bytes()}
+
+		ctx, cancel := context.WithTimeout(storeCtx, idempotencyStoreTimeout)
+		defer cancel()
+		if err := idempotency.Complete(ctx, pool, storedKey, response, config.Get().Idempotency.TTL.Std()); err != nil {
+			log.Errorf("Failed to store response for Idempotency-Key %q: %v", key, err)
+			return
+		}
+		stored = true
+	}
+}
+
+// unversionedRoute возвращает шаблон маршрута без префикса версии API, одинаковый
+// для текущих и устаревших маршрутов
+func unversionedRoute(route string) string {
+	return strings.TrimPrefix(route, APIPrefix)
+}
+
+// scopedIdempotencyKey возвращает ключ, под которым хранится Idempotency-Key key
+// запроса к маршруту route: хеш метода, маршрута, клиента и самого ключа. Клиент
+// определяется по заголовку Authorization, если он передан
+func scopedIdempotencyKey(c *gin.Context, route, key string) string {
+	client := ""
+	if authorization := c.GetHeader("Authorization"); authorization != "" {
+		sum := sha256.Sum256([]byte(authorization))
+		client = hex.EncodeToString(sum[:])
+	}
+	sum := sha256.Sum256([]byte(c.Request.Method + "\n" + unversionedRoute(route) + "\n" + client + "\n" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// validIdempotencyKey проверяет, что ключ не длиннее maxIdempotencyKeyLength
+// и состоит из видимых символов ASCII
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < '!' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// replayIdempotent отвечает на повтор запроса с отпечатком fingerprint и занятым
+// ключом key (хранящимся как storedKey) сохранённым ответом, если запрос тот же,
+// что и первый
+func replayIdempotent(c *gin.Context, pool *sql.DB, key, storedKey, fingerprint string) {
+	log := logger.FromContext(c.Request.Context())
+
+	entry, err := idempotency.Lookup(c.Request.Context(), pool, storedKey)
+	switch {
+	case errors.Is(err, idempotency.ErrNotFound):
+		// Первый запрос завершился ошибкой и освободил ключ после Acquire
+		respondError(c, http.StatusConflict, "A request with this Idempotency-Key has just finished, try again")
+		return
+	case err != nil:
+		log.Errorf("Failed to look up Idempotency-Key: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to check Idempotency-Key")
+		return
+	case entry.Fingerprint != fingerprint:
+		log.Infof("Idempotency-Key %q reused with a different request", key)
+		respondError(c, http.StatusUnprocessableEntity, "Idempotency-Key has already been used with a different request")
+		return
+	case entry.Response == nil:
+		log.Infof("Request with Idempotency-Key %q is still in progress", key)
+		respondError(c, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+		return
+	}
+
+	log.Infof("Replaying stored response %d for Idempotency-Key %q", entry.Response.Status, key)
+	for name, values := range entry.Response.Header {
+		c.Writer.Header()[name] = values
+	}
+	c.Header(idempotentReplayedHeader, "true")
+	c.Writer.WriteHeader(entry.Response.Status)
+	c.Writer.Write(entry.Response.Body)
+}
+
+// releaseIdempotencyKey освобождает ключ key, ответ на который не сохранён
+func releaseIdempotencyKey(ctx context.Context, pool *sql.DB, key string) {
+	ctx, cancel := context.WithTimeout(ctx, idempotencyStoreTimeout)
+	defer cancel()
+	if err := idempotency.Release(ctx, pool, key); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to release Idempotency-Key: %v", err)
+	}
+}
+
+// requestFingerprint - отпечаток запроса: SHA-256 метода, пути, параметров,
+// Content-Type и тела. tooLarge - тело больше предела маршрута, и отпечаток не вычислен
+type requestFingerprint struct {
+	sum      string
+	tooLarge bool
+	body     *bodySpool
+}
+
+// fingerprintRequest читает не больше limit байт тела запроса req, вычисляя
+// отпечаток, и подменяет тело на прочитанную копию, за которой следует
+// непрочитанный остаток. Копию нужно удалить через body.Close после обработки запроса
+func fingerprintRequest(req *http.Request, limit int64) (*requestFingerprint, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s?%s\n%s\n", req.Method, req.URL.Path, req.URL.RawQuery, req.Header.Get("Content-Type"))
+
+	f := &requestFingerprint{body: &bodySpool{}}
+	n, err := io.Copy(io.MultiWriter(h, f.body), io.LimitReader(req.Body, limit+1))
+	if err == nil {
+		var read io.Reader
+		read, err = f.body.reader()
+		req.Body = readCloser{Reader: io.MultiReader(read, req.Body), Closer: req.Body}
+	}
+	if err != nil {
+		f.body.Close()
+		return nil, err
+	}
+
+	f.tooLarge = n > limit
+	f.sum = hex.EncodeToString(h.Sum(nil))
+	return f, nil
+}
+
+// bodySpool хранит прочитанное тело запроса в памяти, а если оно больше
+// maxIdempotentMemoryBody - во временном файле
+type bodySpool struct {
+	buf  bytes.Buffer
+	file *os.File
+}
+
+func (s *bodySpool) Write(data []byte) (int, error) {
+	if s.file == nil && s.buf.Len()+len(data) <= maxIdempotentMemoryBody {
+		return s.buf.Write(data)
+	}
+	if s.file == nil {
+		file, err := os.CreateTemp("", "idempotent-body-*")
+		if err != nil {
+			return 0, err
+		}
+		s.file = file
+		if _, err := s.file.Write(s.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		s.buf = bytes.Buffer{}
+	}
+	return s.file.Write(data)
+}
+
+// reader возвращает чтение сохранённого тела с начала
+func (s *bodySpool) reader() (io.Reader, error) {
+	if s.file == nil {
+		return &s.buf, nil
+	}
+	_, err := s.file.Seek(0, io.SeekStart)
+	return s.file, err
+}
+
+// Close удаляет временный файл тела, если он был создан
+func (s *bodySpool) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// readCloser соединяет Reader и Closer тела запроса
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// idempotentWriter передаёт ответ клиенту и копирует его для сохранения
+type idempotentWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	tooLarge bool
+}
+
+func (w *idempotentWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotentWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// capture копирует часть ответа, пока он не больше maxIdempotentResponse
+func (w *idempotentWriter) capture(data []byte) {
+	if w.tooLarge {
+		return
+	}
+	if w.body.Len()+len(data) > maxIdempotentResponse {
+		w.tooLarge = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/config"
+)
+
+func TestFingerprintRequestKeepsBody(t *testing.T) {
+	for _, size := range []int{0, 10, maxIdempotentMemoryBody + 1} {
+		body := bytes.Repeat([]byte("a"), size)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/songs?x=1", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		fingerprint, err := fingerprintRequest(req, 2*maxIdempotentMemoryBody)
+		if err != nil {
+			t.Fatalf("size %d: fingerprintRequest: %v", size, err)
+		}
+		read, err := io.ReadAll(req.Body)
+		fingerprint.body.Close()
+		if err != nil || !bytes.Equal(read, body) {
+			t.Errorf("size %d: handler read %d bytes (%v), want the whole body", size, len(read), err)
+		}
+		if fingerprint.tooLarge {
+			t.Errorf("size %d: body reported as too large", size)
+		}
+	}
+}
+
+func TestFingerprintRequestTooLarge(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 100)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/songs", bytes.NewReader(body))
+
+	fingerprint, err := fingerprintRequest(req, 99)
+	if err != nil {
+		t.Fatalf("fingerprintRequest: %v", err)
+	}
+	defer fingerprint.body.Close()
+	if !fingerprint.tooLarge {
+		t.Error("body larger than the limit is not reported as too large")
+	}
+	// Обработчик получает тело целиком, даже если оно больше предела
+	if read, err := io.ReadAll(req.Body); err != nil || !bytes.Equal(read, body) {
+		t.Errorf("handler read %d bytes (%v), want the whole body", len(read), err)
+	}
+}
+
+func TestIdempotencyBodyLimit(t *testing.T) {
+	tests := []struct {
+		route string
+		want  int64
+	}{
+		{APIPrefix + "/songs", maxIdempotentBody},
+		{APIPrefix + "/songs/batch", maxIdempotentBody},
+		{"/graphql", maxIdempotentBody},
+		{APIPrefix + "/songs/import", config.Get().Songs.ImportMaxBytes},
+		{"/songs/import", config.Get().Songs.ImportMaxBytes},
+		{APIPrefix + "/songs/playlist/import", playlistMaxBytes},
+	}
+	for _, tt := range tests {
+		if got := idempotencyBodyLimit(tt.route); got != tt.want {
+			t.Errorf("idempotencyBodyLimit(%s) = %d, want %d", tt.route, got, tt.want)
+		}
+	}
+}
+
+func TestFingerprintRequestDiffers(t *testing.T) {
+	fingerprint := func(target, contentType, body string) string {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		f, err := fingerprintRequest(req, maxIdempotentBody)
+		if err != nil {
+			t.Fatalf("fingerprintRequest: %v", err)
+		}
+		f.body.Close()
+		return f.sum
+	}
+
+	base := fingerprint("/api/v1/songs", "application/json", `{"group":"Muse"}`)
+	if base != fingerprint("/api/v1/songs", "application/json", `{"group":"Muse"}`) {
+		t.Error("same requests have different fingerprints")
+	}
+	others := map[string]string{
+		"body":         fingerprint("/api/v1/songs", "application/json", `{"group":"Queen"}`),
+		"query":        fingerprint("/api/v1/songs?dryRun=true", "application/json", `{"group":"Muse"}`),
+		"content type": fingerprint("/api/v1/songs", "text/plain", `{"group":"Muse"}`),
+	}
+	for name, sum := range others {
+		if sum == base {
+			t.Errorf("requests with different %s have the same fingerprint", name)
+		}
+	}
+}
+
+func TestScopedIdempotencyKey(t *testing.T) {
+	scoped := func(method, route, authorization string) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(method, "/", nil)
+		if authorization != "" {
+			c.Request.Header.Set("Authorization", authorization)
+		}
+		return scopedIdempotencyKey(c, route, "key-1")
+	}
+
+	base := scoped(http.MethodPost, APIPrefix+"/songs", "")
+	if legacy := scoped(http.MethodPost, "/songs", ""); legacy != base {
+		t.Error("versioned and legacy routes have different key scopes")
+	}
+	others := map[string]string{
+		"route":  scoped(http.MethodPost, APIPrefix+"/songs/merge", ""),
+		"method": scoped(http.MethodPut, APIPrefix+"/songs", ""),
+		"client": scoped(http.MethodPost, APIPrefix+"/songs", "Bearer token"),
+	}
+	for name, key := range others {
+		if key == base {
+			t.Errorf("the same key with a different %s shares the scope", name)
+		}
+	}
+}
+
+func TestIdempotencyIgnoresExcludedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Idempotency())
+	r.POST(APIPrefix+"/webhooks", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusCreated, string(body))
+	})
+
+	// Ключ не учитывается, поэтому база данных не нужна и ответ не сохраняется
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, APIPrefix+"/webhooks", strings.NewReader(`{"url":"http://example.com"}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusCreated || w.Header().Get(idempotentReplayedHeader) != "" {
+			t.Errorf("request %d: status %d, replayed %q; want 201 from the handler", i+1, w.Code, w.Header().Get(idempotentReplayedHeader))
+		}
+	}
+}
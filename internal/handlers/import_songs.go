@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/importer"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/service"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+// ImportSongs принимает файл с песнями и ставит его импорт в очередь
+// @Summary Import songs from CSV or NDJSON
+// @Description Uploads a CSV file (header with group, song and optional releaseDate, text, link columns) or NDJSON (one song object per line) and starts a background import job. Rows are validated and deduplicated against existing songs by group and song name; with enrich=true missing fields are requested from the external API.
+// @Tags import
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param format query string false "File format: csv or ndjson. Detected from Content-Type if omitted"
+// @Param enrich query bool false "Fill in missing fields from the external API" default(false)
+// @Param file body string true "CSV or NDJSON file contents"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the first response instead of repeating it"
+// @Success 202 {object} models.ImportJob "Import job accepted"
+// @Header 202 {string} Location "URL of the import job status"
+// @Failure 400 {object} models.ErrorResponse "Unsupported format or empty file"
+// @Failure 409 {object} models.ErrorResponse "A request with the same Idempotency-Key is still in progress"
+// @Failure 413 {object} models.ErrorResponse "File is too large"
+// @Failure 422 {object} models.ErrorResponse "Idempotency-Key has already been used with a different request"
+// @Failure 500 {object} models.ErrorResponse "Failed to store the uploaded file"
+// @Failure 503 {object} models.ErrorResponse "Import queue is full"
+// @Router /api/v1/songs/import [post]
+func ImportSongs(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting ImportSongs handler")
+
+	format, err := importer.ParseFormat(c.Query("format"), c.ContentType())
+	if err != nil {
+		log.Errorf("Invalid import format: %v", err)
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	enrich, err := strconv.ParseBool(c.DefaultQuery("enrich", "false"))
+	if err != nil {
+		log.Errorf("Invalid enrich parameter: %v", err)
+		respondError(c, http.StatusBadRequest, "Invalid enrich parameter")
+		return
+	}
+
+	log.Debugf("Request to import songs: format=%s, enrich=%t", format, enrich)
+
+	// Сохраняем файл во временный файл, чтобы обработать его в фоне
+	file, err := os.CreateTemp("", "songs-import-*")
+	if err != nil {
+		log.Errorf("Failed to create temporary file: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to store the uploaded file")
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, config.Get().Songs.ImportMaxBytes)
+	size, err := io.Copy(file, body)
+	file.Close()
+	if err != nil {
+		os.Remove(file.Name())
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Errorf("Import file is too large: %v", err)
+			respondError(c, http.StatusRequestEntityTooLarge, "File is too large")
+			return
+		}
+		log.Errorf("Failed to store the uploaded file: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to store the uploaded file")
+		return
+	}
+	if size == 0 {
+		os.Remove(file.Name())
+		log.Error("Import file is empty")
+		respondError(c, http.StatusBadRequest, "File is empty")
+		return
+	}
+
+	log.Debugf("Stored %d bytes of import data in %s", size, file.Name())
+
+	job := importer.NewJob(format, enrich, file.Name())
+	if err := importer.GetManager().Submit(job, runImport); err != nil {
+		os.Remove(file.Name())
+		log.Errorf("Failed to submit import job: %v", err)
+		if err == importer.ErrShuttingDown {
+			respondError(c, http.StatusServiceUnavailable, "Service is shutting down, try again later")
+			return
+		}
+		respondError(c, http.StatusServiceUnavailable, "Import queue is full, try again later")
+		return
+	}
+
+	log.Infof("Import job %s accepted", job.ID())
+
+	c.Header("Location", APIPrefix+"/songs/import/"+job.ID())
+	c.JSON(http.StatusAccepted, job.Snapshot())
+}
+
+// GetImportJob возвращает состояние задания импорта и страницу результатов его строк
+// @Summary Get import job status
+// @Description Returns the import job status, counters of created, duplicate and failed rows and of rows that could not be enriched, and a page of row results in file order. Failed rows and rows with a warning carry the group, the song and the error details; other rows carry only the row number, the status and the song ID.
+// @Tags import
+// @Produce json
+// @Param jobId path string true "Import job ID"
+// @Param status query string false "Row status filter" Enums(created, duplicate, failed, warning)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of rows per page" default(100)
+// @Success 200 {object} models.ImportJob "Import job status"
+// @Failure 400 {object} models.ErrorResponse "Invalid status filter or pagination parameters"
+// @Failure 404 {object} models.ErrorResponse "Import job not found"
+// @Router /api/v1/songs/import/{jobId} [get]
+func GetImportJob(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting GetImportJob handler")
+
+	status := c.Query("status")
+	switch status {
+	case "", models.ImportRowCreated, models.ImportRowDuplicate, models.ImportRowFailed, models.ImportRowWarning:
+	default:
+		log.Errorf("Invalid import row status: %s", status)
+		respondError(c, http.StatusBadRequest, "Invalid status filter")
+		return
+	}
+
+	page, limit, ok := parsePagination(c, 100)
+	if !ok {
+		return
+	}
+
+	id := c.Param("jobId")
+	job, ok := importer.GetManager().Get(id)
+	if !ok {
+		log.Debugf("Import job %s not found", id)
+		respondError(c, http.StatusNotFound, "Import job not found")
+		return
+	}
+
+	result := job.Snapshot()
+	rows, total := job.Rows(status, page, limit)
+	rowsPage := newPage(c, rows, page, limit, total)
+	result.Rows = &rowsPage
+	c.JSON(http.StatusOK, result)
+}
+
+// runImport обрабатывает файл задания импорта построчно
+func runImport(ctx context.Context, job *importer.Job) error {
+	log := logger.FromContext(ctx)
+
+	file, err := os.Open(job.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return importer.ReadRows(file, job.Format(), func(row importer.Row) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		result := importRow(ctx, row, job.Enrich())
+		log.Debugf("Import job %s, row %d: %s", job.ID(), row.Number, result.Status)
+		job.AddRow(result)
+		return nil
+	})
+}
+
+// importRow импортирует строку и описывает результат
+func importRow(ctx context.Context, row importer.Row, enrich bool) models.ImportRowResult {
+	result := models.ImportRowResult{Row: row.Number, Group: row.Input.Group, Song: row.Input.Song}
+
+	fail := func(err error) models.ImportRowResult {
+		result.Status = models.ImportRowFailed
+		var fieldErrs validation.Errors
+		if errors.As(err, &fieldErrs) {
+			result.Error = "Invalid song data"
+			result.Errors = fieldErrs
+		} else {
+			result.Error = err.Error()
+		}
+		return result
+	}
+
+	if row.Err != nil {
+		return fail(row.Err)
+	}
+
+	imported, err := library.ImportSong(ctx, row.Input, enrich)
+	var svcErr *service.Error
+	if errors.Is(err, service.ErrValidation) && errors.As(err, &svcErr) && svcErr.Err != nil {
+		return fail(svcErr.Err)
+	} else if err != nil {
+		return fail(errors.New(serviceMessage(err)))
+	}
+
+	result.SongID = imported.SongID
+	result.Status = models.ImportRowDuplicate
+	if imported.Created {
+		result.Status = models.ImportRowCreated
+	}
+	if imported.EnrichFailed {
+		result.Warning = "Failed to get song info from external API"
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/cache"
+	"github.com/inanmasov/music-service/internal/db"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/service"
+)
+
+// library - бизнес-логика библиотеки песен, общая для REST, GraphQL и gRPC.
+// Обработчики только разбирают запрос и переводят ошибки в статусы транспорта
+var library = service.NewLibrary(db.Initialize, cache.Get)
+
+// serviceStatus возвращает HTTP-статус ошибки операции. Частные виды ошибок
+// проверяются раньше общих
+func serviceStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, service.ErrVersionRequired):
+		return http.StatusPreconditionRequired
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrUpstreamUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, service.ErrBatchAborted):
+		return http.StatusFailedDependency
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// serviceMessage возвращает сообщение ошибки операции для клиента. Ошибки,
+// не созданные сервисом, не раскрываются
+func serviceMessage(err error) string {
+	var svcErr *service.Error
+	if errors.As(err, &svcErr) {
+		return svcErr.Message
+	}
+	return http.StatusText(http.StatusInternalServerError)
+}
+
+// respondServiceError отвечает ошибкой операции. Некорректные данные песни
+// описываются в формате application/problem+json, отсутствие обязательной
+// версии - как отсутствие заголовка If-Match, в котором её передаёт REST API.
+// К ответу о дубликате добавляется Location уже добавленной песни
+func respondServiceError(c *gin.Context, err error) {
+	status := serviceStatus(err)
+
+	var duplicate *service.DuplicateSongError
+	if errors.As(err, &duplicate) {
+		c.Header("Location", APIPrefix+"/songs/"+strconv.Itoa(duplicate.SongID))
+	}
+
+	var svcErr *service.Error
+	switch {
+	case status == http.StatusPreconditionRequired:
+		respondError(c, status, "If-Match header is required")
+	case status == http.StatusBadRequest && errors.As(err, &svcErr) && svcErr.Err != nil:
+		respondInvalid(c, svcErr.Message, svcErr.Err)
+	default:
+		respondError(c, status, serviceMessage(err))
+	}
+}
+
+// plainSongs возвращает песни без ID групп
+func plainSongs(songs []service.Song) []models.Song {
+	plain := make([]models.Song, len(songs))
+	for i, song := range songs {
+		plain[i] = song.Song
+	}
+	return plain
+}
+
+// redirectMerged отвечает 308 Permanent Redirect на тот же ресурс песни, с которой
+// слита удалённая песня id, и возвращает true. Если песня id не сливалась, ничего
+// не отвечает и возвращает false
+func redirectMerged(c *gin.Context, id int) bool {
+	songID, err := library.SongRedirect(c.Request.Context(), id)
+	if err != nil {
+		return false
+	}
+
+	logger.FromContext(c.Request.Context()).Infof("Song with ID %d was merged into song with ID %d", id, songID)
+
+	target := *c.Request.URL
+	target.Path = strings.Replace(target.Path, "/songs/"+c.Param("id"), "/songs/"+strconv.Itoa(songID), 1)
+	target.RawPath = ""
+	c.Redirect(http.StatusPermanentRedirect, target.RequestURI())
+	return true
+}
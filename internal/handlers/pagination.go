@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// listDefaultLimit - размер страницы по умолчанию для списков вебхуков и доставок
+const listDefaultLimit = 50
+
+// parsePagination разбирает параметры page и limit. При некорректных значениях
+// отвечает 400 и возвращает false
+func parsePagination(c *gin.Context, defaultLimit int) (page, limit int, ok bool) {
+	log := logger.FromContext(c.Request.Context())
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		log.Errorf("Invalid page number: %s", c.Query("page"))
+		respondError(c, http.StatusBadRequest, "Invalid page number")
+		return 0, 0, false
+	}
+
+	limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit <= 0 {
+		log.Errorf("Invalid limit number: %s", c.Query("limit"))
+		respondError(c, http.StatusBadRequest, "Invalid limit number")
+		return 0, 0, false
+	}
+
+	// Конец страницы page*limit должен помещаться в int
+	if page > math.MaxInt/limit {
+		log.Errorf("Page number %d is too large for limit %d", page, limit)
+		respondError(c, http.StatusBadRequest, "Invalid page number")
+		return 0, 0, false
+	}
+
+	log.Debugf("Parsed pagination params: page=%d, limit=%d", page, limit)
+	return page, limit, true
+}
+
+// newPage собирает страницу списка из items с общим числом элементов total
+// и ссылками на соседние страницы
+func newPage[T any](c *gin.Context, items []T, page, limit, total int) models.Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+
+	// Без total + limit - 1, которое переполняется при очень большом limit
+	lastPage := 1
+	if total > 0 {
+		lastPage = (total-1)/limit + 1
+	}
+
+	result := models.Page[T]{
+		Items: items,
+		Pagination: models.Pagination{
+			Page:    page,
+			Limit:   limit,
+			Total:   total,
+			HasMore: page < lastPage,
+		},
+		Links: models.PageLinks{
+			Self:  pageLink(c, page, limit),
+			First: pageLink(c, 1, limit),
+			Last:  pageLink(c, lastPage, limit),
+		},
+	}
+	if page > 1 {
+		result.Links.Prev = pageLink(c, min(page-1, lastPage), limit)
+	}
+	if result.Pagination.HasMore {
+		result.Links.Next = pageLink(c, page+1, limit)
+	}
+	return result
+}
+
+// pageLink возвращает путь запроса с теми же параметрами и страницей page
+func pageLink(c *gin.Context, page, limit int) string {
+	query := c.Request.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(limit))
+	return c.Request.URL.Path + "?" + query.Encode()
+}
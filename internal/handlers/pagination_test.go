@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// testContext возвращает gin.Context запроса GET target
+func testContext(target string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+	return c, rec
+}
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantPage  int
+		wantLimit int
+		wantErr   string
+	}{
+		{query: "", wantPage: 1, wantLimit: 10},
+		{query: "page=3&limit=25", wantPage: 3, wantLimit: 25},
+		{query: "limit=1", wantPage: 1, wantLimit: 1},
+		{query: "page=" + strconv.Itoa(math.MaxInt/10), wantPage: math.MaxInt / 10, wantLimit: 10},
+		{query: "page=" + strconv.Itoa(math.MaxInt/10+1), wantErr: "Invalid page number"},
+		{query: "page=2&limit=" + strconv.Itoa(math.MaxInt), wantErr: "Invalid page number"},
+		{query: "limit=" + strconv.Itoa(math.MaxInt), wantPage: 1, wantLimit: math.MaxInt},
+		{query: "page=0", wantErr: "Invalid page number"},
+		{query: "page=-1", wantErr: "Invalid page number"},
+		{query: "page=two", wantErr: "Invalid page number"},
+		{query: "page=", wantErr: "Invalid page number"},
+		{query: "page=99999999999999999999", wantErr: "Invalid page number"},
+		{query: "limit=0", wantErr: "Invalid limit number"},
+		{query: "limit=-5", wantErr: "Invalid limit number"},
+		{query: "limit=1.5", wantErr: "Invalid limit number"},
+		{query: "page=0&limit=0", wantErr: "Invalid page number"},
+	}
+	for _, tt := range tests {
+		c, rec := testContext("/api/v1/songs?" + tt.query)
+		page, limit, ok := parsePagination(c, 10)
+
+		if tt.wantErr == "" {
+			if !ok || page != tt.wantPage || limit != tt.wantLimit {
+				t.Errorf("%q: got page %d, limit %d, ok %v, want %d, %d", tt.query, page, limit, ok, tt.wantPage, tt.wantLimit)
+			}
+			continue
+		}
+
+		var response models.ErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		if ok || rec.Code != http.StatusBadRequest || response.Error != tt.wantErr {
+			t.Errorf("%q: ok %v, status %d, error %q, want 400 %q", tt.query, ok, rec.Code, response.Error, tt.wantErr)
+		}
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	tests := []struct {
+		name               string
+		page, limit, total int
+		wantHasMore        bool
+		wantLast           string
+		wantPrev, wantNext string
+	}{
+		{"empty list", 1, 10, 0, false, "page=1", "", ""},
+		{"single page", 1, 10, 10, false, "page=1", "", ""},
+		{"first of several", 1, 10, 11, true, "page=2", "", "page=2"},
+		{"middle", 2, 10, 35, true, "page=4", "page=1", "page=3"},
+		{"last", 4, 10, 35, false, "page=4", "page=3", ""},
+		{"beyond the last", 9, 10, 35, false, "page=4", "page=4", ""},
+		{"huge limit", 1, math.MaxInt, 5, false, "page=1", "", ""},
+		{"huge total", 1, 1, math.MaxInt, true, "page=" + strconv.Itoa(math.MaxInt), "", "page=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := testContext("/api/v1/songs?group=Muse&page=x")
+			result := newPage(c, []int(nil), tt.page, tt.limit, tt.total)
+
+			want := models.Pagination{Page: tt.page, Limit: tt.limit, Total: tt.total, HasMore: tt.wantHasMore}
+			if result.Pagination != want {
+				t.Errorf("pagination %+v, want %+v", result.Pagination, want)
+			}
+			if result.Items == nil {
+				t.Error("items are nil, want an empty list in JSON")
+			}
+
+			link := func(page string) string {
+				if page == "" {
+					return ""
+				}
+				return "/api/v1/songs?group=Muse&limit=" + strconv.Itoa(tt.limit) + "&" + page
+			}
+			links := models.PageLinks{
+				Self:  link("page=" + strconv.Itoa(tt.page)),
+				First: link("page=1"),
+				Last:  link(tt.wantLast),
+				Prev:  link(tt.wantPrev),
+				Next:  link(tt.wantNext),
+			}
+			if result.Links != links {
+				t.Errorf("links %+v, want %+v", result.Links, links)
+			}
+		})
+	}
+}
+
+func TestNewPageEnvelope(t *testing.T) {
+	c, _ := testContext("/api/v1/groups")
+	data, err := json.Marshal(newPage(c, []string{"Muse", "Queen"}, 1, 2, 3))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var envelope struct {
+		Items      []string       `json:"items"`
+		Pagination map[string]any `json:"pagination"`
+		Links      map[string]any `json:"links"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatalf("Unmarshal %s: %v", data, err)
+	}
+	if len(envelope.Items) != 2 || envelope.Pagination["total"] != float64(3) || envelope.Pagination["hasMore"] != true {
+		t.Errorf("envelope %s, want 2 items of 3 with more pages", data)
+	}
+	if _, ok := envelope.Links["prev"]; ok {
+		t.Errorf("first page has a prev link: %s", data)
+	}
+}
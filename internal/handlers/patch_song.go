@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/service"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+const (
+	// mergePatchContentType - JSON Merge Patch (RFC 7396)
+	mergePatchContentType = "application/merge-patch+json"
+	// jsonPatchContentType - JSON Patch (RFC 6902)
+	jsonPatchContentType = "application/json-patch+json"
+)
+
+// PatchSong частично изменяет данные песни по её ID
+// @Summary Partially update song details
+// @Description Applies a JSON Merge Patch (RFC 7396, application/merge-patch+json or application/json) or a JSON Patch (RFC 6902, application/json-patch+json) to the song. The patched song must remain valid.
+// @Tags songs
+// @Accept json
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param patch body object true "Merge patch object or JSON Patch operations"
+// @Param If-Match header string false "ETag of the song version being updated"
+// @Success 200 {object} models.Song "Song updated successfully"
+// @Header 200 {string} ETag "Version tag of the updated song"
+// @Failure 400 {object} models.Problem "Invalid song ID, malformed patch or invalid patched song"
+// @Failure 404 {object} models.ErrorResponse "Song not found"
+// @Failure 409 {object} models.ErrorResponse "Another song with the same group and title already exists"
+// @Failure 412 {object} models.ErrorResponse "Song has been modified"
+// @Failure 413 {object} models.ErrorResponse "Request body is too large"
+// @Failure 415 {object} models.ErrorResponse "Unsupported patch format"
+// @Failure 422 {object} models.ErrorResponse "Patch cannot be applied to the song"
+// @Failure 428 {object} models.ErrorResponse "If-Match header is required"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database or update song"
+// @Router /api/v1/songs/{id} [patch]
+func PatchSong(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting PatchSong handler")
+
+	id, ok := parseSongID(c)
+	if !ok {
+		return
+	}
+
+	log.Debugf("Request to patch song with ID: %d, content type: %s", id, c.ContentType())
+
+	change, ok := songPatch(c)
+	if !ok {
+		return
+	}
+
+	song, err := library.ModifySong(c.Request.Context(), id, ifMatch(c), change)
+
+	var applyErr *patchError
+	switch {
+	case errors.As(err, &applyErr):
+		respondError(c, http.StatusUnprocessableEntity, "Failed to apply patch: "+applyErr.err.Error())
+	case err != nil:
+		respondServiceError(c, err)
+	default:
+		c.Header("ETag", songETag(song.ID, song.Version))
+		c.JSON(http.StatusOK, song.Song)
+	}
+}
+
+// songPatch читает патч песни из тела запроса и возвращает функцию, применяющую
+// его к текущему состоянию песни. Формат патча определяется по Content-Type.
+// Если патч некорректен, отвечает ошибкой и возвращает false
+func songPatch(c *gin.Context) (func(current models.Song) (models.SongInput, error), bool) {
+	log := logger.FromContext(c.Request.Context())
+
+	body, ok := readSongBody(c)
+	if !ok {
+		return nil, false
+	}
+
+	// Проверяем формат патча до обращения к базе данных
+	var operations jsonpatch.Patch
+	var err error
+	switch contentType := c.ContentType(); contentType {
+	case jsonPatchContentType:
+		operations, err = jsonpatch.DecodePatch(body)
+		if err != nil {
+			log.Errorf("Invalid JSON Patch: %v", err)
+			respondError(c, http.StatusBadRequest, "Invalid JSON Patch: "+err.Error())
+			return nil, false
+		}
+	case mergePatchContentType, "application/json":
+		if !json.Valid(body) {
+			log.Error("Invalid JSON Merge Patch")
+			respondError(c, http.StatusBadRequest, "Invalid JSON Merge Patch")
+			return nil, false
+		}
+	default:
+		log.Errorf("Unsupported patch content type: %s", contentType)
+		respondError(c, http.StatusUnsupportedMediaType, "Unsupported patch format, use "+mergePatchContentType+" or "+jsonPatchContentType)
+		return nil, false
+	}
+
+	return func(current models.Song) (models.SongInput, error) {
+		document, err := json.Marshal(service.SongToInput(current))
+		if err != nil {
+			log.Errorf("Failed to marshal song: %v", err)
+			return models.SongInput{}, err
+		}
+
+		var patched []byte
+		if operations != nil {
+			patched, err = operations.Apply(document)
+		} else {
+			patched, err = jsonpatch.MergePatch(document, body)
+		}
+		if err != nil {
+			log.Errorf("Failed to apply patch to song with ID %d: %v", current.ID, err)
+			return models.SongInput{}, &patchError{songID: current.ID, err: err}
+		}
+
+		log.Debugf("Patched song document: %s", patched)
+
+		input, err := validation.DecodeSongInput(patched)
+		if err != nil {
+			log.Errorf("Patched song with ID %d is invalid: %v", current.ID, err)
+			return models.SongInput{}, service.Invalid("Invalid song data", err)
+		}
+		return input, nil
+	}, true
+}
+
+// patchError - патч не удалось применить к песне
+type patchError struct {
+	songID int
+	err    error
+}
+
+func (e *patchError) Error() string {
+	return "failed to apply patch: " + e.err.Error()
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/playlist"
+	"github.com/inanmasov/music-service/internal/service"
+)
+
+// playlistMaxBytes - максимальный размер импортируемого плейлиста (10 МБ)
+const playlistMaxBytes = 10 << 20
+
+// ExportPlaylist выгружает песни в виде плейлиста
+// @Summary Export songs as a playlist
+// @Description Streams the songs matching the songs list filters, or the songs with the given IDs in the given order, as an extended M3U8, XSPF or JSPF playlist. Each track has the group as creator, the song name as title and the song link as location. M3U8 requires a location for every track, so songs without a link get the about:blank placeholder in that format.
+// @Tags playlists
+// @Produce audio/x-mpegurl
+// @Produce application/xspf+xml
+// @Produce application/jspf+json
+// @Param format query string false "Playlist format: m3u8, xspf or jspf" default(m3u8)
+// @Param title query string false "Playlist title"
+// @Param creator query string false "Playlist creator"
+// @Param ids query string false "Comma-separated song IDs; the playlist keeps this order"
+// @Param groupName query string false "Group name for filtering"
+// @Param song query string false "Song name for filtering"
+// @Param releaseDate query string false "Release date for filtering"
+// @Param text query string false "Text for filtering"
+// @Param link query string false "Link for filtering"
+// @Success 200 {file} file "Playlist"
+// @Failure 400 {object} models.ErrorResponse "Unsupported format, invalid song IDs or release date"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database or retrieve songs"
+// @Router /api/v1/songs/playlist [get]
+func ExportPlaylist(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting ExportPlaylist handler")
+
+	format := c.DefaultQuery("format", playlist.FormatM3U8)
+	contentType, extension, err := playlist.ContentType(format)
+	if err != nil {
+		log.Errorf("Invalid playlist format: %v", err)
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Получаем список ID песен, если плейлист задан явно
+	var ids []int64
+	if idsParam := c.Query("ids"); idsParam != "" {
+		for _, value := range strings.Split(idsParam, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil || id <= 0 {
+				log.Errorf("Invalid song ID in ids: %s", value)
+				respondError(c, http.StatusBadRequest, "Invalid song IDs")
+				return
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	log.Debugf("Request to export playlist: format=%s, ids=%v", format, ids)
+
+	filter := songFilter(c)
+	filter.IDs = ids
+
+	// Ответ начинается с первой песней, поэтому ошибка запроса ещё может быть
+	// передана статусом. После начала ответа ошибки только логируются
+	var writer playlist.Writer
+	started := false
+	start := func() error {
+		started = true
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", `attachment; filename="playlist.`+extension+`"`)
+		c.Status(http.StatusOK)
+		disableWriteTimeout(c)
+
+		writer, err = playlist.NewWriter(c.Writer, format, c.Query("title"), c.Query("creator"))
+		return err
+	}
+
+	count := 0
+	err = library.EachSong(c.Request.Context(), filter, 1, 0, func(song service.Song) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+
+		track := playlist.Track{Title: song.SongName, Creator: song.GroupName, Location: song.Link}
+		if err := writer.Write(track); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err != nil {
+		if !started {
+			respondServiceError(c, err)
+			return
+		}
+		log.Errorf("Failed to export playlist: %v", err)
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Errorf("Failed to finish playlist: %v", err)
+		return
+	}
+
+	log.Infof("Exported playlist with %d songs successfully", count)
+}
+
+// ImportPlaylist сопоставляет треки плейлиста с песнями библиотеки
+// @Summary Import a playlist
+// @Description Reads an M3U8, XSPF or JSPF playlist and matches each track to an existing song by group (creator) and song name (title), case-insensitively, falling back to the song link (location). With create=true tracks that have a creator and a title but no matching song are added to the library. The report lists the result of every track.
+// @Tags playlists
+// @Accept audio/x-mpegurl
+// @Accept application/xspf+xml
+// @Accept application/jspf+json
+// @Produce json
+// @Param format query string false "Playlist format: m3u8, xspf or jspf. Detected from Content-Type if omitted"
+// @Param create query bool false "Create songs for unmatched tracks" default(false)
+// @Param playlist body string true "Playlist file contents"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the first response instead of repeating it"
+// @Success 200 {object} models.PlaylistImportReport "Import report"
+// @Failure 400 {object} models.ErrorResponse "Unsupported format or malformed playlist"
+// @Failure 409 {object} models.ErrorResponse "A request with the same Idempotency-Key is still in progress"
+// @Failure 422 {object} models.ErrorResponse "Idempotency-Key has already been used with a different request"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database"
+// @Router /api/v1/songs/playlist/import [post]
+func ImportPlaylist(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting ImportPlaylist handler")
+
+	format, err := playlist.ParseFormat(c.Query("format"), c.ContentType())
+	if err != nil {
+		log.Errorf("Invalid playlist format: %v", err)
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	create, err := strconv.ParseBool(c.DefaultQuery("create", "false"))
+	if err != nil {
+		log.Errorf("Invalid create parameter: %v", err)
+		respondError(c, http.StatusBadRequest, "Invalid create parameter")
+		return
+	}
+
+	log.Debugf("Request to import playlist: format=%s, create=%t", format, create)
+
+	list, err := playlist.Decode(http.MaxBytesReader(c.Writer, c.Request.Body, playlistMaxBytes), format)
+	if err != nil {
+		log.Errorf("Failed to parse playlist: %v", err)
+		respondError(c, http.StatusBadRequest, "Malformed playlist: "+err.Error())
+		return
+	}
+
+	log.Debugf("Parsed playlist %q with %d tracks", list.Title, len(list.Tracks))
+
+	report := models.PlaylistImportReport{
+		Title:   list.Title,
+		Creator: list.Creator,
+		Tracks:  []models.PlaylistTrackResult{},
+	}
+	for i, track := range list.Tracks {
+		result := matchTrack(c.Request.Context(), i+1, track, create)
+		switch result.Status {
+		case models.PlaylistTrackMatched:
+			report.Matched++
+		case models.PlaylistTrackCreated:
+			report.Created++
+		case models.PlaylistTrackUnmatched:
+			report.Unmatched++
+		}
+		report.Tracks = append(report.Tracks, result)
+	}
+	report.Total = len(report.Tracks)
+
+	log.Infof("Imported playlist: %d matched, %d created, %d unmatched", report.Matched, report.Created, report.Unmatched)
+
+	c.JSON(http.StatusOK, report)
+}
+
+// matchTrack сопоставляет трек плейлиста с песней библиотеки и описывает результат
+func matchTrack(ctx context.Context, position int, track playlist.Track, create bool) models.PlaylistTrackResult {
+	result := models.PlaylistTrackResult{
+		Position: position,
+		Title:    track.Title,
+		Creator:  track.Creator,
+		Location: track.Location,
+		Status:   models.PlaylistTrackUnmatched,
+	}
+
+	songID, created, err := library.MatchTrack(ctx, service.PlaylistTrack{Group: track.Creator, Song: track.Title, Link: track.Location}, create)
+	var svcErr *service.Error
+	switch {
+	case errors.Is(err, service.ErrValidation) && errors.As(err, &svcErr):
+		result.Reason = "Track cannot be added to the library"
+		result.Errors = svcErr.Fields()
+		return result
+	case err != nil:
+		result.Reason = serviceMessage(err)
+		return result
+	}
+
+	result.SongID = songID
+	result.Status = models.PlaylistTrackMatched
+	if created {
+		result.Status = models.PlaylistTrackCreated
+	}
+	return result
+}
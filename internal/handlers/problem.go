@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+const (
+	// problemContentType - тип содержимого ответов с ошибками по RFC 7807
+	problemContentType = "application/problem+json"
+	// validationProblemType - тип проблемы для ошибок проверки входных данных
+	validationProblemType = "/problems/validation-error"
+	// malformedProblemType - тип проблемы для синтаксически некорректного тела запроса
+	malformedProblemType = "/problems/malformed-request"
+)
+
+// respondError отвечает ошибкой models.ErrorResponse со статусом status
+func respondError(c *gin.Context, status int, message string) {
+	c.JSON(status, models.ErrorResponse{
+		Error:     message,
+		Code:      errorCode(status),
+		RequestID: c.Writer.Header().Get(logger.RequestIDHeader),
+	})
+}
+
+// errorCode возвращает машиночитаемый код ошибки по HTTP-статусу: not_found, conflict...
+func errorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_").Replace(text))
+}
+
+// respondProblem отвечает описанием проблемы в формате application/problem+json
+func respondProblem(c *gin.Context, problem models.Problem) {
+	if problem.Instance == "" {
+		problem.Instance = c.Request.URL.Path
+	}
+	c.Header("Content-Type", problemContentType)
+	c.JSON(problem.Status, problem)
+}
+
+// respondInvalidSong отвечает 400 с перечнем всех некорректных полей песни, либо,
+// если тело запроса не удалось разобрать, с описанием синтаксической ошибки
+func respondInvalidSong(c *gin.Context, err error) {
+	respondInvalid(c, "Invalid song data", err)
+}
+
+// respondInvalid отвечает 400 с перечнем некорректных полей и заголовком title,
+// либо с описанием синтаксической ошибки тела запроса
+func respondInvalid(c *gin.Context, title string, err error) {
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		respondProblem(c, models.Problem{
+			Type:   validationProblemType,
+			Title:  title,
+			Status: http.StatusBadRequest,
+			Detail: "One or more fields are invalid",
+			Errors: fieldErrs,
+		})
+		return
+	}
+
+	respondProblem(c, models.Problem{
+		Type:   malformedProblemType,
+		Title:  "Malformed request body",
+		Status: http.StatusBadRequest,
+		Detail: err.Error(),
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/logger"
+)
+
+// parseSongID получает ID песни из URL. При некорректном ID отвечает 400 и возвращает false
+func parseSongID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		logger.FromContext(c.Request.Context()).Errorf("Invalid song ID: %s", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid song ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// songMaxBytes - максимальный размер тела запроса с данными песни (1 МБ): текст
+// песни ограничен MaxTextLength символами, остальные поля - сотнями
+const songMaxBytes = 1 << 20
+
+// limitSongBody ограничивает тело запроса размером songMaxBytes
+func limitSongBody(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, songMaxBytes)
+}
+
+// readSongBody читает тело запроса с данными песни. Если тело больше songMaxBytes,
+// отвечает 413, если его не удалось прочитать - 400, и возвращает false
+func readSongBody(c *gin.Context) ([]byte, bool) {
+	log := logger.FromContext(c.Request.Context())
+
+	limitSongBody(c)
+	body, err := io.ReadAll(c.Request.Body)
+	if bodyTooLarge(err) {
+		log.Errorf("Request body is too large: %v", err)
+		respondError(c, http.StatusRequestEntityTooLarge, "Request body is too large")
+		return nil, false
+	} else if err != nil {
+		log.Errorf("Failed to read request body: %v", err)
+		respondError(c, http.StatusBadRequest, "Failed to read request body")
+		return nil, false
+	}
+	return body, true
+}
+
+// bodyTooLarge сообщает, что чтение тела запроса прервано из-за ограничения размера
+func bodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSongBodyTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/songs", AddSong)
+	router.PUT("/songs/:id", UpdateSong)
+	router.PATCH("/songs/:id", PatchSong)
+
+	body := `{"group":"Muse","song":"Uprising","text":"` + string(bytes.Repeat([]byte("a"), songMaxBytes)) + `"}`
+	for _, tt := range []struct{ method, target, contentType string }{
+		{http.MethodPost, "/songs", "application/json"},
+		{http.MethodPut, "/songs/1", "application/json"},
+		{http.MethodPatch, "/songs/1", mergePatchContentType},
+	} {
+		req := httptest.NewRequest(tt.method, tt.target, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.target, rec.Code, http.StatusRequestEntityTooLarge)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/logger"
+)
+
+// disableWriteTimeout снимает таймаут записи сервера для потокового ответа, длительность
+// которого зависит от размера библиотеки или времени подключения клиента
+func disableWriteTimeout(c *gin.Context) {
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.FromContext(c.Request.Context()).Warnf("Failed to disable write timeout: %v", err)
+	}
+}
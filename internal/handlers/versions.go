@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIPrefix - префикс маршрутов текущей версии API
+const APIPrefix = "/api/v1"
+
+// legacyRouteKey - ключ контекста gin, отмечающий запрос к маршруту без префикса версии
+const legacyRouteKey = "legacyRoute"
+
+// Deprecated отмечает маршруты без префикса версии устаревшими: ответы получают
+// заголовки Deprecation, Sunset с датой отключения маршрутов и Link на тот же
+// ресурс в текущей версии API
+func Deprecated(sunset time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(legacyRouteKey, true)
+		c.Header("Deprecation", "true")
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		c.Header("Link", "<"+APIPrefix+c.Request.URL.Path+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
+
+// legacyRoute сообщает, что запрос пришёл на маршрут без префикса версии
+func legacyRoute(c *gin.Context) bool {
+	return c.GetBool(legacyRouteKey)
+}
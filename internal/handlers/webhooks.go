@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+// RegisterWebhook регистрирует вебхук
+// @Summary Register a webhook
+// @Description Subscribes a URL to library change events: song.created, song.updated, song.deleted, song.merged, group.created, group.updated and group.deleted. An empty eventTypes list subscribes to all events. Every delivery is a POST with the event as JSON body, signed with HMAC-SHA256 of the body using the webhook secret in the X-Webhook-Signature-256 header ("sha256=<hex>"). If no secret is given one is generated; the secret is returned only in this response. Idempotency-Key is ignored, so that the response with the secret is never stored.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.WebhookInput true "Webhook details"
+// @Success 201 {object} models.Webhook "Webhook registered"
+// @Failure 400 {object} models.Problem "Invalid input data"
+// @Failure 500 {object} models.ErrorResponse "Failed to save webhook or connect to database"
+// @Router /api/v1/webhooks [post]
+func RegisterWebhook(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting RegisterWebhook handler")
+
+	var input models.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Errorf("Failed to bind JSON: %v", err)
+		respondInvalid(c, "Invalid webhook data", validation.DecodeError(err))
+		return
+	}
+
+	log.Debugf("Request to register webhook: url=%s, events=%v", input.URL, input.EventTypes)
+
+	webhook, err := library.RegisterWebhook(c.Request.Context(), input)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.Header("Location", APIPrefix+"/webhooks/"+strconv.Itoa(webhook.ID))
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// GetWebhooks возвращает список вебхуков
+// @Summary List webhooks
+// @Description Returns all registered webhooks without their secrets
+// @Tags webhooks
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of webhooks per page" default(50)
+// @Success 200 {object} models.Page[models.Webhook] "Webhooks"
+// @Failure 400 {object} models.ErrorResponse "Invalid page or limit number"
+// @Failure 500 {object} models.ErrorResponse "Failed to retrieve webhooks or connect to database"
+// @Router /api/v1/webhooks [get]
+func GetWebhooks(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting GetWebhooks handler")
+
+	page, limit, ok := parsePagination(c, listDefaultLimit)
+	if !ok {
+		return
+	}
+
+	webhooks, total, err := library.ListWebhooks(c.Request.Context(), page, limit)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	log.Infof("Retrieved %d webhooks", len(webhooks))
+
+	c.JSON(http.StatusOK, newPage(c, webhooks, page, limit, total))
+}
+
+// TestWebhook отправляет вебхуку тестовое событие
+// @Summary Send a test event to a webhook
+// @Description Synchronously sends a signed webhook.test event to the webhook URL and reports the result. The test event is not stored and not retried.
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the first response instead of repeating it"
+// @Success 200 {object} models.WebhookTestResult "Test delivery result"
+// @Failure 400 {object} models.ErrorResponse "Invalid webhook ID"
+// @Failure 404 {object} models.ErrorResponse "Webhook not found"
+// @Failure 409 {object} models.ErrorResponse "A request with the same Idempotency-Key is still in progress"
+// @Failure 422 {object} models.ErrorResponse "Idempotency-Key has already been used with a different request"
+// @Failure 500 {object} models.ErrorResponse "Failed to connect to database"
+// @Router /api/v1/webhooks/{id}/test [post]
+func TestWebhook(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting TestWebhook handler")
+
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	result, err := library.TestWebhook(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DisableWebhook отключает вебхук
+// @Summary Disable a webhook
+// @Description Stops delivering events to the webhook. Pending deliveries are kept but not sent while the webhook is disabled.
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the first response instead of repeating it"
+// @Success 200 {object} models.Webhook "Disabled webhook"
+// @Failure 400 {object} models.ErrorResponse "Invalid webhook ID"
+// @Failure 404 {object} models.ErrorResponse "Webhook not found"
+// @Failure 409 {object} models.ErrorResponse "A request with the same Idempotency-Key is still in progress"
+// @Failure 422 {object} models.ErrorResponse "Idempotency-Key has already been used with a different request"
+// @Failure 500 {object} models.ErrorResponse "Failed to update webhook or connect to database"
+// @Router /api/v1/webhooks/{id}/disable [post]
+func DisableWebhook(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting DisableWebhook handler")
+
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := library.DisableWebhook(c.Request.Context(), id)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// GetDeadLetters возвращает доставки, исчерпавшие все попытки
+// @Summary List dead-lettered deliveries
+// @Description Returns webhook deliveries that failed after the maximum number of attempts, newest first
+// @Tags webhooks
+// @Produce json
+// @Param webhookId query int false "Only deliveries of this webhook"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of deliveries per page" default(50)
+// @Success 200 {object} models.Page[models.WebhookDelivery] "Dead-lettered deliveries"
+// @Failure 400 {object} models.ErrorResponse "Invalid webhook ID, page or limit number"
+// @Failure 500 {object} models.ErrorResponse "Failed to retrieve deliveries or connect to database"
+// @Router /api/v1/webhooks/dead-letters [get]
+func GetDeadLetters(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting GetDeadLetters handler")
+
+	page, limit, ok := parsePagination(c, listDefaultLimit)
+	if !ok {
+		return
+	}
+
+	webhookID := 0
+	if value := c.Query("webhookId"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			log.Errorf("Invalid webhook ID: %s", value)
+			respondError(c, http.StatusBadRequest, "Invalid webhook ID")
+			return
+		}
+		webhookID = id
+	}
+
+	deliveries, total, err := library.ListDeadLetters(c.Request.Context(), webhookID, page, limit)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	log.Infof("Retrieved %d dead-lettered deliveries", len(deliveries))
+
+	c.JSON(http.StatusOK, newPage(c, deliveries, page, limit, total))
+}
+
+// RetryDeadLetter возвращает доставку из dead-letter в очередь
+// @Summary Retry a dead-lettered delivery
+// @Description Resets the attempt counter of a dead-lettered delivery and schedules it for immediate delivery
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Delivery ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request return the first response instead of repeating it"
+// @Success 202 {object} models.Message "Delivery scheduled"
+// @Failure 400 {object} models.ErrorResponse "Invalid delivery ID"
+// @Failure 404 {object} models.ErrorResponse "Dead-lettered delivery not found"
+// @Failure 409 {object} models.ErrorResponse "A request with the same Idempotency-Key is still in progress"
+// @Failure 422 {object} models.ErrorResponse "Idempotency-Key has already been used with a different request"
+// @Failure 500 {object} models.ErrorResponse "Failed to update delivery or connect to database"
+// @Router /api/v1/webhooks/dead-letters/{id}/retry [post]
+func RetryDeadLetter(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Starting RetryDeadLetter handler")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		log.Errorf("Invalid delivery ID: %s", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+
+	if err := library.RetryDeadLetter(c.Request.Context(), id); err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.Message{Message: "Delivery scheduled for retry"})
+}
+
+// parseWebhookID получает ID вебхука из URL. При некорректном ID отвечает 400 и возвращает false
+func parseWebhookID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		logger.FromContext(c.Request.Context()).Errorf("Invalid webhook ID: %s", c.Param("id"))
+		respondError(c, http.StatusBadRequest, "Invalid webhook ID")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,50 @@
+package health
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// Сведения о сборке, задаются при сборке флагами компоновщика:
+//
+//	go build -ldflags "-X github.com/inanmasov/music-service/internal/health.Version=v1.4.0
+//	  -X github.com/inanmasov/music-service/internal/health.Commit=$(git rev-parse --short HEAD)
+//	  -X github.com/inanmasov/music-service/internal/health.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Если коммит и время не заданы, они берутся из данных системы контроля версий,
+// которые go build встраивает в бинарный файл
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Build возвращает сведения о сборке сервиса
+func Build() models.BuildInfo {
+	build := models.BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if build.Commit == "" {
+				build.Commit = setting.Value
+			}
+		case "vcs.time":
+			if build.BuildTime == "" {
+				build.BuildTime = setting.Value
+			}
+		}
+	}
+	return build
+}
@@ -0,0 +1,195 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/db"
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// Имена проверяемых зависимостей
+const (
+	CheckDatabase   = "database"
+	CheckMigrations = "migrations"
+	CheckEnrichment = "enrichment"
+)
+
+// checkTimeout - сколько ждать ответа каждой зависимости при проверке
+const checkTimeout = 2 * time.Second
+
+var (
+	startedAt    = time.Now()
+	shuttingDown atomic.Bool
+	stopping     = make(chan struct{})
+	stopOnce     sync.Once
+	expected     atomic.Uint64
+)
+
+// probeClient проверяет доступность внешнего API. Проверки не трассируются,
+// чтобы частые запросы оркестратора не засоряли трассировки
+var probeClient = &http.Client{Timeout: checkTimeout}
+
+// SetExpectedMigration задаёт версию схемы, с которой должен работать сервис
+func SetExpectedMigration(version uint) {
+	expected.Store(uint64(version))
+}
+
+// SetShuttingDown отмечает начало остановки сервиса: с этого момента проверка
+// готовности не проходит, и балансировщик перестаёт направлять сюда новые запросы
+func SetShuttingDown() {
+	stopOnce.Do(func() {
+		shuttingDown.Store(true)
+		close(stopping)
+	})
+}
+
+// ShuttingDown сообщает, началась ли остановка сервиса
+func ShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// Stopping возвращает канал, закрываемый в начале остановки сервиса. Долгие потоковые
+// ответы завершаются по нему, чтобы не задерживать остановку сервера
+func Stopping() <-chan struct{} {
+	return stopping
+}
+
+// StartedAt возвращает время запуска сервиса
+func StartedAt() time.Time {
+	return startedAt
+}
+
+// Report - результаты проверки всех зависимостей
+type Report struct {
+	Status     string
+	Checks     map[string]models.CheckResult
+	Migrations *models.MigrationStatus
+}
+
+// Check параллельно проверяет базу данных, версию схемы и внешний API.
+// База данных и схема обязательны: их недоступность даёт статус fail.
+// Недоступность внешнего API даёт статус degraded - песни можно добавлять
+// и без обогащения данными
+func Check(ctx context.Context) Report {
+	report := Report{Checks: make(map[string]models.CheckResult, 3)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	record := func(name string, result models.CheckResult) {
+		mu.Lock()
+		report.Checks[name] = result
+		mu.Unlock()
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		database, migrations, status := checkDatabase(ctx)
+		record(CheckDatabase, database)
+		if migrations.Status != "" {
+			record(CheckMigrations, migrations)
+		}
+		mu.Lock()
+		report.Migrations = status
+		mu.Unlock()
+	}()
+	go func() {
+		defer wg.Done()
+		record(CheckEnrichment, checkEnrichment(ctx))
+	}()
+	wg.Wait()
+
+	report.Status = models.HealthOK
+	for name, result := range report.Checks {
+		switch {
+		case result.Status == models.HealthFail && name == CheckEnrichment:
+			if report.Status == models.HealthOK {
+				report.Status = models.HealthDegraded
+			}
+		case result.Status == models.HealthFail:
+			report.Status = models.HealthFail
+		}
+	}
+	return report
+}
+
+// checkDatabase проверяет подключение к базе данных и версию схемы.
+// Версия схемы не проверяется, если база недоступна
+func checkDatabase(ctx context.Context) (database, migrations models.CheckResult, status *models.MigrationStatus) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	started := time.Now()
+	pool, err := db.Initialize()
+	if err == nil {
+		err = pool.PingContext(ctx)
+	}
+	database = result(started, err)
+	if err != nil {
+		return database, migrations, nil
+	}
+
+	started = time.Now()
+	current, err := migrationStatus(ctx, pool, uint(expected.Load()))
+	if err == nil {
+		err = checkVersion(current)
+	}
+	return database, result(started, err), &current
+}
+
+// checkVersion сверяет текущую версию схемы с ожидаемой
+func checkVersion(status models.MigrationStatus) error {
+	switch {
+	case status.Dirty:
+		return fmt.Errorf("migration %d failed and left the schema dirty", status.Current)
+	case status.Current < status.Expected:
+		return fmt.Errorf("schema version %d is behind expected version %d", status.Current, status.Expected)
+	case status.Current > status.Expected:
+		return fmt.Errorf("schema version %d is ahead of expected version %d", status.Current, status.Expected)
+	}
+	return nil
+}
+
+// checkEnrichment проверяет, что внешний API отвечает. Любой ответ, кроме 5xx,
+// означает, что API доступен
+func checkEnrichment(ctx context.Context) models.CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	started := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimRight(config.Get().MusicAPI.URL, "/")+"/", nil)
+	if err != nil {
+		return result(started, err)
+	}
+
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		return result(started, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return result(started, fmt.Errorf("unexpected status %d", resp.StatusCode))
+	}
+	return result(started, nil)
+}
+
+// result формирует результат проверки, начатой в started
+func result(started time.Time, err error) models.CheckResult {
+	check := models.CheckResult{
+		Status:    models.HealthOK,
+		LatencyMs: float64(time.Since(started).Microseconds()) / 1000,
+	}
+	if err != nil {
+		check.Status = models.HealthFail
+		check.Error = err.Error()
+	}
+	return check
+}
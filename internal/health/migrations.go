@@ -0,0 +1,25 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// migrationStatus читает текущую версию схемы из таблицы golang-migrate.
+// Если миграции ещё не применялись, текущая версия равна 0
+func migrationStatus(ctx context.Context, db *sql.DB, expected uint) (models.MigrationStatus, error) {
+	status := models.MigrationStatus{Expected: expected}
+
+	var version int64
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").
+		Scan(&version, &status.Dirty)
+	if err != nil && err != sql.ErrNoRows {
+		return status, err
+	}
+	if version > 0 {
+		status.Current = uint(version)
+	}
+	return status, nil
+}
@@ -0,0 +1,105 @@
+// Package idempotency хранит в PostgreSQL ответы на запросы с ключом идемпотентности,
+// чтобы повтор запроса с тем же ключом получал тот же ответ, а не выполнялся снова
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// purgeBatch - сколько просроченных ключей удаляется при захвате нового ключа
+const purgeBatch = 100
+
+// ErrNotFound - ключа нет или он просрочен
+var ErrNotFound = errors.New("idempotency key not found")
+
+// Response - сохранённый ответ на первый запрос с ключом
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Entry - состояние ключа. Response равен nil, пока первый запрос ещё выполняется
+type Entry struct {
+	// Fingerprint - отпечаток первого запроса
+	Fingerprint string
+	Response    *Response
+}
+
+// Acquire захватывает ключ key для выполнения запроса с отпечатком fingerprint
+// и возвращает true. Если ключ уже занят, возвращает false, и отпечаток занявшего
+// его запроса можно сравнить через Lookup, даже пока тот выполняется. Захваченный
+// ключ без ответа освобождается через lockTimeout, даже если процесс не вызвал
+// Complete или Release, например, упал. Заодно удаляются просроченные ключи
+func Acquire(ctx context.Context, db *sql.DB, key, fingerprint string, lockTimeout time.Duration) (bool, error) {
+	_, err := db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE key IN (
+			SELECT key FROM idempotency_keys WHERE expires_at <= now() ORDER BY expires_at LIMIT $1
+		) OR (key = $2 AND expires_at <= now())`, purgeBatch, key)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (key, fingerprint, expires_at)
+		VALUES ($1, $2, now() + $3 * interval '1 millisecond')
+		ON CONFLICT (key) DO NOTHING`, key, fingerprint, lockTimeout.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	return inserted == 1, err
+}
+
+// Lookup возвращает состояние непросроченного ключа key или ErrNotFound
+func Lookup(ctx context.Context, db *sql.DB, key string) (Entry, error) {
+	var fingerprint sql.NullString
+	var status sql.NullInt64
+	var header []byte
+	var body []byte
+	err := db.QueryRowContext(ctx, `
+		SELECT fingerprint, status_code, headers, body
+		FROM idempotency_keys
+		WHERE key = $1 AND expires_at > now()`, key).Scan(&fingerprint, &status, &header, &body)
+	if err == sql.ErrNoRows {
+		return Entry{}, ErrNotFound
+	} else if err != nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{Fingerprint: fingerprint.String}
+	if status.Valid {
+		entry.Response = &Response{Status: int(status.Int64), Body: body}
+		if err := json.Unmarshal(header, &entry.Response.Header); err != nil {
+			return Entry{}, err
+		}
+	}
+	return entry, nil
+}
+
+// Complete сохраняет ответ на запрос с ключом key на время ttl
+func Complete(ctx context.Context, db *sql.DB, key string, response Response, ttl time.Duration) error {
+	header, err := json.Marshal(response.Header)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $2, headers = $3, body = $4,
+			expires_at = now() + $5 * interval '1 millisecond'
+		WHERE key = $1`, key, response.Status, header, response.Body, ttl.Milliseconds())
+	return err
+}
+
+// Release освобождает ключ key без сохранения ответа, чтобы запрос можно было повторить
+func Release(ctx context.Context, db *sql.DB, key string) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = $1 AND status_code IS NULL", key)
+	return err
+}
@@ -0,0 +1,80 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/testdb"
+)
+
+func TestFingerprintIsKnownWhileInProgress(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+
+	acquired, err := Acquire(ctx, db, "key", "first", time.Minute)
+	if err != nil || !acquired {
+		t.Fatalf("Acquire = %v, %v; want true, nil", acquired, err)
+	}
+	acquired, err = Acquire(ctx, db, "key", "second", time.Minute)
+	if err != nil || acquired {
+		t.Fatalf("second Acquire = %v, %v; want false, nil", acquired, err)
+	}
+
+	// Отпечаток сохранён при захвате, поэтому другой запрос отличим от повтора до ответа
+	entry, err := Lookup(ctx, db, "key")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if entry.Fingerprint != "first" || entry.Response != nil {
+		t.Errorf("entry = %+v, want fingerprint first without response", entry)
+	}
+
+	response := Response{Status: http.StatusCreated, Header: http.Header{"Location": {"/songs/1"}}, Body: []byte(`{"id":1}`)}
+	if err := Complete(ctx, db, "key", response, time.Hour); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	entry, err = Lookup(ctx, db, "key")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if entry.Fingerprint != "first" || entry.Response == nil || entry.Response.Status != http.StatusCreated ||
+		entry.Response.Header.Get("Location") != "/songs/1" || string(entry.Response.Body) != `{"id":1}` {
+		t.Errorf("entry = %+v, want the stored response", entry)
+	}
+
+	// Ключ с сохранённым ответом не освобождается
+	if err := Release(ctx, db, "key"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if _, err := Lookup(ctx, db, "key"); err != nil {
+		t.Errorf("Lookup after Release of a completed key: %v", err)
+	}
+}
+
+func TestReleaseAndExpiry(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+
+	if _, err := Acquire(ctx, db, "released", "f", time.Minute); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if err := Release(ctx, db, "released"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if _, err := Lookup(ctx, db, "released"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup of a released key: %v, want ErrNotFound", err)
+	}
+
+	// Ключ, захваченный упавшим процессом, снова можно захватить после lockTimeout
+	if _, err := Acquire(ctx, db, "stale", "f", time.Millisecond); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	acquired, err := Acquire(ctx, db, "stale", "g", time.Minute)
+	if err != nil || !acquired {
+		t.Errorf("Acquire of an expired key = %v, %v; want true, nil", acquired, err)
+	}
+}
@@ -0,0 +1,351 @@
+package importer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+const (
+	// queueSize - сколько заданий может ожидать обработки одновременно
+	queueSize = 16
+	// jobRetention - сколько хранится информация о завершённом задании
+	jobRetention = 24 * time.Hour
+)
+
+var (
+	// ErrQueueFull возвращается, если очередь заданий импорта заполнена
+	ErrQueueFull = errors.New("import queue is full")
+	// ErrShuttingDown возвращается, если сервис останавливается и новые задания не принимаются
+	ErrShuttingDown = errors.New("import manager is shutting down")
+)
+
+// RunFunc обрабатывает задание импорта, сообщая о каждой строке через Job.AddRow
+type RunFunc func(ctx context.Context, job *Job) error
+
+// rowStatuses - статусы строк импорта по номерам, которые хранит rowOutcome
+var rowStatuses = []string{models.ImportRowCreated, models.ImportRowDuplicate, models.ImportRowFailed}
+
+// rowOutcome - краткий результат обработки строки импорта. Задание хранит его
+// для каждой строки, а полный результат - только для строк с ошибкой или
+// предупреждением, чтобы память задания росла с размером файла как можно медленнее
+type rowOutcome struct {
+	row     int32
+	songID  int32
+	status  uint8
+	warning bool
+}
+
+// Job - задание импорта. Методы Job безопасны для вызова из разных горутин.
+type Job struct {
+	mu    sync.Mutex
+	state models.ImportJob
+	// outcomes - краткие результаты строк в порядке обработки
+	outcomes []rowOutcome
+	// details - полные результаты строк с ошибкой или предупреждением по индексу в outcomes
+	details map[int]models.ImportRowResult
+
+	// Path - временный файл с загруженными данными, удаляется после обработки
+	Path string
+	run  RunFunc
+}
+
+// NewJob создаёт задание импорта файла path в формате format
+func NewJob(format string, enrich bool, path string) *Job {
+	return &Job{
+		state: models.ImportJob{
+			ID:        newJobID(),
+			Status:    models.ImportJobQueued,
+			Format:    format,
+			Enrich:    enrich,
+			CreatedAt: time.Now().UTC(),
+		},
+		details: make(map[int]models.ImportRowResult),
+		Path:    path,
+	}
+}
+
+// ID возвращает идентификатор задания
+func (j *Job) ID() string {
+	return j.state.ID
+}
+
+// Format возвращает формат импортируемого файла
+func (j *Job) Format() string {
+	return j.state.Format
+}
+
+// Enrich сообщает, нужно ли дополнять недостающие поля через внешнее API
+func (j *Job) Enrich() bool {
+	return j.state.Enrich
+}
+
+// AddRow сохраняет результат обработки строки и обновляет счётчики задания.
+// Для строк без ошибки и предупреждения сохраняются только номер, статус и ID песни
+func (j *Job) AddRow(result models.ImportRowResult) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	outcome := rowOutcome{row: int32(result.Row), songID: int32(result.SongID), warning: result.Warning != ""}
+	j.state.Total++
+	if outcome.warning {
+		j.state.Warnings++
+	}
+	switch result.Status {
+	case models.ImportRowCreated:
+		j.state.Created++
+	case models.ImportRowDuplicate:
+		j.state.Duplicates++
+		outcome.status = 1
+	case models.ImportRowFailed:
+		j.state.Failed++
+		outcome.status = 2
+	}
+
+	if outcome.status == 2 || outcome.warning {
+		j.details[len(j.outcomes)] = result
+	}
+	j.outcomes = append(j.outcomes, outcome)
+}
+
+// Snapshot возвращает текущее состояние задания без результатов строк
+func (j *Job) Snapshot() models.ImportJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.state
+}
+
+// Rows возвращает страницу page по limit результатов строк в порядке обработки
+// и общее число подходящих строк. status оставляет строки с этим статусом или,
+// если он равен models.ImportRowWarning, строки с предупреждением; пустой status
+// не ограничивает выборку. У строк без ошибки и предупреждения нет группы и названия
+func (j *Job) Rows(status string, page, limit int) ([]models.ImportRowResult, int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	rows := []models.ImportRowResult{}
+	skip := (page - 1) * limit
+	total := 0
+	for i, outcome := range j.outcomes {
+		if !outcome.matches(status) {
+			continue
+		}
+		total++
+		if total <= skip || len(rows) >= limit {
+			continue
+		}
+
+		if result, ok := j.details[i]; ok {
+			rows = append(rows, result)
+			continue
+		}
+		rows = append(rows, models.ImportRowResult{
+			Row:    int(outcome.row),
+			Status: rowStatuses[outcome.status],
+			SongID: int(outcome.songID),
+		})
+	}
+	return rows, total
+}
+
+// matches сообщает, подходит ли строка под фильтр status метода Job.Rows
+func (o rowOutcome) matches(status string) bool {
+	switch status {
+	case "":
+		return true
+	case models.ImportRowWarning:
+		return o.warning
+	default:
+		return rowStatuses[o.status] == status
+	}
+}
+
+// finished сообщает, завершилось ли задание раньше момента before
+func (j *Job) finished(before time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.state.FinishedAt != nil && j.state.FinishedAt.Before(before)
+}
+
+// setStatus переводит задание в новый статус, отмечая время начала и завершения
+func (j *Job) setStatus(status string, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now().UTC()
+	j.state.Status = status
+	switch status {
+	case models.ImportJobRunning:
+		j.state.StartedAt = &now
+	case models.ImportJobCompleted, models.ImportJobFailed:
+		j.state.FinishedAt = &now
+	}
+	if err != nil {
+		j.state.Error = err.Error()
+	}
+}
+
+// Manager хранит задания импорта и последовательно выполняет их в фоне
+type Manager struct {
+	mu    sync.RWMutex
+	jobs  map[string]*Job
+	queue chan *Job
+
+	// closed - менеджер останавливается, очередь закрыта. Защищено mu
+	closed bool
+	// ctx передаётся заданиям и отменяется, если задание не успело завершиться при остановке
+	ctx    context.Context
+	cancel context.CancelFunc
+	// done закрывается, когда обработчик очереди завершился
+	done chan struct{}
+}
+
+var (
+	instance *Manager
+	once     sync.Once
+)
+
+// GetManager возвращает общий менеджер заданий импорта, запуская его обработчик
+func GetManager() *Manager {
+	once.Do(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		instance = &Manager{
+			jobs:   make(map[string]*Job),
+			queue:  make(chan *Job, queueSize),
+			ctx:    ctx,
+			cancel: cancel,
+			done:   make(chan struct{}),
+		}
+		go instance.work()
+	})
+	return instance
+}
+
+// Submit ставит задание в очередь. Если очередь заполнена, возвращает ErrQueueFull,
+// если менеджер останавливается - ErrShuttingDown
+func (m *Manager) Submit(job *Job, run RunFunc) error {
+	job.run = run
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrShuttingDown
+	}
+
+	m.prune()
+
+	select {
+	case m.queue <- job:
+		m.jobs[job.ID()] = job
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Get возвращает задание по идентификатору
+func (m *Manager) Get(id string) (*Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	return job, ok
+}
+
+// QueueDepth возвращает количество заданий, ожидающих обработки
+func (m *Manager) QueueDepth() int {
+	return len(m.queue)
+}
+
+// Shutdown прекращает приём заданий и ждёт завершения выполняемого задания.
+// Задания, ещё не начатые к этому моменту, отмечаются как неудавшиеся. Если ctx
+// истекает раньше, выполняемое задание отменяется, и Shutdown возвращает ошибку ctx
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		m.cancel()
+		<-m.done
+		return ctx.Err()
+	}
+}
+
+// prune удаляет информацию о давно завершённых заданиях. Вызывается под m.mu
+func (m *Manager) prune() {
+	before := time.Now().Add(-jobRetention)
+	for id, job := range m.jobs {
+		if job.finished(before) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// work выполняет задания из очереди по одному
+func (m *Manager) work() {
+	log := logger.GetLogger()
+	defer close(m.done)
+
+	for job := range m.queue {
+		if m.stopping() {
+			log.Warnf("Import job %s cancelled: service is shutting down", job.ID())
+			job.setStatus(models.ImportJobFailed, ErrShuttingDown)
+			m.removeFile(job)
+			continue
+		}
+
+		log.Infof("Starting import job %s", job.ID())
+		job.setStatus(models.ImportJobRunning, nil)
+
+		err := job.run(m.ctx, job)
+		if err != nil {
+			log.Errorf("Import job %s failed: %v", job.ID(), err)
+			job.setStatus(models.ImportJobFailed, err)
+		} else {
+			log.Infof("Import job %s completed", job.ID())
+			job.setStatus(models.ImportJobCompleted, nil)
+		}
+
+		m.removeFile(job)
+	}
+}
+
+// stopping сообщает, что менеджер останавливается
+func (m *Manager) stopping() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.closed
+}
+
+// removeFile удаляет временный файл задания
+func (m *Manager) removeFile(job *Job) {
+	if err := os.Remove(job.Path); err != nil {
+		logger.GetLogger().Warnf("Failed to remove import file %s: %v", job.Path, err)
+	}
+}
+
+// newJobID генерирует случайный идентификатор задания
+func newJobID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// addRows добавляет в задание по строке каждого вида
+func addRows(job *Job) {
+	job.AddRow(models.ImportRowResult{Row: 2, Group: "Muse", Song: "Uprising", Status: models.ImportRowCreated, SongID: 1})
+	job.AddRow(models.ImportRowResult{Row: 3, Group: "Muse", Song: "Starlight", Status: models.ImportRowCreated, SongID: 2, Warning: "Failed to get song info from external API"})
+	job.AddRow(models.ImportRowResult{Row: 4, Group: "Muse", Song: "Uprising", Status: models.ImportRowDuplicate, SongID: 1})
+	job.AddRow(models.ImportRowResult{Row: 5, Status: models.ImportRowFailed, Error: "Invalid song data"})
+}
+
+func TestJobCounters(t *testing.T) {
+	job := NewJob(FormatCSV, true, "")
+	addRows(job)
+
+	snapshot := job.Snapshot()
+	if snapshot.Total != 4 || snapshot.Created != 2 || snapshot.Duplicates != 1 || snapshot.Failed != 1 || snapshot.Warnings != 1 {
+		t.Errorf("counters total=%d created=%d duplicates=%d failed=%d warnings=%d, want 4, 2, 1, 1, 1",
+			snapshot.Total, snapshot.Created, snapshot.Duplicates, snapshot.Failed, snapshot.Warnings)
+	}
+	if snapshot.Rows != nil {
+		t.Errorf("snapshot rows = %+v, want none", snapshot.Rows)
+	}
+}
+
+func TestJobRows(t *testing.T) {
+	job := NewJob(FormatCSV, true, "")
+	addRows(job)
+
+	tests := []struct {
+		status string
+		page   int
+		limit  int
+		rows   []int
+		total  int
+	}{
+		{"", 1, 10, []int{2, 3, 4, 5}, 4},
+		{"", 2, 3, []int{5}, 4},
+		{"", 3, 3, []int{}, 4},
+		{models.ImportRowCreated, 1, 10, []int{2, 3}, 2},
+		{models.ImportRowDuplicate, 1, 10, []int{4}, 1},
+		{models.ImportRowFailed, 1, 10, []int{5}, 1},
+		{models.ImportRowWarning, 1, 10, []int{3}, 1},
+	}
+	for _, tt := range tests {
+		rows, total := job.Rows(tt.status, tt.page, tt.limit)
+		numbers := []int{}
+		for _, row := range rows {
+			numbers = append(numbers, row.Row)
+		}
+		if total != tt.total || !slices.Equal(numbers, tt.rows) {
+			t.Errorf("Rows(%q, %d, %d) = rows %v, total %d, want %v, %d", tt.status, tt.page, tt.limit, numbers, total, tt.rows, tt.total)
+		}
+	}
+}
+
+func TestJobRowDetails(t *testing.T) {
+	job := NewJob(FormatCSV, true, "")
+	addRows(job)
+
+	rows, _ := job.Rows("", 1, 10)
+	if len(rows) != 4 {
+		t.Fatalf("rows = %+v, want 4 rows", rows)
+	}
+
+	// От успешной строки остаются только номер, статус и ID песни
+	want := models.ImportRowResult{Row: 2, Status: models.ImportRowCreated, SongID: 1}
+	if rows[0].Row != want.Row || rows[0].Status != want.Status || rows[0].SongID != want.SongID || rows[0].Group != "" || rows[0].Song != "" {
+		t.Errorf("created row = %+v, want %+v", rows[0], want)
+	}
+	if rows[2].Status != models.ImportRowDuplicate || rows[2].SongID != 1 {
+		t.Errorf("duplicate row = %+v, want duplicate of song 1", rows[2])
+	}
+
+	// Строки с предупреждением и ошибкой сохраняются полностью
+	if rows[1].Song != "Starlight" || rows[1].Warning == "" || rows[1].SongID != 2 {
+		t.Errorf("warning row = %+v, want full result", rows[1])
+	}
+	if rows[3].Status != models.ImportRowFailed || rows[3].Error != "Invalid song data" {
+		t.Errorf("failed row = %+v, want full result", rows[3])
+	}
+}
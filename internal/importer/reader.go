@@ -0,0 +1,218 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+// Поддерживаемые форматы файлов импорта
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Columns - столбцы CSV-файла песен в порядке экспорта. При импорте порядок
+// определяется заголовком, обязательны только group и song.
+var Columns = []string{"group", "song", "releaseDate", "text", "link"}
+
+// maxLineSize - максимальная длина строки NDJSON
+const maxLineSize = 1 << 20
+
+// errInvalidReleaseDate - ошибка строки с датой выхода в неподдерживаемом формате
+var errInvalidReleaseDate = validation.Errors{{
+	Field:   "releaseDate",
+	Code:    validation.CodeInvalidType,
+	Message: "must be a YYYY-MM-DD date or an RFC 3339 date-time",
+}}
+
+// Row - строка файла импорта. Если строку не удалось разобрать, Err содержит
+// причину, а Input может быть заполнен частично.
+type Row struct {
+	Number int
+	Input  models.SongInput
+	Err    error
+}
+
+// ParseFormat определяет формат файла по параметру запроса или типу содержимого
+func ParseFormat(format, contentType string) (string, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatNDJSON, "jsonl":
+		return FormatNDJSON, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported import format %q", format)
+	}
+
+	switch contentType {
+	case "text/csv":
+		return FormatCSV, nil
+	case "application/x-ndjson", "application/jsonl", "application/json-lines":
+		return FormatNDJSON, nil
+	}
+	return "", fmt.Errorf("cannot detect import format from content type %q", contentType)
+}
+
+// ReadRows читает строки файла в указанном формате и передаёт их в fn.
+// Ошибки отдельных строк передаются в Row.Err, возвращается только ошибка
+// чтения файла целиком или ошибка, которую вернула fn.
+func ReadRows(r io.Reader, format string, fn func(Row) error) error {
+	switch format {
+	case FormatCSV:
+		return readCSV(r, fn)
+	case FormatNDJSON:
+		return readNDJSON(r, fn)
+	default:
+		return fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// ParseReleaseDate разбирает дату выхода в формате YYYY-MM-DD или RFC 3339
+func ParseReleaseDate(value string) (time.Time, error) {
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// readCSV читает CSV-файл с заголовком
+func readCSV(r io.Reader, fn func(Row) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return errors.New("CSV file is empty")
+	} else if err != nil {
+		return fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))] = i
+	}
+	for _, required := range []string{"group", "song"} {
+		if _, ok := index[required]; !ok {
+			return fmt.Errorf("CSV header must contain column %q", required)
+		}
+	}
+
+	for number := 1; ; number++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		row := Row{Number: number}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			row.Err = err
+		} else if err != nil {
+			return fmt.Errorf("reading CSV row %d: %w", number, err)
+		} else {
+			row.Input, row.Err = csvInput(record, index)
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// csvInput собирает песню из записи CSV по индексам столбцов
+func csvInput(record []string, index map[string]int) (models.SongInput, error) {
+	raw := func(column string) string {
+		i, ok := index[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+	value := func(column string) string {
+		return strings.TrimSpace(raw(column))
+	}
+
+	input := models.SongInput{
+		Group: value("group"),
+		Song:  value("song"),
+		Text:  raw("text"),
+		Link:  value("link"),
+	}
+
+	if releaseDate := value("releaseDate"); releaseDate != "" {
+		date, err := ParseReleaseDate(releaseDate)
+		if err != nil {
+			return input, errInvalidReleaseDate
+		}
+		input.ReleaseDate = date
+	}
+
+	return input, nil
+}
+
+// ndjsonSong - строка NDJSON-файла. Дата выхода принимается строкой, чтобы
+// поддержать как YYYY-MM-DD, так и RFC 3339. ID игнорируется и допускается,
+// чтобы файлы экспорта можно было импортировать повторно.
+type ndjsonSong struct {
+	ID          int    `json:"id"`
+	Group       string `json:"group"`
+	Song        string `json:"song"`
+	ReleaseDate string `json:"releaseDate"`
+	Text        string `json:"text"`
+	Link        string `json:"link"`
+}
+
+// readNDJSON читает файл, в котором каждая непустая строка - JSON-объект песни
+func readNDJSON(r io.Reader, fn func(Row) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	number := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		number++
+
+		row := Row{Number: number}
+
+		var song ndjsonSong
+		if err := validation.DecodeObject(line, &song); err != nil {
+			row.Err = err
+		} else {
+			row.Input = models.SongInput{
+				Group: strings.TrimSpace(song.Group),
+				Song:  strings.TrimSpace(song.Song),
+				Text:  song.Text,
+				Link:  strings.TrimSpace(song.Link),
+			}
+			if song.ReleaseDate != "" {
+				date, err := ParseReleaseDate(song.ReleaseDate)
+				if err != nil {
+					row.Err = errInvalidReleaseDate
+				}
+				row.Input.ReleaseDate = date
+			}
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading NDJSON line %d: %w", number+1, err)
+	}
+	return nil
+}
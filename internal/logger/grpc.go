@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDMetadata - ключ метаданных gRPC с идентификатором запроса
+const requestIDMetadata = "x-request-id"
+
+// UnaryServerInterceptor - аналог RequestLogger для вызовов gRPC: присваивает
+// вызову идентификатор (из метаданных x-request-id или новый), возвращает его
+// в заголовке ответа, кладёт логгер вызова в контекст и пишет итоговую запись
+// с кодом статуса и длительностью
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		started := time.Now()
+		ctx = withCallLogger(ctx, info.FullMethod)
+
+		resp, err := handler(ctx, req)
+		logCall(ctx, started, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor - то же, что UnaryServerInterceptor, для потоковых вызовов
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		started := time.Now()
+		ctx := withCallLogger(stream.Context(), info.FullMethod)
+
+		err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+		logCall(ctx, started, err)
+		return err
+	}
+}
+
+// contextStream - поток вызова с контекстом, содержащим логгер вызова
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// withCallLogger возвращает ctx с логгером вызова method
+func withCallLogger(ctx context.Context, method string) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var requestID string
+	if values := md.Get(requestIDMetadata); len(values) > 0 {
+		requestID = values[0]
+	}
+	if !validRequestID.MatchString(requestID) {
+		requestID = newRequestID()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, requestID))
+
+	fields := logrus.Fields{
+		"request_id": requestID,
+		"method":     method,
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		fields["client"] = p.Addr.String()
+	}
+	if users := md.Get(UserHeader); len(users) > 0 && users[0] != "" {
+		fields["user"] = users[0]
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", requestID))
+	return context.WithValue(ctx, entryKey, GetLogger().WithFields(fields))
+}
+
+// logCall пишет итоговую запись о вызове. Ошибки сервера пишутся с уровнем error,
+// ошибки клиента - warn
+func logCall(ctx context.Context, started time.Time, err error) {
+	code := status.Code(err)
+	completed := FromContext(ctx).WithFields(logrus.Fields{
+		"code":       code.String(),
+		"latency_ms": time.Since(started).Milliseconds(),
+	})
+	switch code {
+	case codes.OK:
+		completed.Info("Request completed")
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.DeadlineExceeded, codes.Unimplemented:
+		completed.Error("Request completed")
+	default:
+		completed.Warn("Request completed")
+	}
+}
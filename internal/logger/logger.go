@@ -1,37 +1,45 @@
 package logger
 
 import (
-	"log"
-	"os"
+	"strings"
 	"sync"
 
-	"github.com/joho/godotenv"
+	"github.com/inanmasov/music-service/internal/config"
 	"github.com/sirupsen/logrus"
 )
 
+// Форматы вывода лога, выбираемые переменной LOG_FORMAT
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
 var (
 	instance *logrus.Logger
 	once     sync.Once
 )
 
-// GetLogger возвращает общий логгер
+// GetLogger возвращает общий логгер. Уровень и формат (text или json) берутся
+// из настроек log сервиса; некорректные значения отклоняются при загрузке настроек,
+// поэтому здесь вместо них используются info и text
 func GetLogger() *logrus.Logger {
 	once.Do(func() {
-		err := godotenv.Load()
-		if err != nil {
-			log.Fatal("error: Loading .env file")
-		}
+		cfg := config.Get().Log
 
-		level := os.Getenv("LOG_LEVEL")
+		instance = logrus.New()
+		instance.AddHook(traceHook{})
 
-		parsedLevel, err := logrus.ParseLevel(level)
+		level, err := logrus.ParseLevel(cfg.Level)
 		if err != nil {
-			log.Fatalf("Invalid log level: %v", err)
+			level = logrus.InfoLevel
 		}
+		instance.SetLevel(level)
 
-		instance = logrus.New()
-		instance.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
-		instance.SetLevel(parsedLevel)
+		var formatter logrus.Formatter = &logrus.TextFormatter{FullTimestamp: true}
+		if strings.ToLower(cfg.Format) == FormatJSON {
+			formatter = &logrus.JSONFormatter{}
+		}
+		instance.SetFormatter(&redactingFormatter{formatter: formatter})
 	})
 	return instance
 }
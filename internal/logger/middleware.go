@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader - заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// UserHeader - заголовок с пользователем, которого передаёт шлюз перед сервисом
+const UserHeader = "X-User-ID"
+
+// validRequestID - допустимый идентификатор запроса от клиента
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// GinKey - ключ логгера запроса в gin.Context
+const GinKey = "logger"
+
+// contextKey - тип ключей контекста пакета
+type contextKey struct{}
+
+// entryKey - ключ записи лога запроса в контексте
+var entryKey = contextKey{}
+
+// FromContext возвращает логгер, привязанный к ctx. Внутри обработки запроса это
+// логгер запроса с полями request_id, route, method, client и user; ctx может быть
+// и контекстом запроса, и самим gin.Context. Если в ctx есть спан трассировки,
+// в записи добавляются trace_id и span_id
+func FromContext(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(entryKey).(*logrus.Entry); ok {
+		return entry.WithContext(ctx)
+	}
+	if entry, ok := ctx.Value(GinKey).(*logrus.Entry); ok {
+		return entry.WithContext(ctx)
+	}
+	return GetLogger().WithContext(ctx)
+}
+
+// RequestLogger присваивает запросу идентификатор (из X-Request-ID или новый),
+// возвращает его в ответе, кладёт логгер запроса в контекст запроса и в
+// gin.Context под ключом GinKey и пишет итоговую запись о запросе со статусом
+// и длительностью
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		fields := logrus.Fields{
+			"request_id": requestID,
+			"method":     c.Request.Method,
+			"route":      c.FullPath(),
+			"client":     c.ClientIP(),
+		}
+		if user := requestUser(c); user != "" {
+			fields["user"] = user
+		}
+		entry := GetLogger().WithFields(fields)
+
+		ctx := context.WithValue(c.Request.Context(), entryKey, entry)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", requestID))
+		c.Request = c.Request.WithContext(ctx)
+		c.Set(GinKey, entry)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		completed := FromContext(c.Request.Context()).WithFields(logrus.Fields{
+			"path":       c.Request.URL.Path,
+			"status":     status,
+			"latency_ms": time.Since(started).Milliseconds(),
+			"bytes":      size,
+		})
+		switch {
+		case status >= 500:
+			completed.Error("Request completed")
+		case status >= 400:
+			completed.Warn("Request completed")
+		default:
+			completed.Info("Request completed")
+		}
+	}
+}
+
+// requestUser возвращает пользователя запроса: выставленного аутентификацией
+// в gin.Context, из Basic-авторизации или из заголовка шлюза
+func requestUser(c *gin.Context) string {
+	if user := c.GetString("user"); user != "" {
+		return user
+	}
+	if user, _, ok := c.Request.BasicAuth(); ok && user != "" {
+		return user
+	}
+	return c.GetHeader(UserHeader)
+}
+
+// newRequestID генерирует случайный идентификатор запроса
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(buf)
+}
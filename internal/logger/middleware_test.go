@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestLoggerContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestLogger())
+
+	var fromRequest, fromGin interface{}
+	router.GET("/songs/:id", func(c *gin.Context) {
+		fromRequest = FromContext(c.Request.Context()).Data["request_id"]
+		fromGin = FromContext(c).Data["request_id"]
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/songs/1", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(RequestIDHeader); got != "req-1" {
+		t.Errorf("response %s = %q, want req-1", RequestIDHeader, got)
+	}
+	if fromRequest != "req-1" || fromGin != "req-1" {
+		t.Errorf("request_id from request context %v, from gin.Context %v, want req-1", fromRequest, fromGin)
+	}
+}
+
+func TestRequestLoggerReplacesInvalidID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestLogger())
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nforged=1")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	got := rec.Header().Get(RequestIDHeader)
+	if got == "" || !validRequestID.MatchString(got) || got == req.Header.Get(RequestIDHeader) {
+		t.Errorf("response %s = %q, want a new identifier", RequestIDHeader, got)
+	}
+}
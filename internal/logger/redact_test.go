@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRedactString(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"no secrets", "song 42 updated", "song 42 updated"},
+		{"key=value", "host=db password=s3cret dbname=music", "host=db password=[REDACTED] dbname=music"},
+		{"key: value", `token: "abc.def"`, `token: "[REDACTED]"`},
+		{"query parameter", "GET /hook?api_key=abc&page=2", "GET /hook?api_key=[REDACTED]&page=2"},
+		{"authorization scheme", "Authorization: Basic dXNlcjpwYXNz", "Authorization: [REDACTED]"},
+		{"case", "TOKEN=abc next", "TOKEN=[REDACTED] next"},
+		{"URL userinfo", "connect postgres://music:s3cret@db:5432/music failed",
+			"connect postgres://music:[REDACTED]@db:5432/music failed"},
+		{"URL password only", "redis://:s3cret@cache:6379/0", "redis://:[REDACTED]@cache:6379/0"},
+		{"URL user without password", "postgres://music@db/music", "postgres://music@db/music"},
+		{"URL without userinfo", "https://example.com:8443/hook", "https://example.com:8443/hook"},
+		{"several URLs", "http://a:1@x and http://b:2@y", "http://a:[REDACTED]@x and http://b:[REDACTED]@y"},
+		{"URL and pair", "dsn=postgres://u:p@h/db?password=q", "dsn=postgres://u:[REDACTED]@h/db?password=[REDACTED]"},
+	}
+	for _, tt := range tests {
+		if got := RedactString(tt.in); got != tt.want {
+			t.Errorf("%s: RedactString(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRedactingFormatter(t *testing.T) {
+	var out bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&out)
+	log.SetFormatter(&redactingFormatter{formatter: &logrus.JSONFormatter{}})
+
+	entry := log.WithFields(logrus.Fields{
+		"db_password": "hunter2",
+		"Cookie":      "session=abc",
+		"dsn":         "postgres://music:s3cret@db/music",
+		"song_id":     42,
+		"group":       "Muse",
+	})
+	entry.Info("connecting with password=topsecret")
+
+	text := out.String()
+	for _, secret := range []string{"hunter2", "session=abc", "s3cret", "topsecret"} {
+		if strings.Contains(text, secret) {
+			t.Errorf("log contains %q: %s", secret, text)
+		}
+	}
+	for _, kept := range []string{`"song_id":42`, `"group":"Muse"`, "postgres://music:[REDACTED]@db/music"} {
+		if !strings.Contains(text, kept) {
+			t.Errorf("log lacks %s: %s", kept, text)
+		}
+	}
+	// Форматтер не меняет исходную запись
+	if entry.Data["db_password"] != "hunter2" {
+		t.Error("formatter changed the entry fields")
+	}
+}
@@ -0,0 +1,26 @@
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceHook добавляет в записи лога идентификаторы трассировки из контекста записи
+type traceHook struct{}
+
+func (traceHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (traceHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	spanContext := trace.SpanContextFromContext(entry.Context)
+	if spanContext.IsValid() {
+		entry.Data["trace_id"] = spanContext.TraceID().String()
+		entry.Data["span_id"] = spanContext.SpanID().String()
+	}
+	return nil
+}
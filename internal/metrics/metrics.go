@@ -0,0 +1,248 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace - префикс имён всех метрик сервиса
+const namespace = "music_service"
+
+// Исходы обращения к внешнему API
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// Результаты обращения к кешу чтения
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+// registry - реестр метрик сервиса. Отдельный от глобального, чтобы в /metrics
+// попадали только явно зарегистрированные коллекторы
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	enrichmentCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "enrichment_requests_total",
+		Help:      "Number of song info requests to the external music API by outcome.",
+	}, []string{"outcome"})
+
+	enrichmentDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "enrichment_request_duration_seconds",
+		Help:      "Latency of song info requests to the external music API by outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"outcome"})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Number of read cache lookups by cached resource and result (hit, miss, error).",
+	}, []string{"resource", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		enrichmentCalls,
+		enrichmentDuration,
+		cacheRequests,
+	)
+}
+
+// Handler возвращает обработчик /metrics в формате Prometheus
+func Handler() gin.HandlerFunc {
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return gin.WrapH(handler)
+}
+
+// Middleware считает HTTP-запросы и их длительность. Маршрут берётся из шаблона
+// (/songs/:id), а не из фактического пути, чтобы число рядов не росло с числом песен
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(started).Seconds())
+	}
+}
+
+// ObserveEnrichment учитывает обращение к внешнему API с исходом outcome
+func ObserveEnrichment(outcome string, duration time.Duration) {
+	enrichmentCalls.WithLabelValues(outcome).Inc()
+	enrichmentDuration.WithLabelValues(outcome).Observe(duration.Seconds())
+}
+
+// ObserveCache учитывает обращение к кешу чтения ресурса resource с результатом result
+func ObserveCache(resource, result string) {
+	cacheRequests.WithLabelValues(resource, result).Inc()
+}
+
+// RegisterDB регистрирует статистику пула подключений и показатели, которые
+// считаются запросами к базе при каждом сборе метрик: число песен и групп
+// и число ожидающих доставок вебхуков. Пул возвращает функция pool (например,
+// db.Initialize), поэтому регистрация не ждёт доступности базы
+func RegisterDB(pool func() (*sql.DB, error)) {
+	registry.MustRegister(
+		&poolCollector{pool: pool, stats: collectors.NewDBStatsCollector(nil, dbName)},
+		&libraryCollector{pool: pool},
+		&deliveriesCollector{pool: pool},
+	)
+}
+
+// RegisterQueueDepth регистрирует размер очереди фоновых заданий с именем queue
+func RegisterQueueDepth(queue string, depth func() int) {
+	registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "job_queue_depth",
+		Help:        "Number of background jobs waiting in the queue.",
+		ConstLabels: prometheus.Labels{"queue": queue},
+	}, func() float64 {
+		return float64(depth())
+	}))
+}
+
+// dbName - значение метки db_name статистики пула подключений
+const dbName = "postgres"
+
+// poolCollector собирает статистику пула подключений, если он уже открыт
+type poolCollector struct {
+	pool func() (*sql.DB, error)
+	// stats описывает метрики статистики пула, сам пул ему не нужен
+	stats prometheus.Collector
+}
+
+func (p *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	p.stats.Describe(ch)
+}
+
+func (p *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	db, err := p.pool()
+	if err != nil {
+		return
+	}
+	collectors.NewDBStatsCollector(db, dbName).Collect(ch)
+}
+
+// libraryCollector собирает показатели библиотеки из базы данных при каждом сборе метрик
+type libraryCollector struct {
+	pool func() (*sql.DB, error)
+}
+
+var (
+	songsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "library", "songs"),
+		"Number of songs in the library.", nil, nil)
+	groupsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "library", "groups"),
+		"Number of groups in the library.", nil, nil)
+	scrapeErrorDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "library", "scrape_error"),
+		"1 if library metrics could not be read from the database.", nil, nil)
+)
+
+// dbScrapeTimeout - сколько ждать ответа базы при сборе показателей из базы данных
+const dbScrapeTimeout = 5 * time.Second
+
+func (l *libraryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- songsDesc
+	ch <- groupsDesc
+	ch <- scrapeErrorDesc
+}
+
+func (l *libraryCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbScrapeTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM songs),
+			(SELECT COUNT(*) FROM groups)`
+
+	var songs, groups float64
+	scrapeError := 0.0
+	db, err := l.pool()
+	if err == nil {
+		err = db.QueryRowContext(ctx, query).Scan(&songs, &groups)
+	}
+	if err != nil {
+		scrapeError = 1
+	} else {
+		ch <- prometheus.MustNewConstMetric(songsDesc, prometheus.GaugeValue, songs)
+		ch <- prometheus.MustNewConstMetric(groupsDesc, prometheus.GaugeValue, groups)
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeErrorDesc, prometheus.GaugeValue, scrapeError)
+}
+
+// deliveriesCollector собирает число ожидающих доставок вебхуков из базы данных
+// при каждом сборе метрик
+type deliveriesCollector struct {
+	pool func() (*sql.DB, error)
+}
+
+var (
+	pendingDeliveriesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "webhook_deliveries", "pending"),
+		"Number of webhook deliveries waiting to be sent.", nil, nil)
+	deliveriesScrapeErrorDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "webhook_deliveries", "scrape_error"),
+		"1 if webhook delivery metrics could not be read from the database.", nil, nil)
+)
+
+func (d *deliveriesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pendingDeliveriesDesc
+	ch <- deliveriesScrapeErrorDesc
+}
+
+func (d *deliveriesCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbScrapeTimeout)
+	defer cancel()
+
+	var pending float64
+	scrapeError := 0.0
+	db, err := d.pool()
+	if err == nil {
+		err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM webhook_deliveries WHERE status = 'pending'`).Scan(&pending)
+	}
+	if err != nil {
+		scrapeError = 1
+	} else {
+		ch <- prometheus.MustNewConstMetric(pendingDeliveriesDesc, prometheus.GaugeValue, pending)
+	}
+	ch <- prometheus.MustNewConstMetric(deliveriesScrapeErrorDesc, prometheus.GaugeValue, scrapeError)
+}
@@ -0,0 +1,57 @@
+package models
+
+import "encoding/json"
+
+// Режимы выполнения пакета операций
+const (
+	// BatchAtomic - все операции в одной транзакции, ошибка любой отменяет все
+	BatchAtomic = "atomic"
+	// BatchBestEffort - каждая операция в своей транзакции независимо от других
+	BatchBestEffort = "best-effort"
+)
+
+// SongBatch - пакет операций с песнями
+type SongBatch struct {
+	// Mode - atomic (по умолчанию) или best-effort
+	Mode       string          `json:"mode" enums:"atomic,best-effort" example:"atomic"`
+	Operations []SongOperation `json:"operations"`
+}
+
+// SongOperation - операция пакета. Song - полное представление песни для create
+// (обязательны только группа и название) и update, ID - песня для update и delete
+type SongOperation struct {
+	Op string `json:"op" enums:"create,update,delete" example:"update"`
+	ID int    `json:"id,omitempty" example:"5"`
+	// Version - ожидаемая версия песни для update и delete, заменяет If-Match
+	Version *int            `json:"version,omitempty" example:"3"`
+	Song    json.RawMessage `json:"song,omitempty" swaggertype:"object"`
+}
+
+// SongBatchResult - результаты операций пакета в порядке операций
+type SongBatchResult struct {
+	Mode      string                `json:"mode" example:"atomic"`
+	Succeeded int                   `json:"succeeded" example:"2"`
+	Failed    int                   `json:"failed" example:"0"`
+	Results   []SongOperationResult `json:"results"`
+}
+
+// SongOperationResult - результат операции пакета. Status - HTTP-статус, которым
+// завершился бы такой же отдельный запрос; 424 - операция атомарного пакета
+// не применена из-за ошибки другой операции
+type SongOperationResult struct {
+	Index  int            `json:"index" example:"0"`
+	Op     string         `json:"op" example:"update"`
+	Status int            `json:"status" example:"200"`
+	Song   *Song          `json:"song,omitempty"`
+	Error  *ErrorResponse `json:"error,omitempty"`
+	// Errors - некорректные поля песни
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// BulkResult - результат массового изменения или удаления песен по фильтру
+type BulkResult struct {
+	// DryRun - изменения только проверены и не сохранены
+	DryRun bool  `json:"dryRun" example:"false"`
+	Count  int   `json:"count" example:"2"`
+	IDs    []int `json:"ids" example:"3,8"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Типы событий изменения библиотеки
+const (
+	EventSongCreated  = "song.created"
+	EventSongUpdated  = "song.updated"
+	EventSongDeleted  = "song.deleted"
+	EventSongMerged   = "song.merged"
+	EventGroupCreated = "group.created"
+	EventGroupUpdated = "group.updated"
+	EventGroupDeleted = "group.deleted"
+	// EventWebhookTest - тестовое событие, отправляемое только при проверке вебхука
+	EventWebhookTest = "webhook.test"
+)
+
+// EventTypes - все типы событий изменения библиотеки
+var EventTypes = []string{EventSongCreated, EventSongUpdated, EventSongDeleted, EventSongMerged, EventGroupCreated, EventGroupUpdated, EventGroupDeleted}
+
+// IsEventType сообщает, является ли eventType типом события изменения библиотеки
+func IsEventType(eventType string) bool {
+	for _, known := range EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event - событие изменения библиотеки, как оно доставляется подписчикам
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// Group описывает музыкальную группу
+type Group struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// GroupInput - новые данные группы
+type GroupInput struct {
+	Name string `json:"name" example:"Muse"`
+}
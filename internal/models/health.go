@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Состояния сервиса и его зависимостей
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthFail     = "fail"
+)
+
+// Liveness - ответ /healthz: процесс запущен и обрабатывает запросы
+type Liveness struct {
+	Status string `json:"status" example:"ok"`
+}
+
+// CheckResult - результат проверки одной зависимости
+type CheckResult struct {
+	Status    string  `json:"status" example:"ok"`
+	LatencyMs float64 `json:"latencyMs" example:"1.25"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Readiness - ответ /readyz. Статус fail означает, что сервис не готов принимать
+// запросы, degraded - что запросы обрабатываются, но необязательная зависимость недоступна
+type Readiness struct {
+	Status       string                 `json:"status" example:"ok"`
+	ShuttingDown bool                   `json:"shuttingDown"`
+	Checks       map[string]CheckResult `json:"checks"`
+}
+
+// BuildInfo описывает сборку сервиса
+type BuildInfo struct {
+	Version   string `json:"version" example:"v1.4.0"`
+	Commit    string `json:"commit,omitempty" example:"91843fd"`
+	BuildTime string `json:"buildTime,omitempty" example:"2024-05-01T12:00:00Z"`
+	GoVersion string `json:"goVersion" example:"go1.22.9"`
+}
+
+// MigrationStatus - версия схемы базы данных
+type MigrationStatus struct {
+	Current  uint `json:"current" example:"3"`
+	Expected uint `json:"expected" example:"3"`
+	Dirty    bool `json:"dirty"`
+}
+
+// ServiceStatus - ответ /status со сведениями о сборке, времени работы и зависимостях
+type ServiceStatus struct {
+	Status        string                 `json:"status" example:"ok"`
+	ShuttingDown  bool                   `json:"shuttingDown"`
+	Build         BuildInfo              `json:"build"`
+	StartedAt     time.Time              `json:"startedAt"`
+	UptimeSeconds float64                `json:"uptimeSeconds" example:"3600"`
+	Migrations    *MigrationStatus       `json:"migrations,omitempty"`
+	Dependencies  map[string]CheckResult `json:"dependencies"`
+}
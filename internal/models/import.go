@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Статусы задания импорта
+const (
+	ImportJobQueued    = "queued"
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+)
+
+// Статусы обработки строки импорта
+const (
+	ImportRowCreated   = "created"
+	ImportRowDuplicate = "duplicate"
+	ImportRowFailed    = "failed"
+)
+
+// ImportRowWarning - фильтр строк импорта, которые добавлены без данных внешнего
+// API из-за его сбоя. Сам статус таких строк - ImportRowCreated
+const ImportRowWarning = "warning"
+
+// ImportJob описывает состояние задания импорта песен. Rows - страница результатов
+// строк, она есть только в ответе на запрос состояния задания
+type ImportJob struct {
+	ID         string                 `json:"id"`
+	Status     string                 `json:"status"`
+	Format     string                 `json:"format"`
+	Enrich     bool                   `json:"enrich"`
+	Total      int                    `json:"total"`
+	Created    int                    `json:"created"`
+	Duplicates int                    `json:"duplicates"`
+	Failed     int                    `json:"failed"`
+	Warnings   int                    `json:"warnings"`
+	Error      string                 `json:"error,omitempty"`
+	CreatedAt  time.Time              `json:"createdAt"`
+	StartedAt  *time.Time             `json:"startedAt,omitempty"`
+	FinishedAt *time.Time             `json:"finishedAt,omitempty"`
+	Rows       *Page[ImportRowResult] `json:"rows,omitempty"`
+}
+
+// ImportRowResult описывает результат обработки одной строки импорта
+type ImportRowResult struct {
+	Row     int          `json:"row"`
+	Group   string       `json:"group,omitempty"`
+	Song    string       `json:"song,omitempty"`
+	Status  string       `json:"status"`
+	SongID  int          `json:"songId,omitempty"`
+	Warning string       `json:"warning,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
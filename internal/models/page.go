@@ -0,0 +1,26 @@
+package models
+
+// Page - страница списка. Items всегда массив, пустой, если на странице ничего нет
+type Page[T any] struct {
+	Items      []T        `json:"items"`
+	Pagination Pagination `json:"pagination"`
+	Links      PageLinks  `json:"links"`
+}
+
+// Pagination описывает положение страницы в списке
+type Pagination struct {
+	Page    int  `json:"page" example:"1"`
+	Limit   int  `json:"limit" example:"10"`
+	Total   int  `json:"total" example:"42"`
+	HasMore bool `json:"hasMore" example:"true"`
+}
+
+// PageLinks - ссылки на соседние страницы с теми же параметрами запроса.
+// Prev и Next отсутствуют на первой и последней странице
+type PageLinks struct {
+	Self  string `json:"self" example:"/api/v1/songs?limit=10&page=2"`
+	First string `json:"first" example:"/api/v1/songs?limit=10&page=1"`
+	Last  string `json:"last" example:"/api/v1/songs?limit=10&page=5"`
+	Prev  string `json:"prev,omitempty" example:"/api/v1/songs?limit=10&page=1"`
+	Next  string `json:"next,omitempty" example:"/api/v1/songs?limit=10&page=3"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// Результаты сопоставления трека плейлиста с песнями библиотеки
+const (
+	PlaylistTrackMatched   = "matched"
+	PlaylistTrackCreated   = "created"
+	PlaylistTrackUnmatched = "unmatched"
+)
+
+// PlaylistImportReport описывает результат импорта плейлиста
+type PlaylistImportReport struct {
+	Title     string                `json:"title,omitempty"`
+	Creator   string                `json:"creator,omitempty"`
+	Total     int                   `json:"total"`
+	Matched   int                   `json:"matched"`
+	Created   int                   `json:"created"`
+	Unmatched int                   `json:"unmatched"`
+	Tracks    []PlaylistTrackResult `json:"tracks"`
+}
+
+// PlaylistTrackResult описывает результат сопоставления одного трека
+type PlaylistTrackResult struct {
+	Position int          `json:"position"`
+	Title    string       `json:"title,omitempty"`
+	Creator  string       `json:"creator,omitempty"`
+	Location string       `json:"location,omitempty"`
+	Status   string       `json:"status"`
+	SongID   int          `json:"songId,omitempty"`
+	Reason   string       `json:"reason,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
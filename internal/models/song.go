@@ -9,9 +9,86 @@ type Song struct {
 	ReleaseDate time.Time `json:"releaseDate"`
 	Text        string    `json:"text"`
 	Link        string    `json:"link"`
+	Version     int       `json:"version"`
 }
 
-// ErrorResponse представляет структуру для ошибок
+// ErrorResponse - ответ с ошибкой. Code - машиночитаемый код по HTTP-статусу
+// (not_found, precondition_failed...), RequestID - идентификатор запроса из X-Request-ID
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error     string `json:"error" example:"Song not found"`
+	Code      string `json:"code" example:"not_found"`
+	RequestID string `json:"requestId,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+}
+
+// SongInput описывает полное представление песни, передаваемое клиентом при изменении
+type SongInput struct {
+	Group       string    `json:"group"`
+	Song        string    `json:"song"`
+	ReleaseDate time.Time `json:"releaseDate"`
+	Text        string    `json:"text"`
+	Link        string    `json:"link"`
+}
+
+// NewSong - данные для добавления песни. Остальные поля берутся из внешнего API
+type NewSong struct {
+	Group string `json:"group" example:"Muse"`
+	Song  string `json:"song" example:"Supermassive Black Hole"`
+}
+
+// SongList - страница списка песен
+type SongList struct {
+	Page  int    `json:"page" example:"1"`
+	Limit int    `json:"limit" example:"10"`
+	Songs []Song `json:"songs"`
+}
+
+// Verse - куплет текста песни
+type Verse string
+
+// SongText - страница куплетов текста песни
+type SongText struct {
+	Page   int      `json:"page" example:"1"`
+	Limit  int      `json:"limit" example:"2"`
+	Total  int      `json:"total" example:"4"`
+	Verses []string `json:"verses"`
+}
+
+// LegacySong - представление добавленной песни в ответе POST /songs без префикса
+// версии API. Сохранено для совместимости, в /api/v1 используется Song
+type LegacySong struct {
+	ID          int       `json:"id"`
+	GroupName   string    `json:"group_name"`
+	SongName    string    `json:"song"`
+	ReleaseDate time.Time `json:"release_date"`
+	Text        string    `json:"text"`
+	Link        string    `json:"link"`
+	Version     int       `json:"version"`
+}
+
+// Message - ответ операции, не возвращающей ресурс
+type Message struct {
+	Message string `json:"message" example:"Song deleted successfully"`
+}
+
+// DuplicateSet - песни одной группы, похожие на дубликаты друг друга
+type DuplicateSet struct {
+	// Reasons - признаки сходства: title - совпадают нормализованные названия,
+	// lyrics - тексты похожи не меньше порога minSimilarity
+	Reasons []string `json:"reasons" example:"title,lyrics"`
+	// Similarity - наибольшее сходство текстов песен набора от 0 до 1
+	Similarity float64 `json:"similarity" example:"0.93"`
+	Songs      []Song  `json:"songs"`
+}
+
+// MergeSongs - запрос слияния дубликатов с песней
+type MergeSongs struct {
+	DuplicateIDs []int `json:"duplicateIds" example:"12,31"`
+}
+
+// SongMerge - данные события song.merged: дубликаты DuplicateIDs удалены,
+// а их ID перенаправлены на песню SongID группы Group
+type SongMerge struct {
+	SongID       int    `json:"songId"`
+	Group        string `json:"group"`
+	DuplicateIDs []int  `json:"duplicateIds"`
 }
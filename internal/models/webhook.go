@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Статусы доставки события вебхуку
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Webhook описывает подписку на события изменения библиотеки
+type Webhook struct {
+	ID         int       `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"eventTypes"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"createdAt"`
+	// Secret возвращается только при регистрации вебхука
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookInput - данные для регистрации вебхука. Пустой список типов событий
+// означает подписку на все события, пустой секрет - сгенерировать его
+type WebhookInput struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"eventTypes"`
+}
+
+// WebhookTestResult - результат отправки тестового события
+type WebhookTestResult struct {
+	Delivered  bool   `json:"delivered"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// WebhookDelivery описывает доставку события вебхуку
+type WebhookDelivery struct {
+	ID             int64     `json:"id"`
+	WebhookID      int       `json:"webhookId"`
+	EventID        int64     `json:"eventId"`
+	EventType      string    `json:"eventType"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	LastStatusCode int       `json:"lastStatusCode,omitempty"`
+	LastError      string    `json:"lastError,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
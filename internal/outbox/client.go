@@ -0,0 +1,98 @@
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// maxRedirects - сколько перенаправлений подписчика выполняет клиент вебхуков
+const maxRedirects = 5
+
+// ErrForbiddenDestination - адрес вебхука ведёт во внутреннюю сеть
+var ErrForbiddenDestination = errors.New("webhook destination is not allowed")
+
+// blockedPrefixes - сети, не относящиеся к публичному интернету, которые не
+// покрываются методами netip.Addr: "этот" хост (0.0.0.0/8), общее адресное
+// пространство операторов (100.64.0.0/10), служебные и документационные сети
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// NewClient возвращает HTTP-клиент для доставки вебхуков с таймаутом запроса
+// timeout. Если allowPrivate не задан, клиент не подключается к адресам
+// внутренних сетей: закрытым, loopback, link-local (в том числе к сервису
+// метаданных облака 169.254.169.254) и служебным. Адрес проверяется при каждом
+// подключении, уже после разрешения имени, поэтому проверку не обойти ни
+// DNS-записью, ведущей во внутреннюю сеть, ни перенаправлением
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = checkDestination
+	}
+
+	transport := &http.Transport{
+		// Прокси не используется: иначе проверялся бы адрес прокси, а не подписчика
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: redirect to %s scheme", ErrForbiddenDestination, req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// checkDestination отклоняет подключение к адресу внутренней сети. address -
+// уже разрешённый адрес вида ip:port
+func checkDestination(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, address)
+	}
+	if !publicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s is not a public address", ErrForbiddenDestination, addrPort.Addr())
+	}
+	return nil
+}
+
+// publicAddr сообщает, что addr - адрес публичного интернета
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package outbox
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"::", false},
+		{"fe80::1", false},
+		{"fd00:ec2::254", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("publicAddr(%s) = %t, want %t", tt.addr, got, tt.public)
+		}
+	}
+}
+
+func TestClientRejectsPrivateDestination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second, false).Get(server.URL)
+	if !errors.Is(err, ErrForbiddenDestination) {
+		t.Errorf("request to %s: %v, want ErrForbiddenDestination", server.URL, err)
+	}
+
+	// Имя, разрешающееся в loopback, отклоняется так же, как сам адрес
+	_, err = NewClient(time.Second, false).Get("http://localhost:" + server.URL[len("http://127.0.0.1:"):])
+	if !errors.Is(err, ErrForbiddenDestination) {
+		t.Errorf("request to localhost: %v, want ErrForbiddenDestination", err)
+	}
+
+	resp, err := NewClient(time.Second, true).Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with private networks allowed: %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,323 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+const (
+	// batchSize - сколько событий и доставок обрабатывается за один проход
+	batchSize = 100
+	// maxResponseBody - сколько байт ответа подписчика сохраняется в описании ошибки
+	maxResponseBody = 512
+	// maxBackoff - максимальная пауза между попытками доставки
+	maxBackoff = time.Hour
+	// seqInterval - пауза между назначениями позиций новым событиям журнала
+	seqInterval = time.Second
+)
+
+// Dispatcher раскладывает события outbox по подписанным вебхукам и доставляет их
+// с повторными попытками. Доставки, исчерпавшие попытки, помечаются как dead.
+// Он же назначает новым событиям позиции в журнале, по которым их читает поток событий
+type Dispatcher struct {
+	pool        func() (*sql.DB, error)
+	client      *http.Client
+	interval    time.Duration
+	maxAttempts int
+	baseBackoff time.Duration
+}
+
+// NewDispatcher создаёт Dispatcher. Пул подключений возвращает функция pool
+// (например, db.Initialize): если база недоступна, проход пропускается, и следующий
+// повторит подключение. interval - пауза между проходами, timeout - таймаут одного
+// HTTP-запроса к подписчику, allowPrivate разрешает доставку во внутренние сети (см. NewClient)
+func NewDispatcher(pool func() (*sql.DB, error), interval, timeout time.Duration, maxAttempts int, allowPrivate bool) *Dispatcher {
+	return &Dispatcher{
+		pool:        pool,
+		client:      NewClient(timeout, allowPrivate),
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		baseBackoff: 10 * time.Second,
+	}
+}
+
+// Run обрабатывает outbox до отмены ctx. Позиции событиям назначаются в
+// отдельной горутине, чтобы медленные доставки не задерживали поток событий
+func (d *Dispatcher) Run(ctx context.Context) {
+	log := logger.GetLogger()
+	log.Infof("Webhook dispatcher started, interval %s", d.interval)
+
+	sequencerDone := make(chan struct{})
+	go func() {
+		defer close(sequencerDone)
+		d.sequence(ctx)
+	}()
+	defer func() { <-sequencerDone }()
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Info("Webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sequence назначает позиции новым событиям журнала каждые seqInterval до отмены ctx
+func (d *Dispatcher) sequence(ctx context.Context) {
+	ticker := time.NewTicker(seqInterval)
+	defer ticker.Stop()
+
+	for {
+		if db, err := d.pool(); err == nil {
+			if err := AssignSeq(ctx, db); err != nil && ctx.Err() == nil {
+				logger.GetLogger().Errorf("Failed to assign outbox event positions: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick выполняет один проход: раскладывает новые события по вебхукам и
+// отправляет доставки, время которых подошло
+func (d *Dispatcher) tick(ctx context.Context) {
+	log := logger.GetLogger()
+
+	db, err := d.pool()
+	if err != nil {
+		log.Warnf("Webhook dispatcher is waiting for database: %v", err)
+		return
+	}
+	if err := d.fanOut(ctx, db); err != nil {
+		log.Errorf("Failed to fan out outbox events: %v", err)
+	}
+	if err := d.deliverDue(ctx, db); err != nil {
+		log.Errorf("Failed to deliver webhook events: %v", err)
+	}
+}
+
+// fanOut создаёт доставки новых событий для всех активных вебхуков, подписанных
+// на тип события, и помечает события как разосланные
+func (d *Dispatcher) fanOut(ctx context.Context, db *sql.DB) error {
+	query := `
+		WITH events AS (
+			SELECT id, event_type
+			FROM outbox_events
+			WHERE dispatched_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), deliveries AS (
+			INSERT INTO webhook_deliveries (webhook_id, event_id)
+			SELECT webhooks.id, events.id
+			FROM events
+			JOIN webhooks ON webhooks.active
+				AND (cardinality(webhooks.event_types) = 0 OR events.event_type = ANY(webhooks.event_types))
+			ON CONFLICT (webhook_id, event_id) DO NOTHING
+		)
+		UPDATE outbox_events
+		SET dispatched_at = now()
+		WHERE id IN (SELECT id FROM events)`
+
+	result, err := db.ExecContext(ctx, query, batchSize)
+	if err != nil {
+		return err
+	}
+
+	if count, err := result.RowsAffected(); err == nil && count > 0 {
+		logger.GetLogger().Debugf("Fanned out %d outbox events", count)
+	}
+	return nil
+}
+
+// delivery - доставка, захваченная для отправки
+type delivery struct {
+	id       int64
+	attempts int
+	url      string
+	secret   string
+	event    models.Event
+}
+
+// deliverDue захватывает доставки, время которых подошло, и отправляет их.
+// На время отправки доставка откладывается, чтобы её не взял другой экземпляр сервиса
+func (d *Dispatcher) deliverDue(ctx context.Context, db *sql.DB) error {
+	query := `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET next_attempt_at = now() + make_interval(secs => $2)
+			WHERE id IN (
+				SELECT webhook_deliveries.id
+				FROM webhook_deliveries
+				JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+				WHERE webhooks.active
+					AND webhook_deliveries.status = 'pending'
+					AND webhook_deliveries.next_attempt_at <= now()
+				ORDER BY webhook_deliveries.next_attempt_at
+				LIMIT $1
+				FOR UPDATE OF webhook_deliveries SKIP LOCKED
+			)
+			RETURNING id, webhook_id, event_id, attempts
+		)
+		SELECT claimed.id, claimed.attempts, webhooks.url, webhooks.secret,
+			outbox_events.id, outbox_events.event_type, outbox_events.payload, outbox_events.created_at
+		FROM claimed
+		JOIN webhooks ON webhooks.id = claimed.webhook_id
+		JOIN outbox_events ON outbox_events.id = claimed.event_id`
+
+	lease := (d.client.Timeout + d.interval).Seconds()
+	rows, err := db.QueryContext(ctx, query, batchSize, lease)
+	if err != nil {
+		return err
+	}
+
+	var due []delivery
+	for rows.Next() {
+		var item delivery
+		var payload []byte
+		err := rows.Scan(&item.id, &item.attempts, &item.url, &item.secret,
+			&item.event.ID, &item.event.Type, &payload, &item.event.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		item.event.Data = payload
+		due = append(due, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, item := range due {
+		if ctx.Err() != nil {
+			return nil
+		}
+		d.attempt(ctx, db, item)
+	}
+	return nil
+}
+
+// attempt отправляет доставку и сохраняет результат попытки
+func (d *Dispatcher) attempt(ctx context.Context, db *sql.DB, item delivery) {
+	log := logger.GetLogger()
+
+	statusCode, err := Send(ctx, d.client, item.url, item.secret, item.id, item.event)
+	attempts := item.attempts + 1
+
+	var code interface{}
+	if statusCode != 0 {
+		code = statusCode
+	}
+
+	if err == nil {
+		log.Debugf("Delivered event %d to %s", item.event.ID, item.url)
+		_, err = db.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = 'delivered', attempts = $2, last_status_code = $3, last_error = NULL, delivered_at = now()
+			WHERE id = $1`, item.id, attempts, code)
+		if err != nil {
+			log.Errorf("Failed to save delivery %d result: %v", item.id, err)
+		}
+		return
+	}
+
+	status := models.DeliveryPending
+	if attempts >= d.maxAttempts {
+		status = models.DeliveryDead
+		log.Errorf("Delivery %d of event %d to %s is dead after %d attempts: %v", item.id, item.event.ID, item.url, attempts, err)
+	} else {
+		log.Warnf("Delivery %d of event %d to %s failed, attempt %d: %v", item.id, item.event.ID, item.url, attempts, err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, last_status_code = $4, last_error = $5,
+			next_attempt_at = now() + make_interval(secs => $6)
+		WHERE id = $1`, item.id, status, attempts, code, err.Error(), d.backoff(attempts).Seconds())
+	if err != nil {
+		log.Errorf("Failed to save delivery %d result: %v", item.id, err)
+	}
+}
+
+// backoff возвращает паузу перед следующей попыткой: экспоненциально растущую
+// от baseBackoff и ограниченную maxBackoff
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// Send отправляет событие на url с подписью HMAC-SHA256 тела запроса.
+// Успешной считается доставка с кодом ответа 2xx
+func Send(ctx context.Context, client *http.Client, url, secret string, deliveryID int64, event models.Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "music-service-webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", event.Type)
+	req.Header.Set("X-Webhook-Event-Id", strconv.FormatInt(event.ID, 10))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(deliveryID, 10))
+	req.Header.Set(SignatureHeader, Sign(secret, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode, nil
+}
+
+// RetryDead возвращает доставку id из dead-letter в очередь: сбрасывает счётчик попыток
+// и назначает отправку на ближайший проход. Возвращает false, если такой доставки нет
+func RetryDead(ctx context.Context, db *sql.DB, id int64) (bool, error) {
+	result, err := db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now()
+		WHERE id = $1 AND status = 'dead'`, id)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
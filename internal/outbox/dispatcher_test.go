@@ -0,0 +1,290 @@
+package outbox
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/testdb"
+)
+
+// receiver - подписчик вебхуков, отвечающий кодами из statuses по очереди
+// (последний код повторяется) и запоминающий полученные запросы
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+// receivedRequest - запрос, полученный receiver
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		status := r.statuses[0]
+		if len(r.statuses) > 1 {
+			r.statuses = r.statuses[1:]
+		}
+		r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// received возвращает полученные запросы
+func (r *receiver) received() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+func TestSendSignsBody(t *testing.T) {
+	r := newReceiver(t, http.StatusNoContent)
+	event := models.Event{ID: 7, Type: "song.created", CreatedAt: time.Now().UTC(), Data: json.RawMessage(`{"id":1}`)}
+
+	status, err := Send(context.Background(), http.DefaultClient, r.URL, "top-secret", 42, event)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", status, http.StatusNoContent)
+	}
+
+	requests := r.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+
+	mac := hmac.New(sha256.New, []byte("top-secret"))
+	mac.Write(req.body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := req.header.Get(SignatureHeader); got != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, got, want)
+	}
+
+	var sent models.Event
+	if err := json.Unmarshal(req.body, &sent); err != nil {
+		t.Fatalf("body is not an event: %v", err)
+	}
+	if sent.ID != event.ID || sent.Type != event.Type {
+		t.Errorf("sent event %d %q, want %d %q", sent.ID, sent.Type, event.ID, event.Type)
+	}
+
+	headers := map[string]string{
+		"Content-Type":       "application/json",
+		"X-Webhook-Event":    "song.created",
+		"X-Webhook-Event-Id": "7",
+		"X-Webhook-Delivery": "42",
+	}
+	for name, value := range headers {
+		if got := req.header.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestSendFailsOnErrorStatus(t *testing.T) {
+	r := newReceiver(t, http.StatusBadGateway)
+
+	status, err := Send(context.Background(), http.DefaultClient, r.URL, "secret", 1, models.Event{ID: 1, Type: "song.deleted"})
+	if err == nil {
+		t.Fatal("Send succeeded on 502")
+	}
+	if status != http.StatusBadGateway {
+		t.Errorf("status = %d, want %d", status, http.StatusBadGateway)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{baseBackoff: 10 * time.Second}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{20, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestTickWaitsForDatabase(t *testing.T) {
+	calls := 0
+	d := NewDispatcher(func() (*sql.DB, error) {
+		calls++
+		return nil, errors.New("connection refused")
+	}, time.Second, time.Second, 3, false)
+
+	d.tick(context.Background())
+	d.tick(context.Background())
+	if calls != 2 {
+		t.Errorf("pool requested %d times, want 2: every pass must retry the connection", calls)
+	}
+}
+
+// deliveryState - состояние доставки в базе
+type deliveryState struct {
+	id         int64
+	status     string
+	attempts   int
+	statusCode sql.NullInt64
+	nextIn     time.Duration
+}
+
+// setupDelivery создаёт вебхук на url и событие для него и возвращает диспетчер
+// с maxAttempts попытками
+func setupDelivery(t *testing.T, db *sql.DB, url string, maxAttempts int) *Dispatcher {
+	t.Helper()
+
+	if _, err := db.Exec("INSERT INTO webhooks (url, secret) VALUES ($1, 'secret')", url); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	_, err := db.Exec(`INSERT INTO outbox_events (event_type, aggregate_id, payload) VALUES ('song.created', 1, '{"id":1}')`)
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	return NewDispatcher(testdb.Pool(db), time.Second, 5*time.Second, maxAttempts, true)
+}
+
+// readDelivery возвращает состояние единственной доставки
+func readDelivery(t *testing.T, db *sql.DB) deliveryState {
+	t.Helper()
+
+	var state deliveryState
+	var nextIn float64
+	err := db.QueryRow(`
+		SELECT id, status, attempts, last_status_code, EXTRACT(EPOCH FROM next_attempt_at - now())
+		FROM webhook_deliveries`).Scan(&state.id, &state.status, &state.attempts, &state.statusCode, &nextIn)
+	if err != nil {
+		t.Fatalf("Failed to read delivery: %v", err)
+	}
+	state.nextIn = time.Duration(nextIn * float64(time.Second))
+	return state
+}
+
+// makeDue назначает отправку доставок на текущий момент, не дожидаясь паузы
+func makeDue(t *testing.T, db *sql.DB) {
+	t.Helper()
+	if _, err := db.Exec("UPDATE webhook_deliveries SET next_attempt_at = now()"); err != nil {
+		t.Fatalf("Failed to make deliveries due: %v", err)
+	}
+}
+
+func TestDispatcherRetriesServerErrors(t *testing.T) {
+	db := testdb.Open(t)
+	r := newReceiver(t, http.StatusInternalServerError, http.StatusOK)
+	d := setupDelivery(t, db, r.URL, 5)
+	d.baseBackoff = time.Minute
+	ctx := context.Background()
+
+	d.tick(ctx)
+	state := readDelivery(t, db)
+	if state.status != models.DeliveryPending || state.attempts != 1 || state.statusCode.Int64 != http.StatusInternalServerError {
+		t.Fatalf("after 500: status %s, attempts %d, code %v; want pending, 1, 500", state.status, state.attempts, state.statusCode)
+	}
+	if state.nextIn < 50*time.Second || state.nextIn > time.Minute {
+		t.Errorf("next attempt in %s, want about 1m", state.nextIn)
+	}
+
+	// Пока пауза не прошла, доставка не повторяется
+	d.tick(ctx)
+	if got := len(r.received()); got != 1 {
+		t.Fatalf("delivery retried before backoff: %d requests", got)
+	}
+
+	makeDue(t, db)
+	d.tick(ctx)
+	state = readDelivery(t, db)
+	if state.status != models.DeliveryDelivered || state.attempts != 2 {
+		t.Fatalf("after 200: status %s, attempts %d; want delivered, 2", state.status, state.attempts)
+	}
+	if got := testdb.Count(t, db, "SELECT COUNT(*) FROM outbox_events WHERE dispatched_at IS NULL"); got != 0 {
+		t.Errorf("%d events left undispatched", got)
+	}
+
+	requests := r.received()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if requests[0].header.Get("X-Webhook-Delivery") != strconv.FormatInt(state.id, 10) {
+		t.Errorf("X-Webhook-Delivery = %q, want %d", requests[0].header.Get("X-Webhook-Delivery"), state.id)
+	}
+	if want := Sign("secret", requests[1].body); requests[1].header.Get(SignatureHeader) != want {
+		t.Errorf("retry signature = %q, want %q", requests[1].header.Get(SignatureHeader), want)
+	}
+}
+
+func TestDispatcherDeadLettersAndRetries(t *testing.T) {
+	db := testdb.Open(t)
+	r := newReceiver(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+	d := setupDelivery(t, db, r.URL, 2)
+	ctx := context.Background()
+
+	d.tick(ctx)
+	makeDue(t, db)
+	d.tick(ctx)
+
+	state := readDelivery(t, db)
+	if state.status != models.DeliveryDead || state.attempts != 2 {
+		t.Fatalf("after %d failures: status %s, attempts %d; want dead, 2", len(r.received()), state.status, state.attempts)
+	}
+
+	// Доставка в dead-letter больше не отправляется
+	makeDue(t, db)
+	d.tick(ctx)
+	if got := len(r.received()); got != 2 {
+		t.Fatalf("dead delivery was sent again: %d requests", got)
+	}
+
+	found, err := RetryDead(ctx, db, state.id)
+	if err != nil || !found {
+		t.Fatalf("RetryDead = %v, %v; want true, nil", found, err)
+	}
+	state = readDelivery(t, db)
+	if state.status != models.DeliveryPending || state.attempts != 0 {
+		t.Fatalf("after RetryDead: status %s, attempts %d; want pending, 0", state.status, state.attempts)
+	}
+
+	d.tick(ctx)
+	state = readDelivery(t, db)
+	if state.status != models.DeliveryDelivered || state.attempts != 1 {
+		t.Fatalf("after retry: status %s, attempts %d; want delivered, 1", state.status, state.attempts)
+	}
+
+	// Вернуть в очередь можно только доставку из dead-letter
+	if found, err := RetryDead(ctx, db, state.id); err != nil || found {
+		t.Errorf("RetryDead of a delivered delivery = %v, %v; want false, nil", found, err)
+	}
+}
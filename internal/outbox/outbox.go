@@ -0,0 +1,69 @@
+package outbox
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/inanmasov/music-service/internal/db"
+)
+
+// SignatureHeader - заголовок с подписью тела запроса вебхука
+const SignatureHeader = "X-Webhook-Signature-256"
+
+// seqLockKey - ключ рекомендательной блокировки, под которой назначаются позиции событий
+const seqLockKey = 0x6f7574626f78 // "outbox"
+
+// Record записывает событие в outbox в той же транзакции, что и изменение данных.
+// Событие будет доставлено подписчикам после фиксации транзакции
+func Record(ctx context.Context, tx *sql.Tx, eventType string, aggregateID int, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO outbox_events (event_type, aggregate_id, payload) VALUES ($1, $2, $3)",
+		eventType, aggregateID, payload,
+	)
+	return err
+}
+
+// Sign возвращает подпись тела запроса вебхука в виде "sha256=<hex HMAC-SHA256>"
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// AssignSeq назначает позиции в журнале (seq) зафиксированным событиям, у которых
+// их ещё нет. Позиции назначаются под рекомендательной блокировкой, которая держится
+// до фиксации, поэтому следующие позиции становятся видимы только после предыдущих,
+// и читатель, идущий по seq, не пропускает события. Если позиции уже назначает
+// другой процесс, AssignSeq ничего не делает
+func AssignSeq(ctx context.Context, pool *sql.DB) error {
+	opts := db.TxOptions{Isolation: sql.LevelReadCommitted}
+	return db.RunInTx(ctx, pool, opts, func(tx *sql.Tx) error {
+		var locked bool
+		if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", seqLockKey).Scan(&locked); err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		// Запрос после блокировки видит позиции, назначенные предыдущим владельцем
+		_, err := tx.ExecContext(ctx, `
+			UPDATE outbox_events
+			SET seq = numbered.seq
+			FROM (
+				SELECT id, nextval('outbox_events_seq') AS seq
+				FROM (SELECT id FROM outbox_events WHERE seq IS NULL ORDER BY id) unsequenced
+			) numbered
+			WHERE outbox_events.id = numbered.id`)
+		return err
+	})
+}
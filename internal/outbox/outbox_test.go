@@ -0,0 +1,123 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/inanmasov/music-service/internal/testdb"
+)
+
+// recordEvent записывает событие song.created в транзакции tx и возвращает его id
+func recordEvent(t *testing.T, tx *sql.Tx, aggregateID int) int64 {
+	t.Helper()
+
+	if err := Record(context.Background(), tx, "song.created", aggregateID, map[string]int{"id": aggregateID}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	var id int64
+	if err := tx.QueryRow("SELECT MAX(id) FROM outbox_events").Scan(&id); err != nil {
+		t.Fatalf("Failed to read event id: %v", err)
+	}
+	return id
+}
+
+// eventSeq возвращает позицию события id или false, если она ещё не назначена
+func eventSeq(t *testing.T, db *sql.DB, id int64) (int64, bool) {
+	t.Helper()
+
+	var seq sql.NullInt64
+	if err := db.QueryRow("SELECT seq FROM outbox_events WHERE id = $1", id).Scan(&seq); err != nil {
+		t.Fatalf("Failed to read event %d position: %v", id, err)
+	}
+	return seq.Int64, seq.Valid
+}
+
+func TestAssignSeqFollowsCommitOrder(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+
+	// Долгая транзакция получает id события раньше, а фиксируется позже
+	slow, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	defer slow.Rollback()
+	slowID := recordEvent(t, slow, 1)
+
+	fast, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	fastID := recordEvent(t, fast, 2)
+	if err := fast.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if fastID <= slowID {
+		t.Fatalf("event ids %d and %d are not in the order of Record calls", slowID, fastID)
+	}
+
+	if err := AssignSeq(ctx, db); err != nil {
+		t.Fatalf("AssignSeq: %v", err)
+	}
+	fastSeq, ok := eventSeq(t, db, fastID)
+	if !ok {
+		t.Fatal("committed event got no position")
+	}
+
+	if err := slow.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if _, ok := eventSeq(t, db, slowID); ok {
+		t.Fatal("event got a position before AssignSeq")
+	}
+	if err := AssignSeq(ctx, db); err != nil {
+		t.Fatalf("AssignSeq: %v", err)
+	}
+	slowSeq, ok := eventSeq(t, db, slowID)
+	if !ok {
+		t.Fatal("event committed later got no position")
+	}
+	if slowSeq <= fastSeq {
+		t.Errorf("event committed later has position %d, not after %d: a reader at %d would skip it", slowSeq, fastSeq, fastSeq)
+	}
+}
+
+func TestAssignSeqSkipsWhileLocked(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	id := recordEvent(t, tx, 1)
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	// Пока позиции назначает другой процесс, AssignSeq не ждёт его и ничего не меняет
+	holder, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	defer holder.Rollback()
+	if _, err := holder.Exec("SELECT pg_advisory_xact_lock($1)", seqLockKey); err != nil {
+		t.Fatalf("Failed to take the lock: %v", err)
+	}
+
+	if err := AssignSeq(ctx, db); err != nil {
+		t.Fatalf("AssignSeq: %v", err)
+	}
+	if _, ok := eventSeq(t, db, id); ok {
+		t.Fatal("AssignSeq assigned a position while another process held the lock")
+	}
+
+	holder.Rollback()
+	if err := AssignSeq(ctx, db); err != nil {
+		t.Fatalf("AssignSeq: %v", err)
+	}
+	if _, ok := eventSeq(t, db, id); !ok {
+		t.Fatal("event got no position after the lock was released")
+	}
+}
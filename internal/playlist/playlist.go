@@ -0,0 +1,410 @@
+package playlist
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Поддерживаемые форматы плейлистов
+const (
+	FormatM3U8 = "m3u8"
+	FormatXSPF = "xspf"
+	FormatJSPF = "jspf"
+)
+
+// xspfNamespace - пространство имён XSPF версии 1
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+// maxLineSize - максимальная длина строки M3U8
+const maxLineSize = 64 * 1024
+
+// MissingLocation - ссылка, которая записывается в M3U8 для трека без ссылки:
+// формат требует строку с адресом после #EXTINF. При чтении M3U8 она снова
+// становится пустой ссылкой
+const MissingLocation = "about:blank"
+
+// contentTypes - тип содержимого и расширение файла для каждого формата
+var contentTypes = map[string][2]string{
+	FormatM3U8: {"audio/x-mpegurl; charset=utf-8", "m3u8"},
+	FormatXSPF: {"application/xspf+xml; charset=utf-8", "xspf"},
+	FormatJSPF: {"application/jspf+json; charset=utf-8", "jspf"},
+}
+
+// Track - трек плейлиста: исполнитель, название и ссылка
+type Track struct {
+	Title    string
+	Creator  string
+	Location string
+}
+
+// Playlist - плейлист с заголовком, автором и треками
+type Playlist struct {
+	Title   string
+	Creator string
+	Tracks  []Track
+}
+
+// Writer последовательно записывает треки в плейлист
+type Writer interface {
+	// Write записывает один трек
+	Write(track Track) error
+	// Close дописывает окончание плейлиста. Нижележащий io.Writer не закрывается
+	Close() error
+}
+
+// ContentType возвращает тип содержимого и расширение файла для формата
+func ContentType(format string) (string, string, error) {
+	types, ok := contentTypes[format]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported playlist format %q", format)
+	}
+	return types[0], types[1], nil
+}
+
+// ParseFormat определяет формат плейлиста по параметру запроса или типу содержимого
+func ParseFormat(format, contentType string) (string, error) {
+	format = strings.ToLower(format)
+	if _, ok := contentTypes[format]; ok {
+		return format, nil
+	}
+	if format == "m3u" {
+		return FormatM3U8, nil
+	}
+	if format != "" {
+		return "", fmt.Errorf("unsupported playlist format %q", format)
+	}
+
+	switch contentType {
+	case "audio/x-mpegurl", "audio/mpegurl", "application/vnd.apple.mpegurl", "application/x-mpegurl":
+		return FormatM3U8, nil
+	case "application/xspf+xml":
+		return FormatXSPF, nil
+	case "application/jspf+json":
+		return FormatJSPF, nil
+	}
+	return "", fmt.Errorf("cannot detect playlist format from content type %q", contentType)
+}
+
+// NewWriter создаёт Writer плейлиста с заголовком title и автором creator
+func NewWriter(w io.Writer, format, title, creator string) (Writer, error) {
+	switch format {
+	case FormatM3U8:
+		return &m3u8Writer{w: w, title: title}, nil
+	case FormatXSPF:
+		return &xspfWriter{encoder: xml.NewEncoder(w), w: w, title: title, creator: creator}, nil
+	case FormatJSPF:
+		return &jspfWriter{w: w, title: title, creator: creator}, nil
+	default:
+		return nil, fmt.Errorf("unsupported playlist format %q", format)
+	}
+}
+
+// Decode читает плейлист в указанном формате
+func Decode(r io.Reader, format string) (Playlist, error) {
+	switch format {
+	case FormatM3U8:
+		return decodeM3U8(r)
+	case FormatXSPF:
+		return decodeXSPF(r)
+	case FormatJSPF:
+		return decodeJSPF(r)
+	default:
+		return Playlist{}, fmt.Errorf("unsupported playlist format %q", format)
+	}
+}
+
+// m3u8Writer пишет расширенный M3U в UTF-8. M3U не допускает треков без
+// ссылки, поэтому для них вместо ссылки пишется MissingLocation
+type m3u8Writer struct {
+	w             io.Writer
+	title         string
+	headerWritten bool
+}
+
+func (m *m3u8Writer) writeHeader() error {
+	if m.headerWritten {
+		return nil
+	}
+	m.headerWritten = true
+
+	header := "#EXTM3U\n"
+	if m.title != "" {
+		header += "#PLAYLIST:" + oneLine(m.title) + "\n"
+	}
+	_, err := io.WriteString(m.w, header)
+	return err
+}
+
+func (m *m3u8Writer) Write(track Track) error {
+	if err := m.writeHeader(); err != nil {
+		return err
+	}
+
+	location := oneLine(track.Location)
+	if location == "" {
+		location = MissingLocation
+	}
+	name := oneLine(track.Title)
+	if track.Creator != "" {
+		name = oneLine(track.Creator) + " - " + name
+	}
+	_, err := fmt.Fprintf(m.w, "#EXTINF:-1,%s\n%s\n", name, location)
+	return err
+}
+
+func (m *m3u8Writer) Close() error {
+	return m.writeHeader()
+}
+
+// decodeM3U8 читает M3U/M3U8. Исполнитель и название берутся из #EXTINF
+// в виде "Исполнитель - Название", ссылка MissingLocation читается как пустая
+func decodeM3U8(r io.Reader) (Playlist, error) {
+	var playlist Playlist
+	var pending *Track
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case line == "" || line == "#EXTM3U":
+		case strings.HasPrefix(line, "#PLAYLIST:"):
+			playlist.Title = strings.TrimSpace(strings.TrimPrefix(line, "#PLAYLIST:"))
+		case strings.HasPrefix(line, "#EXTINF:"):
+			track := Track{}
+			if _, name, ok := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ","); ok {
+				track.Creator, track.Title = splitName(name)
+			}
+			pending = &track
+		case strings.HasPrefix(line, "#"):
+			// Прочие директивы и комментарии не поддерживаются
+		default:
+			track := Track{}
+			if pending != nil {
+				track = *pending
+				pending = nil
+			}
+			if line != MissingLocation {
+				track.Location = line
+			}
+			playlist.Tracks = append(playlist.Tracks, track)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Playlist{}, err
+	}
+
+	return playlist, nil
+}
+
+// splitName разделяет "Исполнитель - Название" на исполнителя и название
+func splitName(name string) (string, string) {
+	creator, title, ok := strings.Cut(name, " - ")
+	if !ok {
+		return "", strings.TrimSpace(name)
+	}
+	return strings.TrimSpace(creator), strings.TrimSpace(title)
+}
+
+// oneLine заменяет переводы строк пробелами, чтобы значение не разорвало строку M3U
+func oneLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// xspfTrack - трек в формате XSPF
+type xspfTrack struct {
+	Location []string `xml:"location,omitempty"`
+	Title    string   `xml:"title,omitempty"`
+	Creator  string   `xml:"creator,omitempty"`
+}
+
+// xspfPlaylist - плейлист в формате XSPF
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Title   string      `xml:"title"`
+	Creator string      `xml:"creator"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+// xspfWriter пишет XSPF потоково: открывающие элементы, затем треки по одному
+type xspfWriter struct {
+	encoder       *xml.Encoder
+	w             io.Writer
+	title         string
+	creator       string
+	headerWritten bool
+}
+
+func (x *xspfWriter) writeHeader() error {
+	if x.headerWritten {
+		return nil
+	}
+	x.headerWritten = true
+
+	if _, err := io.WriteString(x.w, xml.Header); err != nil {
+		return err
+	}
+	tokens := []xml.Token{
+		xml.StartElement{Name: xml.Name{Local: "playlist"}, Attr: []xml.Attr{
+			{Name: xml.Name{Local: "version"}, Value: "1"},
+			{Name: xml.Name{Local: "xmlns"}, Value: xspfNamespace},
+		}},
+	}
+	for _, element := range [][2]string{{"title", x.title}, {"creator", x.creator}} {
+		if element[1] == "" {
+			continue
+		}
+		name := xml.Name{Local: element[0]}
+		tokens = append(tokens, xml.StartElement{Name: name}, xml.CharData(element[1]), xml.EndElement{Name: name})
+	}
+	tokens = append(tokens, xml.StartElement{Name: xml.Name{Local: "trackList"}})
+
+	for _, token := range tokens {
+		if err := x.encoder.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *xspfWriter) Write(track Track) error {
+	if err := x.writeHeader(); err != nil {
+		return err
+	}
+
+	element := xspfTrack{Title: track.Title, Creator: track.Creator}
+	if track.Location != "" {
+		element.Location = []string{track.Location}
+	}
+	return x.encoder.EncodeElement(element, xml.StartElement{Name: xml.Name{Local: "track"}})
+}
+
+func (x *xspfWriter) Close() error {
+	if err := x.writeHeader(); err != nil {
+		return err
+	}
+	for _, name := range []string{"trackList", "playlist"} {
+		if err := x.encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+	return x.encoder.Flush()
+}
+
+// decodeXSPF читает XSPF. Из нескольких location используется первая
+func decodeXSPF(r io.Reader) (Playlist, error) {
+	var document xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return Playlist{}, err
+	}
+
+	playlist := Playlist{Title: document.Title, Creator: document.Creator}
+	for _, element := range document.Tracks {
+		track := Track{Title: strings.TrimSpace(element.Title), Creator: strings.TrimSpace(element.Creator)}
+		if len(element.Location) > 0 {
+			track.Location = strings.TrimSpace(element.Location[0])
+		}
+		playlist.Tracks = append(playlist.Tracks, track)
+	}
+	return playlist, nil
+}
+
+// jspfTrack - трек в формате JSPF
+type jspfTrack struct {
+	Location []string `json:"location,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Creator  string   `json:"creator,omitempty"`
+}
+
+// jspfDocument - плейлист в формате JSPF
+type jspfDocument struct {
+	Playlist struct {
+		Title   string      `json:"title,omitempty"`
+		Creator string      `json:"creator,omitempty"`
+		Track   []jspfTrack `json:"track"`
+	} `json:"playlist"`
+}
+
+// jspfWriter пишет JSPF потоково, не накапливая треки в памяти
+type jspfWriter struct {
+	w       io.Writer
+	title   string
+	creator string
+	count   int
+}
+
+func (j *jspfWriter) Write(track Track) error {
+	if j.count == 0 {
+		header := map[string]string{}
+		if j.title != "" {
+			header["title"] = j.title
+		}
+		if j.creator != "" {
+			header["creator"] = j.creator
+		}
+		data, err := json.Marshal(header)
+		if err != nil {
+			return err
+		}
+		// Убираем закрывающую скобку, чтобы дописать массив треков
+		prefix := `{"playlist":` + strings.TrimSuffix(string(data), "}")
+		if len(header) > 0 {
+			prefix += ","
+		}
+		if _, err := io.WriteString(j.w, prefix+`"track":[`); err != nil {
+			return err
+		}
+	} else if _, err := io.WriteString(j.w, ","); err != nil {
+		return err
+	}
+	j.count++
+
+	element := jspfTrack{Title: track.Title, Creator: track.Creator}
+	if track.Location != "" {
+		element.Location = []string{track.Location}
+	}
+	data, err := json.Marshal(element)
+	if err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jspfWriter) Close() error {
+	if j.count == 0 {
+		var document jspfDocument
+		document.Playlist.Title = j.title
+		document.Playlist.Creator = j.creator
+		document.Playlist.Track = []jspfTrack{}
+		return json.NewEncoder(j.w).Encode(document)
+	}
+	_, err := io.WriteString(j.w, "]}}\n")
+	return err
+}
+
+// decodeJSPF читает JSPF. Из нескольких location используется первая
+func decodeJSPF(r io.Reader) (Playlist, error) {
+	var document jspfDocument
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return Playlist{}, err
+	}
+	if document.Playlist.Track == nil {
+		return Playlist{}, errors.New(`JSPF document must contain "playlist.track"`)
+	}
+
+	playlist := Playlist{Title: document.Playlist.Title, Creator: document.Playlist.Creator}
+	for _, element := range document.Playlist.Track {
+		track := Track{Title: strings.TrimSpace(element.Title), Creator: strings.TrimSpace(element.Creator)}
+		if len(element.Location) > 0 {
+			track.Location = strings.TrimSpace(element.Location[0])
+		}
+		playlist.Tracks = append(playlist.Tracks, track)
+	}
+	return playlist, nil
+}
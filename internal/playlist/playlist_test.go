@@ -0,0 +1,198 @@
+package playlist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// encode записывает плейлист в формате format через Writer
+func encode(t *testing.T, format string, list Playlist) string {
+	t.Helper()
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, format, list.Title, list.Creator)
+	if err != nil {
+		t.Fatalf("NewWriter(%s): %v", format, err)
+	}
+	for _, track := range list.Tracks {
+		if err := writer.Write(track); err != nil {
+			t.Fatalf("Write(%s): %v", format, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close(%s): %v", format, err)
+	}
+	return buf.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	tracks := []Track{
+		{Title: "Uprising", Creator: "Muse", Location: "https://example.com/uprising"},
+		{Title: "Без ссылки", Creator: "Кино"},
+		{Title: "No creator", Location: "https://example.com/no-creator"},
+		{Title: `Quotes "&" <tags>`, Creator: "AC/DC", Location: "https://example.com/a?b=1&c=2"},
+	}
+	tests := []struct {
+		name   string
+		format string
+		list   Playlist
+		want   Playlist
+	}{
+		{
+			name:   "m3u8",
+			format: FormatM3U8,
+			list:   Playlist{Title: "Mix", Creator: "me", Tracks: tracks},
+			// M3U8 не хранит автора плейлиста
+			want: Playlist{Title: "Mix", Tracks: tracks},
+		},
+		{
+			name:   "m3u8 empty",
+			format: FormatM3U8,
+			list:   Playlist{},
+			want:   Playlist{},
+		},
+		{
+			name:   "m3u8 line breaks",
+			format: FormatM3U8,
+			list:   Playlist{Title: "Two\nlines", Tracks: []Track{{Title: "A\nB", Creator: "C\r\nD", Location: "https://example.com/x"}}},
+			want:   Playlist{Title: "Two lines", Tracks: []Track{{Title: "A B", Creator: "C D", Location: "https://example.com/x"}}},
+		},
+		{
+			name:   "xspf",
+			format: FormatXSPF,
+			list:   Playlist{Title: "Mix", Creator: "me", Tracks: tracks},
+			want:   Playlist{Title: "Mix", Creator: "me", Tracks: tracks},
+		},
+		{
+			name:   "xspf empty",
+			format: FormatXSPF,
+			list:   Playlist{},
+			want:   Playlist{},
+		},
+		{
+			name:   "jspf",
+			format: FormatJSPF,
+			list:   Playlist{Title: "Mix", Creator: "me", Tracks: tracks},
+			want:   Playlist{Title: "Mix", Creator: "me", Tracks: tracks},
+		},
+		{
+			name:   "jspf without title",
+			format: FormatJSPF,
+			list:   Playlist{Tracks: tracks[:1]},
+			want:   Playlist{Tracks: tracks[:1]},
+		},
+		{
+			name:   "jspf empty",
+			format: FormatJSPF,
+			list:   Playlist{Title: "Mix"},
+			want:   Playlist{Title: "Mix"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := encode(t, tt.format, tt.list)
+			got, err := Decode(strings.NewReader(data), tt.format)
+			if err != nil {
+				t.Fatalf("Decode: %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v\n%s", got, tt.want, data)
+			}
+		})
+	}
+}
+
+func TestM3U8MissingLocation(t *testing.T) {
+	data := encode(t, FormatM3U8, Playlist{Tracks: []Track{{Title: "Song", Creator: "Group"}}})
+	want := "#EXTM3U\n#EXTINF:-1,Group - Song\n" + MissingLocation + "\n"
+	if data != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name, format, data string
+		want               Playlist
+		wantErr            bool
+	}{
+		{
+			name:   "m3u without EXTINF",
+			format: FormatM3U8,
+			data:   "\ufeffhttps://example.com/a\r\n\r\n# comment\nhttps://example.com/b\n",
+			want:   Playlist{Tracks: []Track{{Location: "https://example.com/a"}, {Location: "https://example.com/b"}}},
+		},
+		{
+			name:   "m3u8 unknown directives",
+			format: FormatM3U8,
+			data:   "#EXTM3U\n#EXTINF:215 tvg-id=\"x\",Muse - Uprising\n#EXTVLCOPT:network-caching=1000\nhttps://example.com/u\n",
+			want:   Playlist{Tracks: []Track{{Title: "Uprising", Creator: "Muse", Location: "https://example.com/u"}}},
+		},
+		{
+			name:   "xspf several locations",
+			format: FormatXSPF,
+			data: `<playlist version="1" xmlns="http://xspf.org/ns/0/"><trackList><track>
+<location> https://example.com/1 </location><location>https://example.com/2</location>
+<title> Uprising </title></track></trackList></playlist>`,
+			want: Playlist{Tracks: []Track{{Title: "Uprising", Location: "https://example.com/1"}}},
+		},
+		{
+			name:    "xspf malformed",
+			format:  FormatXSPF,
+			data:    `<playlist><trackList>`,
+			wantErr: true,
+		},
+		{
+			name:   "jspf several locations",
+			format: FormatJSPF,
+			data:   `{"playlist":{"track":[{"title":"Uprising","creator":"Muse","location":["https://example.com/1","https://example.com/2"]}]}}`,
+			want:   Playlist{Tracks: []Track{{Title: "Uprising", Creator: "Muse", Location: "https://example.com/1"}}},
+		},
+		{
+			name:    "jspf without tracks",
+			format:  FormatJSPF,
+			data:    `{"playlist":{"title":"Mix"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported format",
+			format:  "pls",
+			data:    "[playlist]",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(strings.NewReader(tt.data), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		format, contentType, want string
+		wantErr                   bool
+	}{
+		{format: "M3U8", want: FormatM3U8},
+		{format: "m3u", want: FormatM3U8},
+		{format: "xspf", contentType: "application/jspf+json", want: FormatXSPF},
+		{contentType: "application/vnd.apple.mpegurl", want: FormatM3U8},
+		{contentType: "application/xspf+xml", want: FormatXSPF},
+		{contentType: "application/jspf+json", want: FormatJSPF},
+		{format: "pls", wantErr: true},
+		{contentType: "text/plain", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.format, tt.contentType)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q, %q) = %q, %v, want %q, error %v", tt.format, tt.contentType, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
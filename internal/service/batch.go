@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+// Виды операций пакета
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+const (
+	// MaxBatchSize - наибольшее число операций в пакете
+	MaxBatchSize = 1000
+	// MaxBulkSongs - наибольшее число песен, которые меняет или удаляет одна
+	// массовая операция по фильтру
+	MaxBulkSongs = 1000
+)
+
+// errDryRun прерывает транзакцию пробной массовой операции, чтобы её изменения откатились
+var errDryRun = errors.New("dry run")
+
+// BatchOp - операция пакета
+type BatchOp struct {
+	// Kind - BatchCreate, BatchUpdate или BatchDelete
+	Kind string
+	// ID - песня, которую меняет или удаляет операция
+	ID int
+	// Input - данные песни для BatchCreate и BatchUpdate
+	Input models.SongInput
+	// Precondition - ожидаемая версия песни для BatchUpdate и BatchDelete
+	Precondition Precondition
+	// Err - ошибка разбора операции. Такая операция не выполняется и завершается
+	// этой ошибкой, в атомарном пакете - вместе со всем пакетом
+	Err error
+}
+
+// BatchResult - результат операции пакета
+type BatchResult struct {
+	// Song - добавленная, изменённая или удалённая песня
+	Song Song
+	// Created - BatchCreate добавила песню, а не вернула уже существующую
+	Created bool
+	Err     error
+}
+
+// RunBatch выполняет операции пакета по порядку и возвращает их результаты в том
+// же порядке. Атомарный пакет выполняется в одной транзакции: если операция
+// завершилась ошибкой, изменения всех операций откатываются, а остальные операции
+// завершаются ErrBatchAborted. Иначе каждая операция выполняется в своей транзакции
+// независимо от других. Ошибка возвращается, только если пакет не удалось выполнить целиком
+func (l *Library) RunBatch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	if len(ops) == 0 {
+		return nil, newError(ErrValidation, "Batch must contain at least one operation")
+	}
+	if len(ops) > MaxBatchSize {
+		return nil, newError(ErrValidation, "Batch must contain at most "+strconv.Itoa(MaxBatchSize)+" operations")
+	}
+
+	log := logger.FromContext(ctx)
+	results := make([]BatchResult, len(ops))
+
+	if !atomic {
+		for i, op := range ops {
+			if err := checkBatchOp(op); err != nil {
+				results[i].Err = err
+				continue
+			}
+			err := l.inTx(ctx, func(tx *sql.Tx) error {
+				var err error
+				results[i], err = runBatchOp(ctx, tx, op)
+				return err
+			})
+			results[i].Err = err
+		}
+		l.invalidateBatch(ctx, ops, results)
+		log.Infof("Batch of %d operations completed", len(ops))
+		return results, nil
+	}
+
+	// Некорректная операция отменяет атомарный пакет без обращения к базе
+	failed := -1
+	var err error
+	for i, op := range ops {
+		if err = checkBatchOp(op); err != nil {
+			failed = i
+			break
+		}
+	}
+	if failed >= 0 {
+		return abortBatch(ctx, results, failed, err), nil
+	}
+
+	// Транзакция может повторяться, поэтому номер неудачной операции определяет последняя попытка
+	err = l.inTx(ctx, func(tx *sql.Tx) error {
+		failed = -1
+		for i, op := range ops {
+			var err error
+			results[i], err = runBatchOp(ctx, tx, op)
+			if err != nil {
+				failed = i
+				return err
+			}
+		}
+		return nil
+	})
+	switch {
+	case failed >= 0:
+		return abortBatch(ctx, results, failed, err), nil
+	case err != nil:
+		return nil, err
+	}
+
+	l.invalidateBatch(ctx, ops, results)
+	log.Infof("Batch of %d operations committed", len(ops))
+	return results, nil
+}
+
+// invalidateBatch удаляет из кеша песни, изменённые и удалённые успешными операциями пакета
+func (l *Library) invalidateBatch(ctx context.Context, ops []BatchOp, results []BatchResult) {
+	var ids []int
+	changed := false
+	for i, op := range ops {
+		if results[i].Err != nil {
+			continue
+		}
+		changed = true
+		if op.Kind != BatchCreate {
+			ids = append(ids, op.ID)
+		}
+	}
+	if changed {
+		l.invalidate(ctx, ids...)
+	}
+}
+
+// abortBatch завершает все операции отменённого атомарного пакета ErrBatchAborted,
+// а операцию failed - её ошибкой err
+func abortBatch(ctx context.Context, results []BatchResult, failed int, err error) []BatchResult {
+	logger.FromContext(ctx).Infof("Batch of %d operations rolled back: operation %d failed: %v", len(results), failed, err)
+	aborted := &Error{Kind: ErrBatchAborted, Message: fmt.Sprintf("Not applied because operation %d failed", failed)}
+	for i := range results {
+		results[i] = BatchResult{Err: aborted}
+	}
+	results[failed].Err = err
+	return results
+}
+
+// checkBatchOp проверяет операцию пакета до обращения к базе
+func checkBatchOp(op BatchOp) error {
+	if op.Err != nil {
+		return op.Err
+	}
+
+	switch op.Kind {
+	case BatchCreate:
+		if errs := validation.SongInput(op.Input); len(errs) > 0 {
+			return Invalid("Invalid song data", errs)
+		}
+	case BatchUpdate, BatchDelete:
+	default:
+		return newError(ErrValidation, "Unknown operation "+strconv.Quote(op.Kind))
+	}
+	return nil
+}
+
+// runBatchOp выполняет проверенную checkBatchOp операцию пакета в транзакции tx
+func runBatchOp(ctx context.Context, tx *sql.Tx, op BatchOp) (BatchResult, error) {
+	switch op.Kind {
+	case BatchCreate:
+		song, created, err := addSong(ctx, tx, op.Input)
+		return BatchResult{Song: song, Created: created}, err
+	case BatchUpdate:
+		current, err := lockSong(ctx, tx, op.ID, op.Precondition)
+		if err != nil {
+			return BatchResult{}, err
+		}
+		song, err := modifySong(ctx, tx, current, func(models.Song) (models.SongInput, error) {
+			return op.Input, nil
+		})
+		return BatchResult{Song: song}, err
+	case BatchDelete:
+		current, err := lockSong(ctx, tx, op.ID, op.Precondition)
+		if err != nil {
+			return BatchResult{}, err
+		}
+		return BatchResult{Song: current}, deleteSong(ctx, tx, current)
+	}
+	return BatchResult{}, nil
+}
+
+// BulkModifySongs изменяет все песни, подходящие под filter, функцией change,
+// как ModifySong, в одной транзакции и возвращает их ID. Ошибка любой песни
+// отменяет изменения всех. При dryRun изменения проверяются и откатываются.
+// Пустой filter не допускается, подходящих песен должно быть не больше MaxBulkSongs
+func (l *Library) BulkModifySongs(ctx context.Context, filter SongFilter, dryRun bool, change func(current models.Song) (models.SongInput, error)) ([]int, error) {
+	ids, err := l.bulkChange(ctx, filter, dryRun, func(tx *sql.Tx, current Song) error {
+		_, err := modifySong(ctx, tx, current, change)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !dryRun {
+		logger.FromContext(ctx).Infof("%d songs updated by filter", len(ids))
+	}
+	return ids, nil
+}
+
+// BulkDeleteSongs удаляет все песни, подходящие под filter, в одной транзакции
+// и возвращает их ID. При dryRun песни только определяются. Пустой filter
+// не допускается, подходящих песен должно быть не больше MaxBulkSongs
+func (l *Library) BulkDeleteSongs(ctx context.Context, filter SongFilter, dryRun bool) ([]int, error) {
+	ids, err := l.bulkChange(ctx, filter, dryRun, func(tx *sql.Tx, current Song) error {
+		return deleteSong(ctx, tx, current)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !dryRun {
+		logger.FromContext(ctx).Infof("%d songs deleted by filter", len(ids))
+	}
+	return ids, nil
+}
+
+// bulkChange блокирует песни, подходящие под filter, и применяет к каждой change
+// в одной транзакции. Ошибка change возвращается как есть. При dryRun транзакция
+// откатывается после всех изменений
+func (l *Library) bulkChange(ctx context.Context, filter SongFilter, dryRun bool, change func(tx *sql.Tx, current Song) error) ([]int, error) {
+	if filter.empty() {
+		return nil, newError(ErrValidation, "At least one filter is required")
+	}
+	if err := filter.check(); err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	err := l.inTx(ctx, func(tx *sql.Tx) error {
+		songs, err := lockFiltered(ctx, tx, filter)
+		if err != nil {
+			return err
+		}
+
+		ids = make([]int, len(songs))
+		for i, song := range songs {
+			if err := change(tx, song); err != nil {
+				return err
+			}
+			ids[i] = song.ID
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && err != errDryRun {
+		return nil, err
+	}
+	if !dryRun && len(ids) > 0 {
+		l.invalidate(ctx, ids...)
+	}
+	return ids, nil
+}
+
+// lockFiltered загружает песни, подходящие под filter, и блокирует их до конца
+// транзакции. Если их больше MaxBulkSongs, возвращает ErrValidation
+func lockFiltered(ctx context.Context, tx *sql.Tx, filter SongFilter) ([]Song, error) {
+	conditions, _, args := filter.build(ctx)
+	// Строки блокируются по возрастанию ID, чтобы массовые операции не ждали друг друга по кругу
+	query := selectSongsQuery + conditions + " ORDER BY songs.id LIMIT " + strconv.Itoa(MaxBulkSongs+1) + " FOR UPDATE OF songs"
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve songs", err)
+	}
+	defer rows.Close()
+
+	songs := []Song{}
+	for rows.Next() {
+		song, err := scanSong(rows)
+		if err != nil {
+			return nil, internalError(ctx, "Failed to scan song", err)
+		}
+		songs = append(songs, song)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(ctx, "Failed to retrieve songs", err)
+	}
+
+	if len(songs) > MaxBulkSongs {
+		return nil, newError(ErrValidation, "Filter matches more than "+strconv.Itoa(MaxBulkSongs)+" songs, narrow it down")
+	}
+	return songs, nil
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/testdb"
+)
+
+// songText возвращает текст и версию песни id из базы данных
+func songText(t *testing.T, db *sql.DB, id int) (string, int) {
+	t.Helper()
+	var text sql.NullString
+	var version int
+	if err := db.QueryRow("SELECT text, version FROM songs WHERE id = $1", id).Scan(&text, &version); err != nil {
+		t.Fatalf("Failed to read song %d: %v", id, err)
+	}
+	return text.String, version
+}
+
+// clearEvents удаляет записанные события, чтобы проверить события одной операции
+func clearEvents(t *testing.T, db *sql.DB) {
+	t.Helper()
+	if _, err := db.Exec("DELETE FROM outbox_events"); err != nil {
+		t.Fatalf("Failed to clear events: %v", err)
+	}
+}
+
+// batchOps возвращает пакет: изменение текста песни id, добавление новой песни
+// и удаление песни missingID
+func batchOps(id, missingID int) []BatchOp {
+	return []BatchOp{
+		{Kind: BatchUpdate, ID: id, Input: models.SongInput{Group: "Muse", Song: "Uprising", Text: "changed"}},
+		{Kind: BatchCreate, Input: models.SongInput{Group: "Muse", Song: "Starlight"}},
+		{Kind: BatchDelete, ID: missingID},
+	}
+}
+
+func TestAbortBatch(t *testing.T) {
+	cause := newError(ErrNotFound, "Song not found")
+	results := []BatchResult{{Song: Song{GroupID: 1}, Created: true}, {}, {}}
+
+	results = abortBatch(context.Background(), results, 1, cause)
+
+	for i, result := range results {
+		if i == 1 {
+			if result.Err != cause {
+				t.Errorf("failed operation error = %v, want its own error", result.Err)
+			}
+			continue
+		}
+		var serviceErr *Error
+		if !errors.Is(result.Err, ErrBatchAborted) || !errors.As(result.Err, &serviceErr) {
+			t.Errorf("operation %d error = %v, want ErrBatchAborted", i, result.Err)
+		} else if serviceErr.Message != "Not applied because operation 1 failed" {
+			t.Errorf("operation %d message = %q", i, serviceErr.Message)
+		}
+		if result.Created || result.Song.GroupID != 0 {
+			t.Errorf("operation %d keeps the result %+v of the rolled back transaction", i, result)
+		}
+	}
+}
+
+func TestRunBatchRejectsWithoutDatabase(t *testing.T) {
+	l := NewLibrary(func() (*sql.DB, error) {
+		t.Fatal("batch must be rejected before connecting to the database")
+		return nil, nil
+	}, nil)
+	ctx := context.Background()
+
+	for _, ops := range [][]BatchOp{nil, make([]BatchOp, MaxBatchSize+1)} {
+		if _, err := l.RunBatch(ctx, ops, true); !errors.Is(err, ErrValidation) {
+			t.Errorf("%d operations: error = %v, want a validation error", len(ops), err)
+		}
+	}
+
+	// Некорректная операция отменяет атомарный пакет до обращения к базе
+	parseErr := Invalid("Invalid operation", nil)
+	ops := []BatchOp{
+		{Kind: BatchDelete, ID: 1},
+		{Kind: "rename", ID: 2},
+		{Kind: BatchCreate, Err: parseErr},
+	}
+	results, err := l.RunBatch(ctx, ops, true)
+	if err != nil {
+		t.Fatalf("RunBatch: %v", err)
+	}
+	if !errors.Is(results[0].Err, ErrBatchAborted) || !errors.Is(results[1].Err, ErrValidation) ||
+		!errors.Is(results[2].Err, ErrBatchAborted) {
+		t.Errorf("results %v, want the unknown operation to fail and the others aborted", results)
+	}
+}
+
+func TestRunBatchAtomicRollsBack(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	l := NewLibrary(testdb.Pool(db), nil)
+
+	song := createSongs(t, l, "Muse", "Uprising")[0]
+	clearEvents(t, db)
+
+	results, err := l.RunBatch(ctx, batchOps(song.ID, song.ID+100), true)
+	if err != nil {
+		t.Fatalf("RunBatch: %v", err)
+	}
+	if !errors.Is(results[0].Err, ErrBatchAborted) || !errors.Is(results[1].Err, ErrBatchAborted) ||
+		!errors.Is(results[2].Err, ErrNotFound) {
+		t.Errorf("results %v, want two aborted operations and not found", results)
+	}
+
+	if text, version := songText(t, db, song.ID); text != "" || version != song.Version {
+		t.Errorf("song changed to text %q, version %d by a rolled back batch", text, version)
+	}
+	if n := testdb.Count(t, db, "SELECT COUNT(*) FROM songs"); n != 1 {
+		t.Errorf("%d songs, want only the original song", n)
+	}
+	if types := eventTypes(t, db); len(types) != 0 {
+		t.Errorf("events %v recorded by a rolled back batch", types)
+	}
+}
+
+func TestRunBatchAtomicCommits(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	l := NewLibrary(testdb.Pool(db), nil)
+
+	songs := createSongs(t, l, "Muse", "Uprising", "Madness")
+	clearEvents(t, db)
+
+	results, err := l.RunBatch(ctx, batchOps(songs[0].ID, songs[1].ID), true)
+	if err != nil {
+		t.Fatalf("RunBatch: %v", err)
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			t.Errorf("operation %d: %v", i, result.Err)
+		}
+	}
+	if !results[1].Created {
+		t.Error("create operation did not report a new song")
+	}
+
+	if text, _ := songText(t, db, songs[0].ID); text != "changed" {
+		t.Errorf("song text %q, want changed", text)
+	}
+	if n := testdb.Count(t, db, "SELECT COUNT(*) FROM songs WHERE id = $1", songs[1].ID); n != 0 {
+		t.Error("deleted song is still stored")
+	}
+	want := []string{models.EventSongUpdated, models.EventSongCreated, models.EventSongDeleted}
+	if types := eventTypes(t, db); !slices.Equal(types, want) {
+		t.Errorf("events %v, want %v", types, want)
+	}
+}
+
+func TestRunBatchBestEffort(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	l := NewLibrary(testdb.Pool(db), nil)
+
+	song := createSongs(t, l, "Muse", "Uprising")[0]
+	ops := batchOps(song.ID, song.ID+100)
+	ops = append(ops, BatchOp{Kind: BatchDelete, ID: song.ID, Precondition: ExpectVersion(song.Version)})
+
+	results, err := l.RunBatch(ctx, ops, false)
+	if err != nil {
+		t.Fatalf("RunBatch: %v", err)
+	}
+	if results[0].Err != nil || results[1].Err != nil {
+		t.Errorf("independent operations failed: %v, %v", results[0].Err, results[1].Err)
+	}
+	if !errors.Is(results[2].Err, ErrNotFound) {
+		t.Errorf("delete of a missing song: %v, want ErrNotFound", results[2].Err)
+	}
+	// Первая операция уже увеличила версию песни
+	if !errors.Is(results[3].Err, ErrVersionMismatch) {
+		t.Errorf("delete with a stale version: %v, want ErrVersionMismatch", results[3].Err)
+	}
+
+	if text, _ := songText(t, db, song.ID); text != "changed" {
+		t.Errorf("song text %q, want the change of the successful operation", text)
+	}
+	if n := testdb.Count(t, db, "SELECT COUNT(*) FROM songs"); n != 2 {
+		t.Errorf("%d songs, want the original and the created one", n)
+	}
+}
+
+func TestBulkChangeDryRun(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	l := NewLibrary(testdb.Pool(db), nil)
+
+	songs := createSongs(t, l, "Muse", "Uprising", "Madness")
+	createSongs(t, l, "Queen", "Innuendo")
+	clearEvents(t, db)
+
+	filter := SongFilter{Group: "Muse"}
+	setText := func(current models.Song) (models.SongInput, error) {
+		input := SongToInput(current)
+		input.Text = "bulk"
+		return input, nil
+	}
+
+	ids, err := l.BulkModifySongs(ctx, filter, true, setText)
+	if err != nil {
+		t.Fatalf("BulkModifySongs dry run: %v", err)
+	}
+	if want := []int{songs[0].ID, songs[1].ID}; !slices.Equal(ids, want) {
+		t.Errorf("dry run ids %v, want %v", ids, want)
+	}
+	ids, err = l.BulkDeleteSongs(ctx, filter, true)
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("BulkDeleteSongs dry run: %v, %v", ids, err)
+	}
+
+	for _, song := range songs {
+		if text, version := songText(t, db, song.ID); text != "" || version != song.Version {
+			t.Errorf("song %d changed to text %q, version %d by a dry run", song.ID, text, version)
+		}
+	}
+	if n := testdb.Count(t, db, "SELECT COUNT(*) FROM songs"); n != 3 {
+		t.Errorf("%d songs after dry runs, want 3", n)
+	}
+	if types := eventTypes(t, db); len(types) != 0 {
+		t.Errorf("events %v recorded by dry runs", types)
+	}
+
+	if _, err := l.BulkModifySongs(ctx, filter, false, setText); err != nil {
+		t.Fatalf("BulkModifySongs: %v", err)
+	}
+	for _, song := range songs {
+		if text, _ := songText(t, db, song.ID); text != "bulk" {
+			t.Errorf("song %d text %q, want bulk", song.ID, text)
+		}
+	}
+}
+
+func TestBulkChangeRollsBackOnError(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	l := NewLibrary(testdb.Pool(db), nil)
+
+	songs := createSongs(t, l, "Muse", "Uprising", "Madness")
+	failure := newError(ErrValidation, "Invalid song data")
+
+	_, err := l.BulkModifySongs(ctx, SongFilter{Group: "muse"}, false, func(current models.Song) (models.SongInput, error) {
+		if current.ID == songs[1].ID {
+			return models.SongInput{}, failure
+		}
+		input := SongToInput(current)
+		input.Text = "bulk"
+		return input, nil
+	})
+	if err != failure {
+		t.Fatalf("error = %v, want the error of the second song", err)
+	}
+	if text, version := songText(t, db, songs[0].ID); text != "" || version != songs[0].Version {
+		t.Errorf("first song changed to text %q, version %d, want the change rolled back", text, version)
+	}
+
+	if _, err := l.BulkDeleteSongs(ctx, SongFilter{}, false); !errors.Is(err, ErrValidation) {
+		t.Errorf("empty filter: error = %v, want a validation error", err)
+	}
+	if n := testdb.Count(t, db, "SELECT COUNT(*) FROM songs"); n != 2 {
+		t.Errorf("%d songs, want 2", n)
+	}
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/cache"
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/metrics"
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// Кешируемые ресурсы, они же метки метрики обращений к кешу
+const (
+	cachedSong  = "song"
+	cachedSongs = "songs"
+)
+
+// songsGenerationKey - ключ поколения кеша страниц списка песен. Любое изменение
+// песен начинает новое поколение, и страницы прежних поколений больше не читаются,
+// поэтому их не нужно искать и удалять по одной
+const songsGenerationKey = "songs:generation"
+
+// Поколения защищают кеш от гонки чтения с изменением: читатель узнаёт поколение
+// до чтения из базы и сохраняет результат в этом поколении, а изменение после
+// фиксации начинает новое. Если читатель успел прочитать из базы прежние данные,
+// они попадут в прежнее поколение и больше не будут прочитаны
+
+// songEntry - песня в кеше вместе с ID группы, который не попадает в JSON песни
+type songEntry struct {
+	Song    models.Song `json:"song"`
+	GroupID int         `json:"groupId"`
+}
+
+// songsEntry - страница списка песен в кеше
+type songsEntry struct {
+	Songs []songEntry `json:"songs"`
+	Total int         `json:"total"`
+}
+
+// noCache - источник кеша отключённого кеша
+func noCache() cache.Cache {
+	return nil
+}
+
+// songGenerationKey возвращает ключ поколения кеша песни id. У каждой песни своё
+// поколение, поэтому изменение песни не вытесняет из кеша остальные
+func songGenerationKey(id int) string {
+	return "song:" + strconv.Itoa(id) + ":generation"
+}
+
+// songKey возвращает ключ кеша песни id в текущем поколении или false, если
+// поколение не удалось определить
+func (l *Library) songKey(ctx context.Context, store cache.Cache, id int) (string, bool) {
+	generation, ok := l.generation(ctx, store, cachedSong, songGenerationKey(id), config.Get().Cache.TTL.Std())
+	if !ok {
+		return "", false
+	}
+	return "song:" + strconv.Itoa(id) + ":" + string(generation), true
+}
+
+// cacheGet читает ключ key ресурса resource из кеша в value и сообщает, найден ли он.
+// Сбой кеша не мешает чтению из базы и считается промахом
+func (l *Library) cacheGet(ctx context.Context, resource, key string, value interface{}) bool {
+	store := l.cache()
+	if store == nil {
+		return false
+	}
+
+	data, ok, err := store.Get(ctx, key)
+	if err == nil && ok {
+		err = json.Unmarshal(data, value)
+	}
+	switch {
+	case err != nil:
+		logger.FromContext(ctx).Warnf("Failed to read %s from cache: %v", key, err)
+		metrics.ObserveCache(resource, metrics.CacheError)
+		return false
+	case !ok:
+		metrics.ObserveCache(resource, metrics.CacheMiss)
+		return false
+	}
+	metrics.ObserveCache(resource, metrics.CacheHit)
+	return true
+}
+
+// cacheSet сохраняет value в кеше под ключом key на CACHE_TTL
+func (l *Library) cacheSet(ctx context.Context, key string, value interface{}) {
+	store := l.cache()
+	if store == nil {
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = store.Set(ctx, key, data, config.Get().Cache.TTL.Std())
+	}
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Failed to store %s in cache: %v", key, err)
+	}
+}
+
+// songsKey возвращает ключ кеша страницы page списка песен по filter в текущем
+// поколении или false, если поколение не удалось определить
+func (l *Library) songsKey(ctx context.Context, store cache.Cache, filter SongFilter, page, limit int) (string, bool) {
+	generation, ok := l.generation(ctx, store, cachedSongs, songsGenerationKey, 0)
+	if !ok {
+		return "", false
+	}
+
+	// Фильтры могут быть длинными, поэтому в ключ попадает их хеш
+	query, _ := json.Marshal(struct {
+		Filter      SongFilter
+		Page, Limit int
+	}{filter, page, limit})
+	sum := sha256.Sum256(query)
+	return "songs:" + string(generation) + ":" + hex.EncodeToString(sum[:]), true
+}
+
+// generation возвращает поколение кеша ресурса resource из ключа key, начиная
+// новое со временем жизни ttl, если ключа нет, или false при сбое кеша
+func (l *Library) generation(ctx context.Context, store cache.Cache, resource, key string, ttl time.Duration) ([]byte, bool) {
+	generation, ok, err := store.Get(ctx, key)
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Failed to read cache generation %s: %v", key, err)
+		metrics.ObserveCache(resource, metrics.CacheError)
+		return nil, false
+	}
+	if !ok {
+		generation = l.newGeneration(ctx, store, key, ttl)
+	}
+	return generation, generation != nil
+}
+
+// newGeneration начинает новое поколение кеша в ключе key и возвращает его или nil
+// при сбое кеша. Поколения не повторяются, поэтому записи прежнего поколения
+// не станут снова видны, даже если ключ поколения был вытеснен или удалён
+func (l *Library) newGeneration(ctx context.Context, store cache.Cache, key string, ttl time.Duration) []byte {
+	generation := []byte(strconv.FormatInt(time.Now().UnixNano(), 36))
+	if err := store.Set(ctx, key, generation, ttl); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to start cache generation %s: %v", key, err)
+		return nil
+	}
+	return generation
+}
+
+// invalidate начинает новые поколения кеша песен ids и страниц списка песен.
+// Ключи поколений песен удаляются: следующее чтение песни начнёт новое поколение.
+// Вызывается после фиксации транзакции, изменившей песни
+func (l *Library) invalidate(ctx context.Context, ids ...int) {
+	store := l.cache()
+	if store == nil {
+		return
+	}
+
+	// Изменения уже зафиксированы, поэтому кеш очищается и после отмены запроса
+	ctx = context.WithoutCancel(ctx)
+	if len(ids) > 0 {
+		keys := make([]string, len(ids))
+		for i, id := range ids {
+			keys[i] = songGenerationKey(id)
+		}
+		if err := store.Delete(ctx, keys...); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to invalidate cached songs %v: %v", ids, err)
+		}
+	}
+	l.newGeneration(ctx, store, songsGenerationKey, 0)
+}
+
+// cachedSongEntry переводит песню в запись кеша
+func cachedSongEntry(song Song) songEntry {
+	return songEntry{Song: song.Song, GroupID: song.GroupID}
+}
+
+// song переводит запись кеша в песню
+func (e songEntry) song() Song {
+	return Song{Song: e.Song, GroupID: e.GroupID}
+}
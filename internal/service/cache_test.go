@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/inanmasov/music-service/internal/cache"
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+func TestInvalidateHidesStaleSong(t *testing.T) {
+	store := cache.NewMemory(100)
+	l := NewLibrary(nil, func() cache.Cache { return store })
+	ctx := context.Background()
+
+	// Чтение узнало поколение и прочитало песню из базы до изменения
+	key, ok := l.songKey(ctx, store, 1)
+	if !ok {
+		t.Fatal("songKey failed with a working cache")
+	}
+	stale := Song{Song: models.Song{ID: 1, SongName: "Old"}}
+
+	// Изменение зафиксировано и очистило кеш раньше, чем чтение сохранило песню
+	l.invalidate(ctx, 1)
+	l.cacheSet(ctx, key, cachedSongEntry(stale))
+
+	fresh, ok := l.songKey(ctx, store, 1)
+	if !ok {
+		t.Fatal("songKey failed with a working cache")
+	}
+	if fresh == key {
+		t.Fatal("invalidate kept the song cache generation")
+	}
+	var entry songEntry
+	if l.cacheGet(ctx, cachedSong, fresh, &entry) {
+		t.Errorf("read the stale song %+v after invalidate", entry.song())
+	}
+}
+
+func TestInvalidateKeepsOtherSongs(t *testing.T) {
+	store := cache.NewMemory(100)
+	l := NewLibrary(nil, func() cache.Cache { return store })
+	ctx := context.Background()
+
+	key, _ := l.songKey(ctx, store, 2)
+	l.cacheSet(ctx, key, cachedSongEntry(Song{Song: models.Song{ID: 2, SongName: "Kept"}}))
+	l.invalidate(ctx, 1)
+
+	other, _ := l.songKey(ctx, store, 2)
+	var entry songEntry
+	if other != key || !l.cacheGet(ctx, cachedSong, other, &entry) {
+		t.Error("invalidating song 1 evicted song 2")
+	}
+}
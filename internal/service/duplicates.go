@@ -0,0 +1,435 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/outbox"
+	"github.com/inanmasov/music-service/internal/validation"
+	"github.com/lib/pq"
+)
+
+// Признаки, по которым песни считаются возможными дубликатами
+const (
+	// DuplicateByTitle - совпадают нормализованные названия
+	DuplicateByTitle = "title"
+	// DuplicateByLyrics - тексты похожи не меньше заданного порога
+	DuplicateByLyrics = "lyrics"
+)
+
+// findDuplicateQuery находит песню группы $1 с названием $2 после нормализации,
+// кроме песни $3. Нормализация выполняется функцией normalize_title из миграций,
+// по ней же построены индексы
+const findDuplicateQuery = `
+	SELECT songs.id
+	FROM songs
+	JOIN groups ON songs.group_id = groups.id
+	WHERE normalize_title(groups.name) = normalize_title($1)
+		AND normalize_title(songs.song) = normalize_title($2)
+		AND songs.id <> $3
+	ORDER BY songs.id
+	LIMIT 1`
+
+// DuplicateSet - песни одной группы, похожие на дубликаты друг друга
+type DuplicateSet struct {
+	// Reasons - признаки сходства песен набора: DuplicateByTitle, DuplicateByLyrics
+	Reasons []string
+	// Similarity - наибольшее сходство текстов песен набора от 0 до 1
+	Similarity float64
+	// Songs - песни набора по возрастанию ID
+	Songs []Song
+}
+
+// duplicatePolicy возвращает политику добавления дубликатов SONGS_DUPLICATE_POLICY
+func duplicatePolicy() string {
+	return config.Get().Songs.DuplicatePolicy
+}
+
+// duplicateError возвращает ErrDuplicateSong для уже добавленной песни id
+func duplicateError(id int) error {
+	return &Error{Kind: ErrDuplicateSong, Message: "Song already exists", Err: &DuplicateSongError{SongID: id}}
+}
+
+// lockDuplicate блокирует до конца транзакции пару из нормализованных группы и
+// названия и возвращает ID песни с ними, кроме песни exceptID, или 0. Блокировка
+// не даёт двум транзакциям одновременно добавить одну и ту же песню
+func lockDuplicate(ctx context.Context, tx *sql.Tx, group, title string, exceptID int) (int, error) {
+	_, err := tx.ExecContext(ctx,
+		"SELECT pg_advisory_xact_lock(hashtext(normalize_title($1) || '/' || normalize_title($2)))", group, title)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = tx.QueryRowContext(ctx, findDuplicateQuery, group, title, exceptID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// renamed сообщает, что input меняет нормализованную группу или название песни
+func renamed(current models.Song, input models.SongInput) bool {
+	return normalizeTitle(current.GroupName) != normalizeTitle(input.Group) ||
+		normalizeTitle(current.SongName) != normalizeTitle(input.Song)
+}
+
+// normalizeTitle приводит название так же, как normalize_title в базе данных:
+// нижний регистр, слова из букв и цифр через один пробел
+func normalizeTitle(title string) string {
+	return strings.Join(words(title), " ")
+}
+
+// words разбивает текст на слова из букв и цифр в нижнем регистре
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// FindDuplicates возвращает страницу наборов возможных дубликатов и общее число
+// наборов. Песни одной группы (после нормализации названия группы) попадают в
+// набор, если совпадают их нормализованные названия или сходство их текстов -
+// доля общих слов (коэффициент Жаккара) - не меньше minSimilarity
+func (l *Library) FindDuplicates(ctx context.Context, minSimilarity float64, page, limit int) ([]DuplicateSet, int, error) {
+	if err := CheckPage(page, limit, false); err != nil {
+		return nil, 0, err
+	}
+	if minSimilarity <= 0 || minSimilarity > 1 {
+		return nil, 0, newError(ErrValidation, "Invalid minSimilarity: must be greater than 0 and at most 1")
+	}
+
+	pool, err := l.db(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Песни упорядочены по нормализованной группе, поэтому в памяти держится
+	// только одна группа
+	query := `
+		SELECT normalize_title(groups.name), normalize_title(songs.song),` + songColumns + `
+		FROM songs
+		JOIN groups ON songs.group_id = groups.id
+		ORDER BY 1, songs.id`
+
+	rows, err := pool.QueryContext(ctx, query)
+	if err != nil {
+		return nil, 0, internalError(ctx, "Failed to retrieve songs", err)
+	}
+	defer rows.Close()
+
+	var sets []DuplicateSet
+	var bucket []candidate
+	var bucketGroup string
+	for rows.Next() {
+		var group string
+		var c candidate
+		if err := rows.Scan(&group, &c.title, &c.song.GroupID, &c.song.ID, &c.song.GroupName,
+			&c.song.SongName, &c.releaseDate, &c.text, &c.link, &c.song.Version); err != nil {
+			return nil, 0, internalError(ctx, "Failed to scan song", err)
+		}
+		c.song.ReleaseDate = c.releaseDate.Time
+		c.song.Text = c.text.String
+		c.song.Link = c.link.String
+
+		if group != bucketGroup {
+			sets = append(sets, duplicateSets(bucket, minSimilarity)...)
+			bucket, bucketGroup = bucket[:0], group
+		}
+		bucket = append(bucket, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, internalError(ctx, "Failed to retrieve songs", err)
+	}
+	sets = append(sets, duplicateSets(bucket, minSimilarity)...)
+
+	logger.FromContext(ctx).Debugf("Found %d sets of possible duplicates", len(sets))
+
+	start := min((page-1)*limit, len(sets))
+	end := min(start+limit, len(sets))
+	return sets[start:end], len(sets), nil
+}
+
+// candidate - песня, проверяемая на дубликаты, с нормализованным названием
+type candidate struct {
+	song        Song
+	title       string
+	releaseDate sql.NullTime
+	text        sql.NullString
+	link        sql.NullString
+}
+
+// duplicateSets объединяет песни одной группы в наборы дубликатов. Песни,
+// не похожие ни на одну другую, в результат не попадают
+func duplicateSets(songs []candidate, minSimilarity float64) []DuplicateSet {
+	if len(songs) < 2 {
+		return nil
+	}
+
+	lyrics := make([]map[string]struct{}, len(songs))
+	for i, c := range songs {
+		lyrics[i] = wordSet(c.song.Text)
+	}
+
+	// Наборы строятся объединением непересекающихся множеств: песни, связанные
+	// цепочкой похожих пар, попадают в один набор
+	parent := make([]int, len(songs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+
+	type link struct {
+		a, b       int
+		byTitle    bool
+		similarity float64
+	}
+	var links []link
+	for i := range songs {
+		for j := i + 1; j < len(songs); j++ {
+			l := link{a: i, b: j, byTitle: songs[i].title == songs[j].title}
+			if len(lyrics[i]) > 0 && len(lyrics[j]) > 0 {
+				l.similarity = jaccard(lyrics[i], lyrics[j])
+			}
+			if l.byTitle || l.similarity >= minSimilarity {
+				links = append(links, l)
+				// Корнем остаётся песня с меньшим ID
+				ri, rj := root(i), root(j)
+				parent[max(ri, rj)] = min(ri, rj)
+			}
+		}
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	byRoot := make(map[int]*DuplicateSet)
+	var roots []int
+	for _, l := range links {
+		r := root(l.a)
+		set, ok := byRoot[r]
+		if !ok {
+			set = &DuplicateSet{}
+			byRoot[r] = set
+			roots = append(roots, r)
+		}
+		if l.byTitle && !slices.Contains(set.Reasons, DuplicateByTitle) {
+			set.Reasons = append(set.Reasons, DuplicateByTitle)
+		}
+		if l.similarity >= minSimilarity && !slices.Contains(set.Reasons, DuplicateByLyrics) {
+			set.Reasons = append(set.Reasons, DuplicateByLyrics)
+		}
+		set.Similarity = max(set.Similarity, l.similarity)
+	}
+	for i, c := range songs {
+		if set, ok := byRoot[root(i)]; ok {
+			set.Songs = append(set.Songs, c.song)
+		}
+	}
+
+	// Корень набора - песня с наименьшим ID, поэтому наборы идут по возрастанию ID первой песни
+	slices.Sort(roots)
+	sets := make([]DuplicateSet, len(roots))
+	for i, r := range roots {
+		sets[i] = *byRoot[r]
+	}
+	return sets
+}
+
+// wordSet возвращает множество слов текста
+func wordSet(text string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, word := range words(text) {
+		set[word] = struct{}{}
+	}
+	return set
+}
+
+// jaccard возвращает долю общих слов двух множеств среди всех их слов
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	common := 0
+	for word := range a {
+		if _, ok := b[word]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// MergeSongs сливает песни duplicateIDs с песней id в одной транзакции. Пустые
+// дата выхода, текст и ссылка песни id заполняются из дубликатов в порядке
+// duplicateIDs, дубликаты удаляются, а их ID перенаправляются на песню id
+// (см. SongRedirect). precondition - ожидаемая версия песни id
+func (l *Library) MergeSongs(ctx context.Context, id int, duplicateIDs []int, precondition Precondition) (Song, error) {
+	duplicateIDs, err := checkMerge(id, duplicateIDs)
+	if err != nil {
+		return Song{}, err
+	}
+
+	song, err := l.changeSong(ctx, id, precondition, func(tx *sql.Tx, current Song) (Song, error) {
+		duplicates, err := lockSongs(ctx, tx, duplicateIDs)
+		if err != nil {
+			return Song{}, err
+		}
+
+		song := current
+		input := SongToInput(current.Song)
+		for _, duplicate := range duplicates {
+			if input.ReleaseDate.IsZero() {
+				input.ReleaseDate = duplicate.ReleaseDate
+			}
+			if input.Text == "" {
+				input.Text = duplicate.Text
+			}
+			if input.Link == "" {
+				input.Link = duplicate.Link
+			}
+		}
+		if input != SongToInput(current.Song) {
+			song, err = saveSong(ctx, tx, id, input)
+			if err != nil {
+				return Song{}, internalError(ctx, "Failed to update song", err)
+			}
+		}
+
+		if err := redirectSongs(ctx, tx, song, duplicates); err != nil {
+			return Song{}, internalError(ctx, "Failed to merge songs", err)
+		}
+		return song, nil
+	})
+	if err != nil {
+		return Song{}, err
+	}
+	// Закешированные дубликаты не должны мешать перенаправлению на песню id
+	l.invalidate(ctx, duplicateIDs...)
+
+	logger.FromContext(ctx).Infof("Songs %v merged into song with ID %d", duplicateIDs, id)
+	return song, nil
+}
+
+// checkMerge проверяет ID дубликатов и возвращает их без повторов
+func checkMerge(id int, duplicateIDs []int) ([]int, error) {
+	field := func(code, message string) error {
+		return Invalid("Invalid merge request", validation.Errors{{Field: "duplicateIds", Code: code, Message: message}})
+	}
+
+	if len(duplicateIDs) == 0 {
+		return nil, field(validation.CodeRequired, "must contain at least one song ID")
+	}
+	unique := make([]int, 0, len(duplicateIDs))
+	for _, duplicateID := range duplicateIDs {
+		switch {
+		case duplicateID <= 0:
+			return nil, field(validation.CodeOutOfRange, "must contain positive song IDs")
+		case duplicateID == id:
+			return nil, field(validation.CodeOutOfRange, "must not contain the ID of the song being kept")
+		case !slices.Contains(unique, duplicateID):
+			unique = append(unique, duplicateID)
+		}
+	}
+	return unique, nil
+}
+
+// lockSongs блокирует песни ids до конца транзакции и возвращает их в порядке ids.
+// Если какой-то песни нет, возвращает ErrNotFound
+func lockSongs(ctx context.Context, tx *sql.Tx, ids []int) ([]Song, error) {
+	// Строки блокируются по возрастанию ID, чтобы слияния не ждали друг друга по кругу
+	query := selectSongsQuery + " AND songs.id = ANY($1) ORDER BY songs.id FOR UPDATE OF songs"
+	rows, err := tx.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve songs", err)
+	}
+	defer rows.Close()
+
+	found := make(map[int]Song, len(ids))
+	for rows.Next() {
+		song, err := scanSong(rows)
+		if err != nil {
+			return nil, internalError(ctx, "Failed to scan song", err)
+		}
+		found[song.ID] = song
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(ctx, "Failed to retrieve songs", err)
+	}
+
+	songs := make([]Song, len(ids))
+	for i, id := range ids {
+		song, ok := found[id]
+		if !ok {
+			return nil, newError(ErrNotFound, fmt.Sprintf("Song with ID %d not found", id))
+		}
+		songs[i] = song
+	}
+	return songs, nil
+}
+
+// redirectSongs удаляет дубликаты песни song и перенаправляет их ID на неё. Перенаправления,
+// ведущие на дубликаты после прошлых слияний, переводятся на song. О каждом
+// удалённом дубликате записывается событие song.deleted, о слиянии - song.merged.
+// Группы дубликатов, у которых не осталось песен, удаляются
+func redirectSongs(ctx context.Context, tx *sql.Tx, song Song, duplicates []Song) error {
+	ids := make([]int, len(duplicates))
+	for i, duplicate := range duplicates {
+		ids[i] = duplicate.ID
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE song_redirects SET song_id = $1 WHERE song_id = ANY($2)", song.ID, pq.Array(ids)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO song_redirects (old_id, song_id) SELECT unnest($2::int[]), $1", song.ID, pq.Array(ids)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM songs WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+		return err
+	}
+
+	groupIDs := make([]int, len(duplicates))
+	for i, duplicate := range duplicates {
+		if err := outbox.Record(ctx, tx, models.EventSongDeleted, duplicate.ID, duplicate.Song); err != nil {
+			return err
+		}
+		groupIDs[i] = duplicate.GroupID
+	}
+	if err := outbox.Record(ctx, tx, models.EventSongMerged, song.ID,
+		models.SongMerge{SongID: song.ID, Group: song.GroupName, DuplicateIDs: ids}); err != nil {
+		return err
+	}
+	return removeEmptyGroups(ctx, tx, groupIDs...)
+}
+
+// SongRedirect возвращает ID песни, с которой была слита удалённая песня id.
+// Если песня id не сливалась, возвращает ErrNotFound
+func (l *Library) SongRedirect(ctx context.Context, id int) (int, error) {
+	pool, err := l.db(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var songID int
+	err = pool.QueryRowContext(ctx, "SELECT song_id FROM song_redirects WHERE old_id = $1", id).Scan(&songID)
+	if err == sql.ErrNoRows {
+		return 0, newError(ErrNotFound, "Song not found")
+	} else if err != nil {
+		return 0, internalError(ctx, "Failed to retrieve song", err)
+	}
+	return songID, nil
+}
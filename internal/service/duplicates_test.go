@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/inanmasov/music-service/internal/testdb"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+// titleCases - названия и их нормализованный вид, общие для проверки normalizeTitle
+// и функции normalize_title в базе данных
+var titleCases = []struct {
+	title, want string
+}{
+	{"Uprising", "uprising"},
+	{"  Bohemian   Rhapsody ", "bohemian rhapsody"},
+	{"AC/DC", "ac dc"},
+	{"Guns N' Roses", "guns n roses"},
+	{"Don't Stop Me Now!!!", "don t stop me now"},
+	{"Track_01 (Live, 2001)", "track 01 live 2001"},
+	{"tab\tand\nnew line", "tab and new line"},
+	{"Кино — Группа крови", "кино группа крови"},
+	{"ЁЖИК В ТУМАНЕ", "ёжик в тумане"},
+	{"Mötley Crüe", "mötley crüe"},
+	{"...", ""},
+	{"", ""},
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	for _, tt := range titleCases {
+		if got := normalizeTitle(tt.title); got != tt.want {
+			t.Errorf("normalizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+// TestNormalizeTitleMatchesDatabase проверяет, что normalizeTitle совпадает с
+// normalize_title в базе данных: по ней ищутся дубликаты при добавлении песен
+func TestNormalizeTitleMatchesDatabase(t *testing.T) {
+	db := testdb.Open(t)
+	for _, tt := range titleCases {
+		var got string
+		if err := db.QueryRow("SELECT normalize_title($1)", tt.title).Scan(&got); err != nil {
+			t.Fatalf("normalize_title(%q): %v", tt.title, err)
+		}
+		if want := normalizeTitle(tt.title); got != want {
+			t.Errorf("normalize_title(%q) = %q in the database, normalizeTitle = %q", tt.title, got, want)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"!?", nil},
+		{"Hello, World", []string{"hello", "world"}},
+		{"Let it be\nLet it be", []string{"let", "it", "be", "let", "it", "be"}},
+		{"Всё идёт по плану", []string{"всё", "идёт", "по", "плану"}},
+	}
+	for _, tt := range tests {
+		if got := words(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("words(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"a b c", "a b c", 1},
+		{"a b c", "d e f", 0},
+		{"a b", "b c", 1.0 / 3},
+		{"a a b", "A B", 1},
+		{"a", "a b c d", 0.25},
+		{"a b c d", "a", 0.25},
+	}
+	for _, tt := range tests {
+		got := jaccard(wordSet(tt.a), wordSet(tt.b))
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("jaccard(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// newCandidate возвращает песню id с названием title и текстом text для duplicateSets
+func newCandidate(id int, title, text string) candidate {
+	c := candidate{title: normalizeTitle(title)}
+	c.song.ID = id
+	c.song.SongName = title
+	c.song.Text = text
+	return c
+}
+
+// setIDs возвращает ID песен каждого набора
+func setIDs(sets []DuplicateSet) [][]int {
+	ids := make([][]int, len(sets))
+	for i, set := range sets {
+		for _, song := range set.Songs {
+			ids[i] = append(ids[i], song.ID)
+		}
+	}
+	return ids
+}
+
+func TestDuplicateSets(t *testing.T) {
+	tests := []struct {
+		name          string
+		songs         []candidate
+		minSimilarity float64
+		wantIDs       [][]int
+		wantReasons   [][]string
+		wantSimilar   []float64
+	}{
+		{
+			name:          "single song",
+			songs:         []candidate{newCandidate(1, "Uprising", "")},
+			minSimilarity: 0.5,
+		},
+		{
+			name:          "different songs",
+			songs:         []candidate{newCandidate(1, "Uprising", "paranoia is in bloom"), newCandidate(2, "Starlight", "far away")},
+			minSimilarity: 0.5,
+		},
+		{
+			name:          "same title",
+			songs:         []candidate{newCandidate(1, "Uprising", ""), newCandidate(2, "UPRISING!", "")},
+			minSimilarity: 0.8,
+			wantIDs:       [][]int{{1, 2}},
+			wantReasons:   [][]string{{DuplicateByTitle}},
+			wantSimilar:   []float64{0},
+		},
+		{
+			name: "similar lyrics",
+			songs: []candidate{
+				newCandidate(1, "Uprising", "a b c d"),
+				newCandidate(2, "Uprising (Live)", "a b c e"),
+			},
+			minSimilarity: 0.6,
+			wantIDs:       [][]int{{1, 2}},
+			wantReasons:   [][]string{{DuplicateByLyrics}},
+			wantSimilar:   []float64{0.6},
+		},
+		{
+			name: "lyrics below threshold",
+			songs: []candidate{
+				newCandidate(1, "Uprising", "a b c d"),
+				newCandidate(2, "Uprising (Live)", "a b c e"),
+			},
+			minSimilarity: 0.7,
+		},
+		{
+			name: "empty lyrics are not similar",
+			songs: []candidate{
+				newCandidate(1, "One", ""),
+				newCandidate(2, "Two", ""),
+			},
+			minSimilarity: 0.1,
+		},
+		{
+			name: "chain and both reasons",
+			songs: []candidate{
+				newCandidate(3, "Starlight", "x y z"),
+				newCandidate(5, "Starlight", "p q"),
+				newCandidate(7, "Starlight (Remix)", "x y z"),
+			},
+			minSimilarity: 0.9,
+			wantIDs:       [][]int{{3, 5, 7}},
+			wantReasons:   [][]string{{DuplicateByTitle, DuplicateByLyrics}},
+			wantSimilar:   []float64{1},
+		},
+		{
+			name: "separate sets ordered by first ID",
+			songs: []candidate{
+				newCandidate(1, "Uprising", ""),
+				newCandidate(2, "Starlight", ""),
+				newCandidate(3, "Starlight", ""),
+				newCandidate(4, "Uprising", ""),
+				newCandidate(5, "Madness", ""),
+			},
+			minSimilarity: 1,
+			wantIDs:       [][]int{{1, 4}, {2, 3}},
+			wantReasons:   [][]string{{DuplicateByTitle}, {DuplicateByTitle}},
+			wantSimilar:   []float64{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sets := duplicateSets(tt.songs, tt.minSimilarity)
+			if got := setIDs(sets); !slices.EqualFunc(got, tt.wantIDs, slices.Equal[[]int]) {
+				t.Fatalf("sets %v, want %v", got, tt.wantIDs)
+			}
+			for i, set := range sets {
+				if !slices.Equal(set.Reasons, tt.wantReasons[i]) {
+					t.Errorf("set %d reasons %v, want %v", i, set.Reasons, tt.wantReasons[i])
+				}
+				if math.Abs(set.Similarity-tt.wantSimilar[i]) > 1e-9 {
+					t.Errorf("set %d similarity %v, want %v", i, set.Similarity, tt.wantSimilar[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckMerge(t *testing.T) {
+	tests := []struct {
+		name         string
+		duplicateIDs []int
+		want         []int
+		wantCode     string
+	}{
+		{name: "unique", duplicateIDs: []int{3, 2}, want: []int{3, 2}},
+		{name: "repeated", duplicateIDs: []int{3, 2, 3, 2}, want: []int{3, 2}},
+		{name: "empty", wantCode: validation.CodeRequired},
+		{name: "not positive", duplicateIDs: []int{2, 0}, wantCode: validation.CodeOutOfRange},
+		{name: "kept song", duplicateIDs: []int{2, 1}, wantCode: validation.CodeOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkMerge(1, tt.duplicateIDs)
+			if tt.wantCode == "" {
+				if err != nil || !slices.Equal(got, tt.want) {
+					t.Errorf("got %v, %v, want %v", got, err, tt.want)
+				}
+				return
+			}
+
+			var serviceErr *Error
+			if !errors.As(err, &serviceErr) || !errors.Is(err, ErrValidation) {
+				t.Fatalf("error = %v, want a validation error", err)
+			}
+			fields := serviceErr.Fields()
+			if len(fields) != 1 || fields[0].Field != "duplicateIds" || fields[0].Code != tt.wantCode {
+				t.Errorf("field errors %+v, want duplicateIds %s", fields, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestFindDuplicatesValidation(t *testing.T) {
+	l := NewLibrary(nil, nil)
+	for _, similarity := range []float64{0, -0.5, 1.5} {
+		if _, _, err := l.FindDuplicates(context.Background(), similarity, 1, 10); !errors.Is(err, ErrValidation) {
+			t.Errorf("minSimilarity %v: error = %v, want a validation error", similarity, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+// Виды ошибок операций. Транспорты сопоставляют их со своими статусами
+// через errors.Is: REST - с HTTP-статусами, gRPC - с кодами статуса
+var (
+	// ErrNotFound - запрошенного объекта нет
+	ErrNotFound = errors.New("not found")
+	// ErrConflict - операция противоречит текущему состоянию данных
+	ErrConflict = errors.New("conflict")
+	// ErrValidation - некорректные входные данные
+	ErrValidation = errors.New("validation failed")
+	// ErrUpstreamUnavailable - внешний сервис не ответил или ответил ошибкой
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	// ErrBatchAborted - операция атомарного пакета не применена из-за ошибки другой операции
+	ErrBatchAborted = errors.New("batch aborted")
+
+	// ErrVersionMismatch - частный случай ErrConflict: песня изменилась после того,
+	// как клиент прочитал её версию
+	ErrVersionMismatch = fmt.Errorf("%w: version mismatch", ErrConflict)
+	// ErrVersionRequired - частный случай ErrValidation: ожидаемая версия песни
+	// обязательна, но не передана
+	ErrVersionRequired = fmt.Errorf("%w: version required", ErrValidation)
+	// ErrDuplicateSong - частный случай ErrConflict: песня с той же группой и названием
+	// уже есть в библиотеке. Причина ошибки - *DuplicateSongError
+	ErrDuplicateSong = fmt.Errorf("%w: duplicate song", ErrConflict)
+)
+
+// DuplicateSongError - причина ErrDuplicateSong: ID уже добавленной песни
+type DuplicateSongError struct {
+	SongID int
+}
+
+func (e *DuplicateSongError) Error() string {
+	return fmt.Sprintf("song %d has the same group and title", e.SongID)
+}
+
+// Error - ошибка операции. Message можно показывать клиенту, Kind - вид ошибки
+// (ErrNotFound, ErrConflict...), у внутренних ошибок он пуст. Err - причина
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap возвращает вид и причину ошибки для errors.Is и errors.As
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Fields возвращает ошибки полей, если ошибка вызвана некорректными полями
+func (e *Error) Fields() validation.Errors {
+	var fields validation.Errors
+	errors.As(e.Err, &fields)
+	return fields
+}
+
+// newError создаёт ошибку вида kind с сообщением message
+func newError(kind error, message string) error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Invalid создаёт ошибку проверки входных данных. err - ошибки полей
+// (validation.Errors) или ошибка разбора данных
+func Invalid(message string, err error) error {
+	return &Error{Kind: ErrValidation, Message: message, Err: err}
+}
+
+// internalError записывает err в журнал и возвращает внутреннюю ошибку с сообщением message
+func internalError(ctx context.Context, message string, err error) error {
+	logger.FromContext(ctx).Errorf("%s: %v", message, err)
+	return &Error{Message: message, Err: err}
+}
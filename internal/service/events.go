@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/lib/pq"
+)
+
+// EventFilter - условия отбора событий журнала изменений. Пустые поля не
+// ограничивают выборку
+type EventFilter struct {
+	// Types - типы событий
+	Types []string
+	// Group - название группы (без учёта регистра): события её песен и её самой
+	Group string
+}
+
+// ChangeEvent - событие журнала изменений с его позицией в журнале
+type ChangeEvent struct {
+	Seq   int64
+	Event models.Event
+}
+
+// LastEventSeq возвращает позицию последнего события журнала или 0, если журнал пуст
+func (l *Library) LastEventSeq(ctx context.Context) (int64, error) {
+	pool, err := l.db(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var seq int64
+	if err := pool.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM outbox_events").Scan(&seq); err != nil {
+		return 0, internalError(ctx, "Failed to read the change log", err)
+	}
+	return seq, nil
+}
+
+// ReadEvents читает из журнала изменений не больше limit событий после позиции
+// afterSeq, подходящих под фильтр. Позиции идут в порядке фиксации, поэтому
+// событие, ставшее видимым позже, не окажется позади уже прочитанных
+func (l *Library) ReadEvents(ctx context.Context, afterSeq int64, filter EventFilter, limit int) ([]ChangeEvent, error) {
+	pool, err := l.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT seq, id, event_type, payload, created_at
+		FROM outbox_events
+		WHERE seq > $1`
+	args := []interface{}{afterSeq}
+
+	if len(filter.Types) > 0 {
+		args = append(args, pq.Array(filter.Types))
+		query += " AND event_type = ANY($" + strconv.Itoa(len(args)) + ")"
+	}
+	if filter.Group != "" {
+		// Группа песни хранится в поле group, название самой группы - в поле name
+		args = append(args, filter.Group)
+		query += " AND lower(COALESCE(payload->>'group', payload->>'name')) = lower($" + strconv.Itoa(len(args)) + ")"
+	}
+
+	args = append(args, limit)
+	query += " ORDER BY seq LIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to read the change log", err)
+	}
+	defer rows.Close()
+
+	var events []ChangeEvent
+	for rows.Next() {
+		var item ChangeEvent
+		var payload []byte
+		if err := rows.Scan(&item.Seq, &item.Event.ID, &item.Event.Type, &payload, &item.Event.CreatedAt); err != nil {
+			return nil, internalError(ctx, "Failed to read the change log", err)
+		}
+		item.Event.Data = payload
+		events = append(events, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(ctx, "Failed to read the change log", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/lib/pq"
+)
+
+// SongFilter - условия отбора песен. Пустое условие не применяется. Все условия,
+// кроме ReleaseDate и IDs, ищут подстроку без учёта регистра. Если заданы IDs,
+// песни возвращаются в порядке IDs, иначе - в порядке ID
+type SongFilter struct {
+	Group       string
+	Song        string
+	ReleaseDate string
+	Text        string
+	Link        string
+	IDs         []int64
+}
+
+// empty сообщает, что фильтр не задаёт ни одного условия
+func (f SongFilter) empty() bool {
+	return f.Group == "" && f.Song == "" && f.ReleaseDate == "" && f.Text == "" && f.Link == "" && f.IDs == nil
+}
+
+// check проверяет значения фильтров
+func (f SongFilter) check() error {
+	if f.ReleaseDate != "" {
+		if _, err := time.Parse(time.DateOnly, f.ReleaseDate); err != nil {
+			return newError(ErrValidation, "releaseDate must be a YYYY-MM-DD date")
+		}
+	}
+	return nil
+}
+
+// build строит SQL-условия фильтра, каждое из которых начинается с " AND",
+// порядок сортировки и аргументы условий
+func (f SongFilter) build(ctx context.Context) (conditions, order string, args []interface{}) {
+	log := logger.FromContext(ctx)
+
+	log.Debugf("Request to get songs with filters: group=%s, song=%s, releaseDate=%s, text=%s, link=%s, ids=%v",
+		f.Group, f.Song, f.ReleaseDate, f.Text, f.Link, f.IDs)
+
+	// Добавляем фильтрацию по имени группы
+	if f.Group != "" {
+		conditions += " AND groups.name ILIKE $" + strconv.Itoa(len(args)+1)
+		args = append(args, "%"+f.Group+"%")
+	}
+
+	// Добавляем фильтрацию по названию песни
+	if f.Song != "" {
+		conditions += " AND songs.song ILIKE $" + strconv.Itoa(len(args)+1)
+		args = append(args, "%"+f.Song+"%")
+	}
+
+	// Добавляем фильтрацию по дате выхода
+	if f.ReleaseDate != "" {
+		conditions += " AND songs.release_date = $" + strconv.Itoa(len(args)+1)
+		args = append(args, f.ReleaseDate)
+	}
+
+	// Добавляем фильтрацию по тексту песни
+	if f.Text != "" {
+		conditions += " AND songs.text ILIKE $" + strconv.Itoa(len(args)+1)
+		args = append(args, "%"+f.Text+"%")
+	}
+
+	// Добавляем фильтрацию по ссылке
+	if f.Link != "" {
+		conditions += " AND songs.link ILIKE $" + strconv.Itoa(len(args)+1)
+		args = append(args, "%"+f.Link+"%")
+	}
+
+	// Явный список песен задаёт и порядок
+	order = " ORDER BY songs.id"
+	if f.IDs != nil {
+		arg := "$" + strconv.Itoa(len(args)+1) + "::bigint[]"
+		conditions += " AND songs.id = ANY(" + arg + ")"
+		order = " ORDER BY array_position(" + arg + ", songs.id::bigint)"
+		args = append(args, pq.Array(f.IDs))
+	}
+
+	return conditions, order, args
+}
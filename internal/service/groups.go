@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/outbox"
+	"github.com/inanmasov/music-service/internal/validation"
+	"github.com/lib/pq"
+)
+
+// ListGroups возвращает страницу групп, в названии которых есть name (без учёта
+// регистра), и общее число таких групп. Пустой name не ограничивает выборку
+func (l *Library) ListGroups(ctx context.Context, name string, page, limit int) ([]*models.Group, int, error) {
+	if err := CheckPage(page, limit, false); err != nil {
+		return nil, 0, err
+	}
+
+	pool, err := l.db(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	condition := ""
+	var args []interface{}
+	if name != "" {
+		condition = " WHERE name ILIKE $1"
+		args = append(args, "%"+name+"%")
+	}
+
+	var total int
+	if err := pool.QueryRowContext(ctx, "SELECT COUNT(*) FROM groups"+condition, args...).Scan(&total); err != nil {
+		return nil, 0, internalError(ctx, "Failed to retrieve groups", err)
+	}
+
+	query := "SELECT id, name FROM groups" + condition + " ORDER BY id" +
+		" LIMIT $" + strconv.Itoa(len(args)+1) + " OFFSET $" + strconv.Itoa(len(args)+2)
+	args = append(args, limit, (page-1)*limit)
+
+	rows, err := pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, internalError(ctx, "Failed to retrieve groups", err)
+	}
+	defer rows.Close()
+
+	groups := []*models.Group{}
+	for rows.Next() {
+		var group models.Group
+		if err := rows.Scan(&group.ID, &group.Name); err != nil {
+			return nil, 0, internalError(ctx, "Failed to retrieve groups", err)
+		}
+		groups = append(groups, &group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, internalError(ctx, "Failed to retrieve groups", err)
+	}
+	return groups, total, nil
+}
+
+// GroupsByID загружает группы по ID одним запросом. Несуществующих групп в результате нет
+func (l *Library) GroupsByID(ctx context.Context, ids []int) (map[int]*models.Group, error) {
+	pool, err := l.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pool.QueryContext(ctx, "SELECT id, name FROM groups WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve groups", err)
+	}
+	defer rows.Close()
+
+	groups := make(map[int]*models.Group, len(ids))
+	for rows.Next() {
+		var group models.Group
+		if err := rows.Scan(&group.ID, &group.Name); err != nil {
+			return nil, internalError(ctx, "Failed to retrieve groups", err)
+		}
+		groups[group.ID] = &group
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(ctx, "Failed to retrieve groups", err)
+	}
+	return groups, nil
+}
+
+// GroupSongCounts считает песни групп одним запросом. У групп без песен счётчика нет
+func (l *Library) GroupSongCounts(ctx context.Context, ids []int) (map[int]int, error) {
+	pool, err := l.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pool.QueryContext(ctx,
+		"SELECT group_id, COUNT(*) FROM songs WHERE group_id = ANY($1) GROUP BY group_id", pq.Array(ids))
+	if err != nil {
+		return nil, internalError(ctx, "Failed to count songs", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int, len(ids))
+	for rows.Next() {
+		var groupID, count int
+		if err := rows.Scan(&groupID, &count); err != nil {
+			return nil, internalError(ctx, "Failed to count songs", err)
+		}
+		counts[groupID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(ctx, "Failed to count songs", err)
+	}
+	return counts, nil
+}
+
+// GroupSongs загружает страницу page песен каждой из групп ids одним запросом
+func (l *Library) GroupSongs(ctx context.Context, ids []int, page, limit int) (map[int][]Song, error) {
+	if err := CheckPage(page, limit, false); err != nil {
+		return nil, err
+	}
+
+	pool, err := l.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT group_id, id, name, song, release_date, text, link, version
+		FROM (
+			SELECT` + songColumns + `,
+				ROW_NUMBER() OVER (PARTITION BY songs.group_id ORDER BY songs.id) AS position
+			FROM songs
+			JOIN groups ON songs.group_id = groups.id
+			WHERE songs.group_id = ANY($1)
+		) ranked
+		WHERE position > $2 AND position <= $3
+		ORDER BY group_id, id`
+
+	offset := (page - 1) * limit
+	rows, err := pool.QueryContext(ctx, query, pq.Array(ids), offset, offset+limit)
+	if err != nil {
+		return nil, internalError(ctx, "Failed to retrieve songs", err)
+	}
+	defer rows.Close()
+
+	songs := make(map[int][]Song, len(ids))
+	for rows.Next() {
+		song, err := scanSong(rows)
+		if err != nil {
+			return nil, internalError(ctx, "Failed to retrieve songs", err)
+		}
+		songs[song.GroupID] = append(songs[song.GroupID], song)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(ctx, "Failed to retrieve songs", err)
+	}
+	return songs, nil
+}
+
+// RenameGroup переименовывает группу id и записывает событие group.updated.
+// Название группы входит в представление её песен, поэтому их версии увеличиваются
+// и о каждой записывается событие song.updated. Группу нельзя переименовать в
+// название, совпадающее с названием другой группы после нормализации (см. normalize_title)
+func (l *Library) RenameGroup(ctx context.Context, id int, name string) (models.Group, error) {
+	name = strings.TrimSpace(name)
+	if errs := validation.GroupName(name); len(errs) > 0 {
+		return models.Group{}, Invalid("Invalid group data", errs)
+	}
+
+	var group models.Group
+	var songIDs []int
+	err := l.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "SELECT id, name FROM groups WHERE id = $1 FOR UPDATE", id).Scan(&group.ID, &group.Name)
+		if err == sql.ErrNoRows {
+			logger.FromContext(ctx).Infof("Group with ID %d not found", id)
+			return newError(ErrNotFound, "Group not found")
+		} else if err != nil {
+			return internalError(ctx, "Failed to retrieve group", err)
+		}
+		if group.Name == name {
+			return nil
+		}
+
+		var existing int
+		err = tx.QueryRowContext(ctx,
+			"SELECT id FROM groups WHERE id <> $1 AND normalize_title(name) = normalize_title($2) LIMIT 1", id, name).Scan(&existing)
+		if err == nil {
+			return newError(ErrConflict, "Group with this name already exists")
+		} else if err != sql.ErrNoRows {
+			return internalError(ctx, "Failed to check for duplicate groups", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE groups SET name = $2 WHERE id = $1", id, name); err != nil {
+			if uniqueViolation(err) {
+				return newError(ErrConflict, "Group with this name already exists")
+			}
+			return internalError(ctx, "Failed to update group", err)
+		}
+		group.Name = name
+		if err := outbox.Record(ctx, tx, models.EventGroupUpdated, id, group); err != nil {
+			return internalError(ctx, "Failed to update group", err)
+		}
+
+		songIDs, err = touchGroupSongs(ctx, tx, id)
+		if err != nil {
+			return internalError(ctx, "Failed to update group songs", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Group{}, err
+	}
+	l.invalidate(ctx, songIDs...)
+
+	logger.FromContext(ctx).Infof("Group with ID %d renamed, %d songs updated", id, len(songIDs))
+	return group, nil
+}
+
+// touchGroupSongs увеличивает версии песен группы groupID, записывает о каждой
+// событие song.updated и возвращает их ID
+func touchGroupSongs(ctx context.Context, tx *sql.Tx, groupID int) ([]int, error) {
+	if _, err := tx.ExecContext(ctx, "UPDATE songs SET version = version + 1 WHERE group_id = $1", groupID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, selectSongsQuery+" AND songs.group_id = $1 ORDER BY songs.id", groupID)
+	if err != nil {
+		return nil, err
+	}
+	var songs []Song
+	for rows.Next() {
+		song, err := scanSong(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		songs = append(songs, song)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(songs))
+	for i, song := range songs {
+		if err := outbox.Record(ctx, tx, models.EventSongUpdated, song.ID, song.Song); err != nil {
+			return nil, err
+		}
+		ids[i] = song.ID
+	}
+	return ids, nil
+}
+
+// removeEmptyGroups удаляет группы из ids, у которых не осталось песен, и
+// записывает о каждой событие group.deleted. Группы сначала блокируются: так
+// транзакции, добавляющие в них песни, успевают завершиться, и их песни видны
+// при проверке. Песня, добавленная в удаляемую группу позже, не даст её удалить
+// (внешний ключ songs.group_id запрещает удаление)
+func removeEmptyGroups(ctx context.Context, tx *sql.Tx, ids ...int) error {
+	if _, err := tx.ExecContext(ctx,
+		"SELECT id FROM groups WHERE id = ANY($1) ORDER BY id FOR UPDATE", pq.Array(ids)); err != nil {
+		return err
+	}
+
+	query := `
+		DELETE FROM groups
+		WHERE id = ANY($1) AND NOT EXISTS (SELECT 1 FROM songs WHERE songs.group_id = groups.id)
+		RETURNING id, name`
+	rows, err := tx.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	var groups []models.Group
+	for rows.Next() {
+		var group models.Group
+		if err := rows.Scan(&group.ID, &group.Name); err != nil {
+			rows.Close()
+			return err
+		}
+		groups = append(groups, group)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		logger.FromContext(ctx).Debugf("Group %s has no songs left and is deleted", group.Name)
+		if err := outbox.Record(ctx, tx, models.EventGroupDeleted, group.ID, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uniqueViolation сообщает, что запрос нарушил ограничение уникальности (23505)
+func uniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
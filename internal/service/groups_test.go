@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/testdb"
+)
+
+// eventTypes возвращает типы записанных событий в порядке записи
+func eventTypes(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query("SELECT event_type FROM outbox_events ORDER BY id")
+	if err != nil {
+		t.Fatalf("Failed to read events: %v", err)
+	}
+	defer rows.Close()
+
+	types := []string{}
+	for rows.Next() {
+		var eventType string
+		if err := rows.Scan(&eventType); err != nil {
+			t.Fatalf("Failed to read events: %v", err)
+		}
+		types = append(types, eventType)
+	}
+	return types
+}
+
+// createSongs добавляет песни группы group и возвращает их
+func createSongs(t *testing.T, l *Library, group string, titles ...string) []Song {
+	t.Helper()
+	songs := make([]Song, len(titles))
+	for i, title := range titles {
+		song, _, err := l.CreateSong(context.Background(), models.SongInput{Group: group, Song: title})
+		if err != nil {
+			t.Fatalf("CreateSong %s: %v", title, err)
+		}
+		songs[i] = song
+	}
+	return songs
+}
+
+func TestRenameGroup(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	l := NewLibrary(testdb.Pool(db), nil)
+
+	songs := createSongs(t, l, "Muse", "Uprising", "Starlight")
+	createSongs(t, l, "Queen", "Bohemian Rhapsody")
+	if _, err := db.Exec("DELETE FROM outbox_events"); err != nil {
+		t.Fatalf("Failed to clear events: %v", err)
+	}
+
+	group, err := l.RenameGroup(ctx, songs[0].GroupID, " MUSE ")
+	if err != nil {
+		t.Fatalf("RenameGroup: %v", err)
+	}
+	if group.Name != "MUSE" {
+		t.Errorf("group name = %q, want MUSE", group.Name)
+	}
+
+	want := []string{models.EventGroupUpdated, models.EventSongUpdated, models.EventSongUpdated}
+	if types := eventTypes(t, db); !slices.Equal(types, want) {
+		t.Errorf("events = %v, want %v", types, want)
+	}
+	song, err := l.GetSong(ctx, songs[0].ID)
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if song.GroupName != "MUSE" || song.Version != songs[0].Version+1 {
+		t.Errorf("song group %q, version %d, want MUSE, %d", song.GroupName, song.Version, songs[0].Version+1)
+	}
+
+	// Название другой группы после нормализации занято
+	if _, err := l.RenameGroup(ctx, songs[0].GroupID, "queen!"); !errors.Is(err, ErrConflict) {
+		t.Errorf("RenameGroup to queen! = %v, want ErrConflict", err)
+	}
+	if _, err := l.RenameGroup(ctx, songs[0].GroupID, " "); !errors.Is(err, ErrValidation) {
+		t.Errorf("RenameGroup to an empty name = %v, want ErrValidation", err)
+	}
+	if _, err := l.RenameGroup(ctx, 1000, "Muse"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RenameGroup of a missing group = %v, want ErrNotFound", err)
+	}
+}
+
+func TestEmptyGroupIsDeleted(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	l := NewLibrary(testdb.Pool(db), nil)
+
+	songs := createSongs(t, l, "Muse", "Uprising", "Starlight")
+	groupCount := func(name string) int {
+		return testdb.Count(t, db, "SELECT COUNT(*) FROM groups WHERE name = $1", name)
+	}
+	deleted := func() int {
+		return testdb.Count(t, db, "SELECT COUNT(*) FROM outbox_events WHERE event_type = $1", models.EventGroupDeleted)
+	}
+
+	// Группа с оставшейся песней сохраняется
+	if _, err := l.DeleteSong(ctx, songs[0].ID, nil); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	if groupCount("Muse") != 1 || deleted() != 0 {
+		t.Fatal("group with a song left was deleted")
+	}
+
+	// Песня переходит в другую группу, и прежняя группа остаётся без песен
+	moved, err := l.UpdateSong(ctx, songs[1].ID, models.SongInput{Group: "Queen", Song: "Starlight"}, nil)
+	if err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	if groupCount("Muse") != 0 || deleted() != 1 {
+		t.Errorf("group Muse left without songs: %d rows, %d group.deleted events, want none and 1", groupCount("Muse"), deleted())
+	}
+
+	if _, err := l.DeleteSong(ctx, moved.ID, nil); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	if groupCount("Queen") != 0 || deleted() != 2 {
+		t.Errorf("group Queen left without songs: %d rows, %d group.deleted events, want none and 2", groupCount("Queen"), deleted())
+	}
+}
+
+func TestMergeDeletesEmptyGroups(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	l := NewLibrary(testdb.Pool(db), nil)
+
+	target := createSongs(t, l, "Muse", "Uprising")[0]
+
+	// Дубликат добавляется в обход политики дубликатов
+	var duplicate Song
+	err := l.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		duplicate, err = insertSong(ctx, tx, models.SongInput{Group: "muse", Song: "Uprising"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("insertSong: %v", err)
+	}
+
+	if _, err := l.MergeSongs(ctx, target.ID, []int{duplicate.ID}, nil); err != nil {
+		t.Fatalf("MergeSongs: %v", err)
+	}
+	if n := testdb.Count(t, db, "SELECT COUNT(*) FROM groups WHERE id = $1", duplicate.GroupID); n != 0 {
+		t.Error("group of the merged duplicate was kept")
+	}
+	if n := testdb.Count(t, db, "SELECT COUNT(*) FROM groups WHERE id = $1", target.GroupID); n != 1 {
+		t.Error("group of the target song was deleted")
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+// ImportedSong - результат импорта песни
+type ImportedSong struct {
+	// SongID - ID добавленной песни или песни, которая уже была в библиотеке
+	SongID int
+	// Created - песня добавлена, false - такая песня уже была в библиотеке
+	Created bool
+	// EnrichFailed - недостающие поля не удалось получить из внешнего API,
+	// песня добавлена без них
+	EnrichFailed bool
+}
+
+// ImportSong добавляет песню из импорта. Песня, которая уже есть в библиотеке
+// (в том числе добавленная другим запросом во время импорта), не добавляется
+// независимо от политики дубликатов. С enrich недостающие дата выхода, текст
+// и ссылка запрашиваются во внешнем API; сбой внешнего API не мешает добавлению
+func (l *Library) ImportSong(ctx context.Context, input models.SongInput, enrich bool) (ImportedSong, error) {
+	if errs := validation.NewSong(input.Group, input.Song); len(errs) > 0 {
+		return ImportedSong{}, Invalid("Invalid song data", errs)
+	}
+
+	// Песни, которые уже есть в библиотеке, пропускаются до запроса во внешний API
+	songID, err := l.FindSongByName(ctx, input.Group, input.Song)
+	if err == nil {
+		return ImportedSong{SongID: songID}, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return ImportedSong{}, err
+	}
+
+	var imported ImportedSong
+	if enrich && (input.ReleaseDate.IsZero() || input.Text == "" || input.Link == "") {
+		songDetail, err := l.SongInfo(ctx, input.Group, input.Song)
+		if err != nil {
+			logger.FromContext(ctx).Warnf("Failed to enrich imported song %s - %s: %v", input.Group, input.Song, err)
+			imported.EnrichFailed = true
+		} else {
+			if input.ReleaseDate.IsZero() {
+				input.ReleaseDate = songDetail.ReleaseDate
+			}
+			if input.Text == "" {
+				input.Text = songDetail.Text
+			}
+			if input.Link == "" {
+				input.Link = songDetail.Link
+			}
+		}
+	}
+
+	// Песню могли добавить после проверки выше: тогда она тоже уже есть в библиотеке
+	song, created, err := l.CreateSong(ctx, input)
+	var duplicate *DuplicateSongError
+	if errors.As(err, &duplicate) {
+		return ImportedSong{SongID: duplicate.SongID}, nil
+	} else if err != nil {
+		return ImportedSong{}, err
+	}
+
+	imported.SongID = song.ID
+	imported.Created = created
+	return imported, nil
+}
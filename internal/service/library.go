@@ -0,0 +1,144 @@
+// Package service содержит бизнес-логику библиотеки песен: поиск и создание групп,
+// обогащение данными внешнего API, транзакции и пагинацию куплетов. Методы
+// возвращают ошибки *Error с видом из ErrNotFound, ErrConflict, ErrValidation
+// и ErrUpstreamUnavailable и не зависят от транспорта, поэтому одна и та же
+// логика обслуживает REST, GraphQL, gRPC и фоновые задания
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/inanmasov/music-service/internal/cache"
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/db"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// Library - операции с песнями и группами библиотеки
+type Library struct {
+	pool  func() (*sql.DB, error)
+	cache func() cache.Cache
+}
+
+// NewLibrary создаёт библиотеку, получающую пул подключений функцией pool
+// (например, db.Initialize), а кеш чтения песен - функцией cache (например,
+// cache.Get, nil отключает кеш). Пул и кеш запрашиваются при каждой операции,
+// поэтому недоступная при запуске база данных не мешает создать библиотеку
+func NewLibrary(pool func() (*sql.DB, error), cache func() cache.Cache) *Library {
+	if cache == nil {
+		cache = noCache
+	}
+	return &Library{pool: pool, cache: cache}
+}
+
+// Song - песня вместе с ID её группы
+type Song struct {
+	models.Song
+	GroupID int `json:"-"`
+}
+
+// db возвращает пул подключений к базе данных
+func (l *Library) db(ctx context.Context) (*sql.DB, error) {
+	pool, err := l.pool()
+	if err != nil {
+		return nil, internalError(ctx, "Failed to connect to database", err)
+	}
+	return pool, nil
+}
+
+// CheckDatabase проверяет, что подключение к базе данных установлено. Позволяет
+// отказать в запросе целиком, а не ошибкой каждой из его операций
+func (l *Library) CheckDatabase(ctx context.Context) error {
+	_, err := l.db(ctx)
+	return err
+}
+
+// inTx выполняет fn в транзакции с уровнем изоляции и числом повторов из настроек.
+// Ошибки fn возвращаются как есть, транзакция, не прошедшая из-за конкурирующих
+// изменений после всех повторов, - как ErrConflict, прочие сбои - как внутренние ошибки
+func (l *Library) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	pool, err := l.db(ctx)
+	if err != nil {
+		return err
+	}
+
+	var fnErr error
+	err = db.RunInTx(ctx, pool, db.DefaultTxOptions(), func(tx *sql.Tx) error {
+		fnErr = fn(tx)
+		return fnErr
+	})
+	switch {
+	case err == nil || err == fnErr:
+		return err
+	case errors.Is(err, db.ErrTxRetriesExhausted):
+		logger.FromContext(ctx).Warnf("Transaction aborted by concurrent changes: %v", err)
+		return &Error{Kind: ErrConflict, Message: "Conflicting concurrent changes, try again", Err: err}
+	default:
+		return internalError(ctx, "Failed to complete transaction", err)
+	}
+}
+
+// CheckPage проверяет номер и размер страницы. Размер 0 допустим, если allowAll:
+// тогда возвращаются все элементы. Конец страницы page*limit должен помещаться в int
+func CheckPage(page, limit int, allowAll bool) error {
+	if page <= 0 || limit > 0 && page > math.MaxInt/limit {
+		return newError(ErrValidation, "Invalid page number")
+	}
+	if limit < 0 || limit == 0 && !allowAll {
+		return newError(ErrValidation, "Invalid limit number")
+	}
+	return nil
+}
+
+// VersesPerPage - число куплетов на странице текста песни, если размер страницы не задан
+const VersesPerPage = 2
+
+// Verses возвращает куплеты страницы page текста text и общее число куплетов.
+// Куплеты разделены пустой строкой, страница за пределами текста пуста
+func Verses(text string, page, limit int) ([]string, int, error) {
+	if err := CheckPage(page, limit, false); err != nil {
+		return nil, 0, err
+	}
+
+	verses := strings.Split(text, "\n\n")
+	start := min((page-1)*limit, len(verses))
+	end := min(start+limit, len(verses))
+	return verses[start:end], len(verses), nil
+}
+
+// versionRequired возвращает true, если для изменения и удаления песен
+// обязательна ожидаемая версия (заголовок If-Match в REST API)
+func versionRequired() bool {
+	return config.Get().Songs.RequireIfMatch
+}
+
+// Precondition сообщает, совпадает ли текущая версия песни с ожидаемой клиентом.
+// nil означает, что клиент не передал ожидаемую версию
+type Precondition func(current models.Song) bool
+
+// ExpectVersion возвращает условие, выполненное, если версия песни равна version
+func ExpectVersion(version int) Precondition {
+	return func(current models.Song) bool {
+		return current.Version == version
+	}
+}
+
+// checkPrecondition проверяет условие на текущую версию песни
+func checkPrecondition(ctx context.Context, current models.Song, precondition Precondition) error {
+	log := logger.FromContext(ctx)
+
+	switch {
+	case precondition == nil && versionRequired():
+		log.Debugf("Version is required for song with ID: %d", current.ID)
+		return &Error{Kind: ErrVersionRequired, Message: "Song version is required"}
+	case precondition != nil && !precondition(current):
+		log.Infof("Precondition failed for song with ID %d, current version %d", current.ID, current.Version)
+		return &Error{Kind: ErrVersionMismatch, Message: "Song has been modified"}
+	}
+	return nil
+}
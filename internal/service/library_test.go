@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestCheckPage(t *testing.T) {
+	tests := []struct {
+		page, limit int
+		allowAll    bool
+		wantErr     string
+	}{
+		{page: 1, limit: 10},
+		{page: 1, limit: 0, allowAll: true},
+		{page: math.MaxInt / 10, limit: 10},
+		{page: 1, limit: math.MaxInt},
+		{page: 0, limit: 10, wantErr: "Invalid page number"},
+		{page: -1, limit: 10, wantErr: "Invalid page number"},
+		{page: math.MaxInt/10 + 1, limit: 10, wantErr: "Invalid page number"},
+		{page: 2, limit: math.MaxInt, wantErr: "Invalid page number"},
+		{page: 1, limit: 0, wantErr: "Invalid limit number"},
+		{page: 1, limit: -1, allowAll: true, wantErr: "Invalid limit number"},
+	}
+	for _, tt := range tests {
+		err := CheckPage(tt.page, tt.limit, tt.allowAll)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("CheckPage(%d, %d, %v): %v", tt.page, tt.limit, tt.allowAll, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrValidation) || err.Error() != tt.wantErr {
+			t.Errorf("CheckPage(%d, %d, %v) = %v, want %q", tt.page, tt.limit, tt.allowAll, err, tt.wantErr)
+		}
+	}
+}
+
+func TestVerses(t *testing.T) {
+	text := "one\n\ntwo\n\nthree"
+	tests := []struct {
+		page, limit int
+		want        []string
+	}{
+		{1, 2, []string{"one", "two"}},
+		{2, 2, []string{"three"}},
+		{3, 2, []string{}},
+		{math.MaxInt / 2, 2, []string{}},
+		{1, math.MaxInt, []string{"one", "two", "three"}},
+	}
+	for _, tt := range tests {
+		verses, total, err := Verses(text, tt.page, tt.limit)
+		if err != nil || total != 3 || !slices.Equal(verses, tt.want) {
+			t.Errorf("Verses(page %d, limit %d) = %q, %d, %v, want %q of 3", tt.page, tt.limit, verses, total, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/metrics"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/tracing"
+)
+
+// musicAPIClient - HTTP-клиент внешнего API, передающий контекст трассировки
+var musicAPIClient = tracing.NewHTTPClient()
+
+// SongInfo запрашивает дату выхода, текст и ссылку песни во внешнем API.
+// Исход и длительность запроса учитываются в метриках, запрос попадает в трассировку ctx.
+// Любой сбой внешнего API возвращается как ErrUpstreamUnavailable
+func (l *Library) SongInfo(ctx context.Context, group, song string) (models.Song, error) {
+	started := time.Now()
+	songDetail, err := fetchSongInfo(ctx, group, song)
+
+	outcome := metrics.OutcomeSuccess
+	if err != nil {
+		outcome = metrics.OutcomeError
+	}
+	metrics.ObserveEnrichment(outcome, time.Since(started))
+
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get song info from external API: %v", err)
+		return models.Song{}, &Error{Kind: ErrUpstreamUnavailable, Message: "Failed to call external API", Err: err}
+	}
+	return songDetail, nil
+}
+
+// fetchSongInfo выполняет запрос /info внешнего API
+func fetchSongInfo(ctx context.Context, group, song string) (models.Song, error) {
+	// Формируем полный URL с параметрами запроса
+	url := fmt.Sprintf("%s/info?group=%s&song=%s", strings.TrimRight(config.Get().MusicAPI.URL, "/"), url.QueryEscape(group), url.QueryEscape(song))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return models.Song{}, err
+	}
+	resp, err := musicAPIClient.Do(req)
+	if err != nil {
+		return models.Song{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return models.Song{}, fmt.Errorf("external API returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return models.Song{}, fmt.Errorf("reading response body: %w", err)
+	}
+
+	var songDetail models.Song
+	if err := json.Unmarshal(body, &songDetail); err != nil {
+		return models.Song{}, fmt.Errorf("unmarshalling JSON response: %w", err)
+	}
+	return songDetail, nil
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// PlaylistTrack - трек плейлиста: группа, название песни и ссылка
+type PlaylistTrack struct {
+	Group string
+	Song  string
+	Link  string
+}
+
+// MatchTrack ищет песню трека плейлиста по группе и названию (см. FindSongByName),
+// затем по ссылке, и возвращает её ID. Если песни нет, а create, трек с группой
+// и названием добавляется в библиотеку, и created = true. Без create отсутствие
+// песни возвращается как ErrNotFound
+func (l *Library) MatchTrack(ctx context.Context, track PlaylistTrack, create bool) (songID int, created bool, err error) {
+	err = ErrNotFound
+	if track.Group != "" && track.Song != "" {
+		songID, err = l.FindSongByName(ctx, track.Group, track.Song)
+	}
+	if errors.Is(err, ErrNotFound) && track.Link != "" {
+		songID, err = l.FindSongByLink(ctx, track.Link)
+	}
+	if err == nil {
+		return songID, false, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return 0, false, err
+	}
+
+	if !create {
+		return 0, false, newError(ErrNotFound, "No matching song in the library")
+	}
+
+	input := models.SongInput{Group: track.Group, Song: track.Song, Link: track.Link}
+	song, created, err := l.CreateSong(ctx, input)
+	var duplicate *DuplicateSongError
+	if errors.As(err, &duplicate) {
+		// Песню добавили после поиска выше
+		return duplicate.SongID, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+	return song.ID, created, nil
+}
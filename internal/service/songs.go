@@ -0,0 +1,623 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/outbox"
+	"github.com/inanmasov/music-service/internal/validation"
+)
+
+// songColumns - столбцы песни вместе с ID группы в порядке, ожидаемом scanSong
+const songColumns = `
+		songs.group_id,
+		songs.id,
+		groups.name,
+		songs.song,
+		songs.release_date,
+		songs.text,
+		songs.link,
+		songs.version`
+
+// selectSongsQuery выбирает песни вместе с названием группы; к нему добавляются условия
+const selectSongsQuery = `
+	SELECT` + songColumns + `
+	FROM songs
+	JOIN groups ON songs.group_id = groups.id
+	WHERE 1=1`
+
+// countSongsQuery считает песни; к нему добавляются условия SongFilter
+const countSongsQuery = `
+	SELECT COUNT(*)
+	FROM songs
+	JOIN groups ON songs.group_id = groups.id
+	WHERE 1=1`
+
+// querier - общий интерфейс *sql.DB и *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// rowScanner - общий интерфейс *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// CountSongs считает песни, подходящие под filter
+func (l *Library) CountSongs(ctx context.Context, filter SongFilter) (int, error) {
+	if err := filter.check(); err != nil {
+		return 0, err
+	}
+
+	pool, err := l.db(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	conditions, _, args := filter.build(ctx)
+
+	var total int
+	if err := pool.QueryRowContext(ctx, countSongsQuery+conditions, args...).Scan(&total); err != nil {
+		return 0, internalError(ctx, "Failed to retrieve songs", err)
+	}
+	return total, nil
+}
+
+// EachSong передаёт fn песни страницы page, подходящие под filter, не загружая
+// их все в память. При limit 0 передаются все подходящие песни. Ошибка fn
+// прерывает обход и возвращается как есть
+func (l *Library) EachSong(ctx context.Context, filter SongFilter, page, limit int, fn func(Song) error) error {
+	if err := CheckPage(page, limit, true); err != nil {
+		return err
+	}
+	if err := filter.check(); err != nil {
+		return err
+	}
+
+	pool, err := l.db(ctx)
+	if err != nil {
+		return err
+	}
+
+	conditions, order, args := filter.build(ctx)
+	query := selectSongsQuery + conditions + order
+	if limit > 0 {
+		query += " LIMIT $" + strconv.Itoa(len(args)+1) + " OFFSET $" + strconv.Itoa(len(args)+2)
+		args = append(args, limit, (page-1)*limit)
+		logger.FromContext(ctx).Debugf("Adding pagination: LIMIT=%d, OFFSET=%d", limit, (page-1)*limit)
+	}
+
+	rows, err := pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return internalError(ctx, "Failed to retrieve songs", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		song, err := scanSong(rows)
+		if err != nil {
+			return internalError(ctx, "Failed to scan song", err)
+		}
+		if err := fn(song); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return internalError(ctx, "Failed to retrieve songs", err)
+	}
+	return nil
+}
+
+// ListSongs возвращает страницу песен, подходящих под filter, и общее число подходящих песен.
+// Страница берётся из кеша чтения, если она там есть
+func (l *Library) ListSongs(ctx context.Context, filter SongFilter, page, limit int) ([]Song, int, error) {
+	if err := CheckPage(page, limit, false); err != nil {
+		return nil, 0, err
+	}
+
+	var key string
+	cached := false
+	if store := l.cache(); store != nil {
+		key, cached = l.songsKey(ctx, store, filter, page, limit)
+	}
+	var entry songsEntry
+	if cached && l.cacheGet(ctx, cachedSongs, key, &entry) {
+		songs := make([]Song, len(entry.Songs))
+		for i, song := range entry.Songs {
+			songs[i] = song.song()
+		}
+		return songs, entry.Total, nil
+	}
+
+	total, err := l.CountSongs(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	songs := []Song{}
+	err = l.EachSong(ctx, filter, page, limit, func(song Song) error {
+		songs = append(songs, song)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if cached {
+		entry = songsEntry{Songs: make([]songEntry, len(songs)), Total: total}
+		for i, song := range songs {
+			entry.Songs[i] = cachedSongEntry(song)
+		}
+		l.cacheSet(ctx, key, entry)
+	}
+	return songs, total, nil
+}
+
+// GetSong возвращает песню по ID. Песня берётся из кеша чтения, если она там есть
+func (l *Library) GetSong(ctx context.Context, id int) (Song, error) {
+	var key string
+	cached := false
+	if store := l.cache(); store != nil {
+		key, cached = l.songKey(ctx, store, id)
+	}
+	var entry songEntry
+	if cached && l.cacheGet(ctx, cachedSong, key, &entry) {
+		return entry.song(), nil
+	}
+
+	pool, err := l.db(ctx)
+	if err != nil {
+		return Song{}, err
+	}
+
+	song, err := fetchSong(ctx, pool, id, false)
+	if err == sql.ErrNoRows {
+		logger.FromContext(ctx).Debugf("Song with ID %d not found", id)
+		return Song{}, newError(ErrNotFound, "Song not found")
+	} else if err != nil {
+		return Song{}, internalError(ctx, "Failed to retrieve song", err)
+	}
+
+	if cached {
+		l.cacheSet(ctx, key, cachedSongEntry(song))
+	}
+	return song, nil
+}
+
+// AddSong добавляет песню группы group с названием name. Дата выхода, текст
+// и ссылка запрашиваются во внешнем API. Если такая песня уже есть, результат
+// зависит от политики SONGS_DUPLICATE_POLICY: ErrDuplicateSong, существующая
+// песня (created = false) или ещё одна новая песня
+func (l *Library) AddSong(ctx context.Context, group, name string) (Song, bool, error) {
+	log := logger.FromContext(ctx)
+
+	group = strings.TrimSpace(group)
+	name = strings.TrimSpace(name)
+	if errs := validation.NewSong(group, name); len(errs) > 0 {
+		return Song{}, false, Invalid("Invalid song data", errs)
+	}
+
+	log.Debugf("Adding song - Group: %s, Song: %s", group, name)
+
+	// Дубликат ищется до запроса во внешний API, чтобы не запрашивать данные
+	// песни, которая не будет добавлена. Окончательно он проверяется в транзакции
+	if policy := duplicatePolicy(); policy != config.DuplicateAllow {
+		existing, err := l.FindSongByName(ctx, group, name)
+		switch {
+		case err == nil && policy == config.DuplicateReject:
+			log.Infof("Song %s - %s already exists with ID %d", group, name, existing)
+			return Song{}, false, duplicateError(existing)
+		case err == nil:
+			song, err := l.GetSong(ctx, existing)
+			return song, false, err
+		case !errors.Is(err, ErrNotFound):
+			return Song{}, false, err
+		}
+	}
+
+	songDetail, err := l.SongInfo(ctx, group, name)
+	if err != nil {
+		return Song{}, false, err
+	}
+
+	log.Debugf("Retrieved song details from external API: %+v", songDetail)
+
+	song, created, err := l.createSong(ctx, models.SongInput{
+		Group:       group,
+		Song:        name,
+		ReleaseDate: songDetail.ReleaseDate,
+		Text:        songDetail.Text,
+		Link:        songDetail.Link,
+	})
+	if err != nil {
+		return Song{}, false, err
+	}
+
+	if created {
+		log.Infof("Song with ID %d added", song.ID)
+	}
+	return song, created, nil
+}
+
+// CreateSong добавляет песню с уже известными данными, например, из импорта
+// или плейлиста. Группа и название обязательны, остальные поля - нет.
+// Дубликаты обрабатываются по политике SONGS_DUPLICATE_POLICY, как в AddSong:
+// ErrDuplicateSong или существующая песня с created = false
+func (l *Library) CreateSong(ctx context.Context, input models.SongInput) (Song, bool, error) {
+	if errs := validation.SongInput(input); len(errs) > 0 {
+		return Song{}, false, Invalid("Invalid song data", errs)
+	}
+	return l.createSong(ctx, input)
+}
+
+// NewSongInput собирает и проверяет полное представление песни. Дата выхода
+// передаётся в формате YYYY-MM-DD, пустая строка означает песню без даты
+func NewSongInput(group, song, releaseDate, text, link string) (models.SongInput, error) {
+	input := models.SongInput{
+		Group: strings.TrimSpace(group),
+		Song:  strings.TrimSpace(song),
+		Text:  text,
+		Link:  strings.TrimSpace(link),
+	}
+
+	var errs validation.Errors
+	date, err := time.Parse(time.DateOnly, releaseDate)
+	if err != nil && releaseDate != "" {
+		errs = append(errs, models.FieldError{Field: "releaseDate", Code: validation.CodeInvalidType, Message: "must be a YYYY-MM-DD date"})
+	} else {
+		input.ReleaseDate = date
+		errs = validation.SongInput(input)
+	}
+	if len(errs) > 0 {
+		return models.SongInput{}, Invalid("Invalid song data", errs)
+	}
+	return input, nil
+}
+
+// UpdateSong полностью заменяет данные песни. precondition - ожидаемая версия песни
+func (l *Library) UpdateSong(ctx context.Context, id int, input models.SongInput, precondition Precondition) (Song, error) {
+	return l.ModifySong(ctx, id, precondition, func(models.Song) (models.SongInput, error) {
+		return input, nil
+	})
+}
+
+// ModifySong сохраняет новое состояние песни, построенное функцией change по
+// текущему. Песня блокируется до конца транзакции, поэтому change видит
+// последнюю версию. Ошибка change возвращается как есть. При политике
+// дубликатов reject песню нельзя переименовать в уже существующую
+func (l *Library) ModifySong(ctx context.Context, id int, precondition Precondition, change func(current models.Song) (models.SongInput, error)) (Song, error) {
+	song, err := l.changeSong(ctx, id, precondition, func(tx *sql.Tx, current Song) (Song, error) {
+		return modifySong(ctx, tx, current, change)
+	})
+	if err != nil {
+		return Song{}, err
+	}
+
+	logger.FromContext(ctx).Infof("Song with ID %d updated successfully", id)
+	return song, nil
+}
+
+// DeleteSong удаляет песню и возвращает её последнее состояние. precondition -
+// ожидаемая версия песни
+func (l *Library) DeleteSong(ctx context.Context, id int, precondition Precondition) (Song, error) {
+	song, err := l.changeSong(ctx, id, precondition, func(tx *sql.Tx, current Song) (Song, error) {
+		return current, deleteSong(ctx, tx, current)
+	})
+	if err != nil {
+		return Song{}, err
+	}
+
+	logger.FromContext(ctx).Infof("Song with ID %d deleted successfully", id)
+	return song, nil
+}
+
+// FindSongByName ищет песню группы по нормализованным названиям группы и песни
+// (см. normalize_title) и возвращает её ID
+func (l *Library) FindSongByName(ctx context.Context, group, song string) (int, error) {
+	return l.findSongID(ctx, findDuplicateQuery, group, song, 0)
+}
+
+// FindSongByLink ищет песню по ссылке и возвращает её ID
+func (l *Library) FindSongByLink(ctx context.Context, link string) (int, error) {
+	return l.findSongID(ctx, "SELECT id FROM songs WHERE link = $1 ORDER BY id LIMIT 1", link)
+}
+
+// findSongID возвращает ID песни, найденной запросом query
+func (l *Library) findSongID(ctx context.Context, query string, args ...interface{}) (int, error) {
+	pool, err := l.db(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = pool.QueryRowContext(ctx, query, args...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, newError(ErrNotFound, "Song not found")
+	} else if err != nil {
+		return 0, internalError(ctx, "Failed to search for the song", err)
+	}
+	return id, nil
+}
+
+// changeSong блокирует песню, проверяет precondition и применяет change в одной
+// транзакции. Ошибка change возвращается как есть
+func (l *Library) changeSong(ctx context.Context, id int, precondition Precondition, change func(tx *sql.Tx, current Song) (Song, error)) (Song, error) {
+	var song Song
+	err := l.inTx(ctx, func(tx *sql.Tx) error {
+		current, err := lockSong(ctx, tx, id, precondition)
+		if err != nil {
+			return err
+		}
+
+		song, err = change(tx, current)
+		return err
+	})
+	if err != nil {
+		return Song{}, err
+	}
+	l.invalidate(ctx, id)
+	return song, nil
+}
+
+// createSong добавляет песню и событие song.created в одной транзакции. Если
+// политика дубликатов не allow и такая песня уже есть, возвращается
+// ErrDuplicateSong или существующая песня с created = false
+func (l *Library) createSong(ctx context.Context, input models.SongInput) (Song, bool, error) {
+	var song Song
+	var created bool
+	err := l.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		song, created, err = addSong(ctx, tx, input)
+		return err
+	})
+	if err != nil {
+		return Song{}, false, err
+	}
+	if created {
+		l.invalidate(ctx)
+	}
+	return song, created, nil
+}
+
+// lockSong загружает песню и блокирует её до конца транзакции, чтобы её версия
+// не изменилась, затем проверяет precondition
+func lockSong(ctx context.Context, tx *sql.Tx, id int, precondition Precondition) (Song, error) {
+	current, err := fetchSong(ctx, tx, id, true)
+	if err == sql.ErrNoRows {
+		logger.FromContext(ctx).Infof("Song with ID %d not found", id)
+		return Song{}, newError(ErrNotFound, "Song not found")
+	} else if err != nil {
+		return Song{}, internalError(ctx, "Failed to retrieve song", err)
+	}
+
+	if err := checkPrecondition(ctx, current.Song, precondition); err != nil {
+		return Song{}, err
+	}
+	return current, nil
+}
+
+// addSong добавляет песню в транзакции tx с учётом политики дубликатов. Если
+// такая песня уже есть, возвращается ErrDuplicateSong или существующая песня
+// с created = false
+func addSong(ctx context.Context, tx *sql.Tx, input models.SongInput) (Song, bool, error) {
+	policy := duplicatePolicy()
+	if policy != config.DuplicateAllow {
+		existing, err := lockDuplicate(ctx, tx, input.Group, input.Song, 0)
+		if err != nil {
+			return Song{}, false, internalError(ctx, "Failed to check for duplicate songs", err)
+		}
+		if existing != 0 && policy == config.DuplicateReject {
+			return Song{}, false, duplicateError(existing)
+		}
+		if existing != 0 {
+			song, err := fetchSong(ctx, tx, existing, false)
+			if err != nil {
+				return Song{}, false, internalError(ctx, "Failed to retrieve song", err)
+			}
+			return song, false, nil
+		}
+	}
+
+	song, err := insertSong(ctx, tx, input)
+	if err != nil {
+		return Song{}, false, internalError(ctx, "Failed to insert song into database", err)
+	}
+	return song, true, nil
+}
+
+// modifySong сохраняет состояние заблокированной песни current, построенное
+// функцией change. Ошибка change возвращается как есть
+func modifySong(ctx context.Context, tx *sql.Tx, current Song, change func(current models.Song) (models.SongInput, error)) (Song, error) {
+	input, err := change(current.Song)
+	if err != nil {
+		return Song{}, err
+	}
+	if errs := validation.SongInput(input); len(errs) > 0 {
+		return Song{}, Invalid("Invalid song data", errs)
+	}
+
+	// Дубликаты проверяются, только если меняются группа или название: песни,
+	// добавленные до включения политики, должны оставаться изменяемыми
+	if duplicatePolicy() == config.DuplicateReject && renamed(current.Song, input) {
+		existing, err := lockDuplicate(ctx, tx, input.Group, input.Song, current.ID)
+		if err != nil {
+			return Song{}, internalError(ctx, "Failed to check for duplicate songs", err)
+		}
+		if existing != 0 {
+			return Song{}, duplicateError(existing)
+		}
+	}
+
+	song, err := saveSong(ctx, tx, current.ID, input)
+	if err != nil {
+		return Song{}, internalError(ctx, "Failed to update song", err)
+	}
+	if song.GroupID != current.GroupID {
+		if err := removeEmptyGroups(ctx, tx, current.GroupID); err != nil {
+			return Song{}, internalError(ctx, "Failed to update song", err)
+		}
+	}
+	return song, nil
+}
+
+// deleteSong удаляет заблокированную песню и записывает событие song.deleted.
+// Группа, у которой не осталось песен, удаляется (см. removeEmptyGroups)
+func deleteSong(ctx context.Context, tx *sql.Tx, current Song) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM songs WHERE id = $1", current.ID); err != nil {
+		return internalError(ctx, "Failed to delete song from database", err)
+	}
+	if err := outbox.Record(ctx, tx, models.EventSongDeleted, current.ID, current.Song); err != nil {
+		return internalError(ctx, "Failed to delete song from database", err)
+	}
+	if err := removeEmptyGroups(ctx, tx, current.GroupID); err != nil {
+		return internalError(ctx, "Failed to delete song from database", err)
+	}
+	return nil
+}
+
+// fetchSong загружает песню по ID. Если forUpdate, строка песни блокируется
+// до конца транзакции. Если песни нет, возвращает sql.ErrNoRows
+func fetchSong(ctx context.Context, q querier, id int, forUpdate bool) (Song, error) {
+	query := selectSongsQuery + " AND songs.id = $1"
+	if forUpdate {
+		query += " FOR UPDATE OF songs"
+	}
+
+	return scanSong(q.QueryRowContext(ctx, query, id))
+}
+
+// scanSong считывает песню из строки со столбцами songColumns. Пустые дата, текст
+// и ссылка (NULL) превращаются в нулевые значения
+func scanSong(row rowScanner) (Song, error) {
+	var song Song
+	var releaseDate sql.NullTime
+	var text, link sql.NullString
+	err := row.Scan(&song.GroupID, &song.ID, &song.GroupName, &song.SongName, &releaseDate, &text, &link, &song.Version)
+	if err != nil {
+		return Song{}, err
+	}
+
+	song.ReleaseDate = releaseDate.Time
+	song.Text = text.String
+	song.Link = link.String
+	return song, nil
+}
+
+// findOrCreateGroup возвращает ID группы с указанным именем, создавая её при необходимости.
+// О новой группе записывается событие group.created. Найденная группа блокируется
+// от удаления до конца транзакции (см. removeEmptyGroups)
+func findOrCreateGroup(ctx context.Context, tx *sql.Tx, name string) (int, error) {
+	log := logger.FromContext(ctx)
+
+	var groupID int
+	err := tx.QueryRowContext(ctx, "SELECT id FROM groups WHERE name = $1 FOR KEY SHARE", name).Scan(&groupID)
+	if err == sql.ErrNoRows {
+		log.Debugf("Group not found, adding new group: %s", name)
+		err = tx.QueryRowContext(ctx, "INSERT INTO groups (name) VALUES ($1) RETURNING id", name).Scan(&groupID)
+		if err == nil {
+			err = outbox.Record(ctx, tx, models.EventGroupCreated, groupID, models.Group{ID: groupID, Name: name})
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return groupID, nil
+}
+
+// insertSong добавляет новую песню, создавая при необходимости группу, и записывает
+// событие song.created. Пустые дата, текст и ссылка сохраняются как NULL
+func insertSong(ctx context.Context, tx *sql.Tx, input models.SongInput) (Song, error) {
+	groupID, err := findOrCreateGroup(ctx, tx, input.Group)
+	if err != nil {
+		return Song{}, err
+	}
+
+	query := `
+		INSERT INTO songs (group_id, song, release_date, text, link)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+		RETURNING id, version
+		`
+
+	song := newSong(groupID, input)
+	err = tx.QueryRowContext(ctx, query, groupID, input.Song, nullDate(input.ReleaseDate), input.Text, input.Link).Scan(&song.ID, &song.Version)
+	if err != nil {
+		return Song{}, err
+	}
+
+	if err := outbox.Record(ctx, tx, models.EventSongCreated, song.ID, song.Song); err != nil {
+		return Song{}, err
+	}
+
+	return song, nil
+}
+
+// saveSong полностью заменяет данные песни, увеличивает её версию и записывает
+// событие song.updated. Пустые дата, текст и ссылка сохраняются как NULL, как в insertSong
+func saveSong(ctx context.Context, tx *sql.Tx, id int, input models.SongInput) (Song, error) {
+	groupID, err := findOrCreateGroup(ctx, tx, input.Group)
+	if err != nil {
+		return Song{}, err
+	}
+
+	query := `
+		UPDATE songs
+		SET group_id = $1, song = $2, release_date = $3, text = NULLIF($4, ''), link = NULLIF($5, ''), version = version + 1
+		WHERE id = $6
+		RETURNING version
+		`
+
+	song := newSong(groupID, input)
+	song.ID = id
+	err = tx.QueryRowContext(ctx, query, groupID, input.Song, nullDate(input.ReleaseDate), input.Text, input.Link, id).Scan(&song.Version)
+	if err != nil {
+		return Song{}, err
+	}
+
+	if err := outbox.Record(ctx, tx, models.EventSongUpdated, id, song.Song); err != nil {
+		return Song{}, err
+	}
+
+	return song, nil
+}
+
+// nullDate возвращает значение даты для запроса: nil (NULL) для нулевой даты
+func nullDate(date time.Time) interface{} {
+	if date.IsZero() {
+		return nil
+	}
+	return date
+}
+
+// newSong возвращает песню группы groupID с данными input
+func newSong(groupID int, input models.SongInput) Song {
+	return Song{
+		Song: models.Song{
+			GroupName:   input.Group,
+			SongName:    input.Song,
+			ReleaseDate: input.ReleaseDate,
+			Text:        input.Text,
+			Link:        input.Link,
+		},
+		GroupID: groupID,
+	}
+}
+
+// SongToInput возвращает изменяемые поля песни
+func SongToInput(song models.Song) models.SongInput {
+	return models.SongInput{
+		Group:       song.GroupName,
+		Song:        song.SongName,
+		ReleaseDate: song.ReleaseDate,
+		Text:        song.Text,
+		Link:        song.Link,
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/testdb"
+)
+
+// raise завершает запрос в транзакции tx ошибкой PostgreSQL с кодом code
+func raise(ctx context.Context, tx *sql.Tx, code string) error {
+	_, err := tx.ExecContext(ctx, `DO $$ BEGIN RAISE EXCEPTION 'forced failure' USING ERRCODE = '`+code+`'; END $$`)
+	return err
+}
+
+// checkEmpty проверяет, что транзакции не оставили ни песен, ни групп, ни событий
+func checkEmpty(t *testing.T, db *sql.DB) {
+	t.Helper()
+	for _, table := range []string{"songs", "groups", "outbox_events"} {
+		if n := testdb.Count(t, db, "SELECT COUNT(*) FROM "+table); n != 0 {
+			t.Errorf("%d rows in %s, want none", n, table)
+		}
+	}
+}
+
+func TestCreateSongRollsBackPartialChanges(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	l := NewLibrary(testdb.Pool(db), nil)
+
+	// Группа и событие group.created уже записаны, когда вставка песни не проходит
+	if _, err := db.Exec("ALTER TABLE songs ADD CONSTRAINT test_song_fails CHECK (song <> 'Fail')"); err != nil {
+		t.Fatalf("Failed to add constraint: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("ALTER TABLE songs DROP CONSTRAINT IF EXISTS test_song_fails") })
+
+	_, _, err := l.CreateSong(ctx, models.SongInput{Group: "Muse", Song: "Fail"})
+	if err == nil || errors.Is(err, ErrValidation) {
+		t.Fatalf("CreateSong = %v, want the error of the failing insert", err)
+	}
+	checkEmpty(t, db)
+}
+
+func TestInTxRollsBackOnPanic(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	l := NewLibrary(testdb.Pool(db), nil)
+
+	func() {
+		defer func() {
+			if value := recover(); value != "boom" {
+				t.Errorf("recovered %v, want the panic of fn", value)
+			}
+		}()
+		l.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := insertSong(ctx, tx, models.SongInput{Group: "Muse", Song: "Uprising"}); err != nil {
+				t.Errorf("insertSong: %v", err)
+			}
+			panic("boom")
+		})
+	}()
+	checkEmpty(t, db)
+}
+
+func TestInTxRetriesConcurrencyFailures(t *testing.T) {
+	for _, code := range []string{"40001", "40P01"} {
+		t.Run(code, func(t *testing.T) {
+			db := testdb.Open(t)
+			ctx := context.Background()
+			l := NewLibrary(testdb.Pool(db), nil)
+
+			attempts := 0
+			err := l.inTx(ctx, func(tx *sql.Tx) error {
+				attempts++
+				if _, err := insertSong(ctx, tx, models.SongInput{Group: "Muse", Song: "Uprising"}); err != nil {
+					return err
+				}
+				if attempts == 1 {
+					return raise(ctx, tx, code)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("inTx: %v", err)
+			}
+			if attempts != 2 {
+				t.Errorf("%d attempts, want 2", attempts)
+			}
+			// Первая попытка откатилась целиком, остались только изменения второй
+			if n := testdb.Count(t, db, "SELECT COUNT(*) FROM songs"); n != 1 {
+				t.Errorf("%d songs, want 1", n)
+			}
+			if n := testdb.Count(t, db, "SELECT COUNT(*) FROM outbox_events"); n != 2 {
+				t.Errorf("%d events, want group.created and song.created", n)
+			}
+		})
+	}
+}
+
+func TestInTxExhaustedRetriesAreConflicts(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	l := NewLibrary(testdb.Pool(db), nil)
+
+	err := l.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := insertSong(ctx, tx, models.SongInput{Group: "Muse", Song: "Uprising"}); err != nil {
+			return err
+		}
+		return raise(ctx, tx, "40001")
+	})
+	var svcErr *Error
+	if !errors.Is(err, ErrConflict) || !errors.As(err, &svcErr) {
+		t.Fatalf("inTx = %v, want ErrConflict", err)
+	}
+	checkEmpty(t, db)
+}
+
+func TestInTxReturnsErrorsOfFn(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	l := NewLibrary(testdb.Pool(db), nil)
+
+	attempts := 0
+	err := l.inTx(ctx, func(tx *sql.Tx) error {
+		attempts++
+		if _, err := insertSong(ctx, tx, models.SongInput{Group: "Muse", Song: "Uprising"}); err != nil {
+			return err
+		}
+		return newError(ErrNotFound, "Song not found")
+	})
+	if !errors.Is(err, ErrNotFound) || attempts != 1 {
+		t.Errorf("inTx = %v after %d attempts, want ErrNotFound after 1", err, attempts)
+	}
+	checkEmpty(t, db)
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/config"
+	"github.com/inanmasov/music-service/internal/logger"
+	"github.com/inanmasov/music-service/internal/models"
+	"github.com/inanmasov/music-service/internal/outbox"
+	"github.com/inanmasov/music-service/internal/validation"
+	"github.com/lib/pq"
+)
+
+// webhookColumns - столбцы вебхука в порядке, ожидаемом scanWebhook
+const webhookColumns = "id, url, event_types, active, created_at"
+
+// RegisterWebhook проверяет и сохраняет вебхук. Если секрет не задан, он
+// генерируется. Секрет возвращается только в результате этого вызова
+func (l *Library) RegisterWebhook(ctx context.Context, input models.WebhookInput) (models.Webhook, error) {
+	input.URL = strings.TrimSpace(input.URL)
+	if errs := validation.WebhookInput(input); len(errs) > 0 {
+		return models.Webhook{}, Invalid("Invalid webhook data", errs)
+	}
+
+	if input.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return models.Webhook{}, internalError(ctx, "Failed to generate webhook secret", err)
+		}
+		input.Secret = secret
+	}
+	if input.EventTypes == nil {
+		input.EventTypes = []string{}
+	}
+
+	pool, err := l.db(ctx)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	query := `
+		INSERT INTO webhooks (url, secret, event_types)
+		VALUES ($1, $2, $3)
+		RETURNING ` + webhookColumns
+
+	webhook, err := scanWebhook(pool.QueryRowContext(ctx, query, input.URL, input.Secret, pq.Array(input.EventTypes)))
+	if err != nil {
+		return models.Webhook{}, internalError(ctx, "Failed to save webhook", err)
+	}
+	webhook.Secret = input.Secret
+
+	logger.FromContext(ctx).Infof("Webhook %d registered for %s", webhook.ID, webhook.URL)
+	return webhook, nil
+}
+
+// ListWebhooks возвращает страницу вебхуков без секретов и общее число вебхуков
+func (l *Library) ListWebhooks(ctx context.Context, page, limit int) ([]models.Webhook, int, error) {
+	if err := CheckPage(page, limit, false); err != nil {
+		return nil, 0, err
+	}
+
+	pool, err := l.db(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := pool.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhooks").Scan(&total); err != nil {
+		return nil, 0, internalError(ctx, "Failed to retrieve webhooks", err)
+	}
+
+	rows, err := pool.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY id LIMIT $1 OFFSET $2", limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, internalError(ctx, "Failed to retrieve webhooks", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, 0, internalError(ctx, "Failed to retrieve webhooks", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, internalError(ctx, "Failed to retrieve webhooks", err)
+	}
+	return webhooks, total, nil
+}
+
+// TestWebhook синхронно отправляет вебхуку id подписанное событие webhook.test.
+// Событие не сохраняется и не повторяется, сбой доставки описывается в результате
+func (l *Library) TestWebhook(ctx context.Context, id int) (models.WebhookTestResult, error) {
+	pool, err := l.db(ctx)
+	if err != nil {
+		return models.WebhookTestResult{}, err
+	}
+
+	var url, secret string
+	err = pool.QueryRowContext(ctx, "SELECT url, secret FROM webhooks WHERE id = $1", id).Scan(&url, &secret)
+	if err == sql.ErrNoRows {
+		logger.FromContext(ctx).Infof("Webhook with ID %d not found", id)
+		return models.WebhookTestResult{}, newError(ErrNotFound, "Webhook not found")
+	} else if err != nil {
+		return models.WebhookTestResult{}, internalError(ctx, "Failed to retrieve webhook", err)
+	}
+
+	data, _ := json.Marshal(map[string]int{"webhookId": id})
+	event := models.Event{Type: models.EventWebhookTest, CreatedAt: time.Now().UTC(), Data: data}
+
+	cfg := config.Get().Webhooks
+	client := outbox.NewClient(cfg.Timeout.Std(), cfg.AllowPrivateNetworks)
+	started := time.Now()
+	statusCode, err := outbox.Send(ctx, client, url, secret, 0, event)
+	result := models.WebhookTestResult{
+		Delivered:  err == nil,
+		StatusCode: statusCode,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Test delivery to webhook %d failed: %v", id, err)
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+// DisableWebhook отключает вебхук id. Ожидающие доставки сохраняются, но не
+// отправляются, пока вебхук отключён
+func (l *Library) DisableWebhook(ctx context.Context, id int) (models.Webhook, error) {
+	pool, err := l.db(ctx)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	query := "UPDATE webhooks SET active = FALSE WHERE id = $1 RETURNING " + webhookColumns
+	webhook, err := scanWebhook(pool.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		logger.FromContext(ctx).Infof("Webhook with ID %d not found", id)
+		return models.Webhook{}, newError(ErrNotFound, "Webhook not found")
+	} else if err != nil {
+		return models.Webhook{}, internalError(ctx, "Failed to update webhook", err)
+	}
+
+	logger.FromContext(ctx).Infof("Webhook %d disabled", id)
+	return webhook, nil
+}
+
+// ListDeadLetters возвращает страницу доставок, исчерпавших все попытки, от новых
+// к старым, и их общее число. webhookID, отличный от 0, оставляет доставки одного вебхука
+func (l *Library) ListDeadLetters(ctx context.Context, webhookID, page, limit int) ([]models.WebhookDelivery, int, error) {
+	if err := CheckPage(page, limit, false); err != nil {
+		return nil, 0, err
+	}
+
+	pool, err := l.db(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter := " WHERE d.status = 'dead'"
+	args := []interface{}{}
+	if webhookID != 0 {
+		filter += " AND d.webhook_id = $1"
+		args = append(args, webhookID)
+	}
+
+	var total int
+	err = pool.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_deliveries d"+filter, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, internalError(ctx, "Failed to retrieve deliveries", err)
+	}
+
+	query := `
+		SELECT d.id, d.webhook_id, d.event_id, e.event_type, d.status, d.attempts,
+			d.last_status_code, d.last_error, d.created_at
+		FROM webhook_deliveries d
+		JOIN outbox_events e ON e.id = d.event_id` + filter +
+		" ORDER BY d.id DESC LIMIT $" + strconv.Itoa(len(args)+1) + " OFFSET $" + strconv.Itoa(len(args)+2)
+	args = append(args, limit, (page-1)*limit)
+
+	rows, err := pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, internalError(ctx, "Failed to retrieve deliveries", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var statusCode sql.NullInt64
+		var lastError sql.NullString
+		err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType,
+			&delivery.Status, &delivery.Attempts, &statusCode, &lastError, &delivery.CreatedAt)
+		if err != nil {
+			return nil, 0, internalError(ctx, "Failed to retrieve deliveries", err)
+		}
+		delivery.LastStatusCode = int(statusCode.Int64)
+		delivery.LastError = lastError.String
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, internalError(ctx, "Failed to retrieve deliveries", err)
+	}
+	return deliveries, total, nil
+}
+
+// RetryDeadLetter возвращает доставку id из dead-letter в очередь на ближайший проход
+func (l *Library) RetryDeadLetter(ctx context.Context, id int64) error {
+	pool, err := l.db(ctx)
+	if err != nil {
+		return err
+	}
+
+	found, err := outbox.RetryDead(ctx, pool, id)
+	if err != nil {
+		return internalError(ctx, "Failed to update delivery", err)
+	}
+	if !found {
+		logger.FromContext(ctx).Infof("Dead-lettered delivery %d not found", id)
+		return newError(ErrNotFound, "Dead-lettered delivery not found")
+	}
+
+	logger.FromContext(ctx).Infof("Delivery %d scheduled for retry", id)
+	return nil
+}
+
+// scanWebhook считывает вебхук из строки со столбцами webhookColumns
+func scanWebhook(row rowScanner) (models.Webhook, error) {
+	var webhook models.Webhook
+	var eventTypes pq.StringArray
+	err := row.Scan(&webhook.ID, &webhook.URL, &eventTypes, &webhook.Active, &webhook.CreatedAt)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook.EventTypes = []string(eventTypes)
+	if webhook.EventTypes == nil {
+		webhook.EventTypes = []string{}
+	}
+	return webhook, nil
+}
+
+// newWebhookSecret генерирует случайный секрет вебхука
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,177 @@
+package tracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// dbSystem - значение атрибута db.system для спанов SQL
+const dbSystem = "postgresql"
+
+// WrapConnector оборачивает коннектор драйвера базы данных так, что каждый
+// SQL-запрос и каждая транзакция получают спан. Родительский спан берётся из
+// контекста запроса, поэтому для трассировки нужно использовать *Context методы sql.DB
+func WrapConnector(connector driver.Connector) driver.Connector {
+	return &tracedConnector{connector: connector}
+}
+
+type tracedConnector struct {
+	connector driver.Connector
+}
+
+func (t *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := t.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{conn: conn}, nil
+}
+
+func (t *tracedConnector) Driver() driver.Driver {
+	return t.connector.Driver()
+}
+
+// tracedConn передаёт вызовы подключению драйвера, оборачивая запросы в спаны
+type tracedConn struct {
+	conn driver.Conn
+}
+
+func (t *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return t.conn.Prepare(query)
+}
+
+func (t *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := t.conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return t.conn.Prepare(query)
+}
+
+func (t *tracedConn) Close() error {
+	return t.conn.Close()
+}
+
+func (t *tracedConn) Begin() (driver.Tx, error) {
+	return t.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (t *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	beginner, ok := t.conn.(driver.ConnBeginTx)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	// Спан транзакции длится от BEGIN до COMMIT или ROLLBACK
+	ctx, span := Tracer().Start(ctx, "sql transaction",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", dbSystem)),
+	)
+	tx, err := beginner.BeginTx(ctx, opts)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return &tracedTx{tx: tx, span: span}, nil
+}
+
+func (t *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := t.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := startQuerySpan(ctx, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	endSpan(span, err)
+	return rows, err
+}
+
+func (t *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := t.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := startQuerySpan(ctx, query)
+	result, err := execer.ExecContext(ctx, query, args)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := t.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (t *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := t.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (t *tracedConn) IsValid() bool {
+	if validator, ok := t.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// tracedTx завершает спан транзакции при её фиксации или откате
+type tracedTx struct {
+	tx   driver.Tx
+	span trace.Span
+}
+
+func (t *tracedTx) Commit() error {
+	err := t.tx.Commit()
+	t.span.SetAttributes(attribute.String("db.transaction.outcome", "commit"))
+	endSpan(t.span, err)
+	return err
+}
+
+func (t *tracedTx) Rollback() error {
+	err := t.tx.Rollback()
+	t.span.SetAttributes(attribute.String("db.transaction.outcome", "rollback"))
+	endSpan(t.span, err)
+	return err
+}
+
+// startQuerySpan начинает спан SQL-запроса. Имя спана - SQL-операция (SELECT, INSERT...),
+// текст запроса сохраняется с плейсхолдерами, без значений параметров
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := sqlOperation(query)
+	return Tracer().Start(ctx, "sql "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", dbSystem),
+			attribute.String("db.operation", operation),
+			attribute.String("db.statement", strings.TrimSpace(query)),
+		),
+	)
+}
+
+// sqlOperation возвращает первое ключевое слово запроса
+func sqlOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// endSpan завершает спан, отмечая ошибку, если она есть
+func endSpan(span trace.Span, err error) {
+	if err != nil && err != driver.ErrSkip {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортёры трассировок, выбираемые переменной OTEL_TRACES_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// tracerName - имя инструментирующей библиотеки для спанов сервиса
+const tracerName = "github.com/inanmasov/music-service"
+
+// Init настраивает трассировку и возвращает функцию, отправляющую оставшиеся спаны
+// при остановке сервиса.
+//
+// exporterName выбирает экспортёр: otlp (адрес коллектора задаётся стандартными
+// OTEL_EXPORTER_OTLP_* переменными), stdout или none. Контекст трассировки
+// W3C traceparent принимается и передаётся дальше в любом случае
+func Init(ctx context.Context, exporterName, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporterName = strings.ToLower(exporterName)
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout, "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q: use otlp, stdout or none", exporterName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer возвращает трассировщик сервиса
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// NewHTTPClient возвращает HTTP-клиент, который создаёт спан для каждого исходящего
+// запроса и передаёт контекст трассировки в заголовке traceparent
+func NewHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: otelhttp.NewTransport(http.DefaultTransport,
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return r.Method + " " + r.URL.Host + r.URL.Path
+			}),
+		),
+	}
+}
@@ -0,0 +1,297 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// Машиночитаемые коды ошибок полей
+const (
+	CodeRequired          = "required"
+	CodeTooLong           = "too_long"
+	CodeInvalidCharacters = "invalid_characters"
+	CodeInvalidURL        = "invalid_url"
+	CodeUnsupportedScheme = "unsupported_scheme"
+	CodeOutOfRange        = "out_of_range"
+	CodeInvalidType       = "invalid_type"
+	CodeUnknownField      = "unknown_field"
+	CodeUnknownEventType  = "unknown_event_type"
+)
+
+const (
+	// MaxNameLength - максимальная длина названия группы и песни (VARCHAR(255) в БД)
+	MaxNameLength = 255
+	// MaxLinkLength - максимальная длина ссылки (VARCHAR(255) в БД)
+	MaxLinkLength = 255
+	// MaxTextLength - максимальная длина текста песни в символах
+	MaxTextLength = 20000
+)
+
+// MinReleaseDate - самая ранняя допустимая дата выхода песни (первая известная звукозапись)
+var MinReleaseDate = time.Date(1860, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// allowedLinkSchemes - схемы, допустимые в ссылке на песню
+var allowedLinkSchemes = map[string]bool{"http": true, "https": true}
+
+// Errors - список ошибок по полям. Реализует error, чтобы его можно было
+// возвращать наравне с прочими ошибками.
+type Errors []models.FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// add добавляет ошибку поля в список
+func (e *Errors) add(field, code, message string) {
+	*e = append(*e, models.FieldError{Field: field, Code: code, Message: message})
+}
+
+// has сообщает, есть ли в списке ошибка поля field
+func (e Errors) has(field string) bool {
+	for _, fieldErr := range e {
+		if fieldErr.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSong проверяет группу и название песни при её добавлении
+func NewSong(group, song string) Errors {
+	var errs Errors
+	checkName(&errs, "group", group)
+	checkName(&errs, "song", song)
+	return errs
+}
+
+// GroupName проверяет название группы при её переименовании
+func GroupName(name string) Errors {
+	var errs Errors
+	checkName(&errs, "name", name)
+	return errs
+}
+
+// SongInput проверяет все поля полного представления песни. Группа и название
+// обязательны, дата выхода проверяется, только если задана: песни из импорта,
+// плейлистов и внешнего API могут не иметь даты, и их должно быть можно изменить
+func SongInput(input models.SongInput) Errors {
+	errs := NewSong(input.Group, input.Song)
+	if !input.ReleaseDate.IsZero() {
+		checkReleaseDate(&errs, "releaseDate", input.ReleaseDate)
+	}
+	checkText(&errs, "text", input.Text)
+	checkLink(&errs, "link", input.Link)
+	return errs
+}
+
+// DecodeSongInput строго разбирает JSON-представление песни и проверяет его.
+// Неизвестные поля, значения неверных типов и нарушения правил возвращаются
+// вместе как Errors, синтаксические ошибки JSON - как обычная ошибка.
+func DecodeSongInput(data []byte) (models.SongInput, error) {
+	var input models.SongInput
+
+	errs, err := decodeObject(data, &input)
+	if err != nil {
+		return models.SongInput{}, err
+	}
+	if errs.has("$") {
+		return models.SongInput{}, errs
+	}
+
+	input.Group = strings.TrimSpace(input.Group)
+	input.Song = strings.TrimSpace(input.Song)
+	input.Link = strings.TrimSpace(input.Link)
+
+	// Правила проверяются только для полей, которые удалось разобрать
+	for _, fieldErr := range SongInput(input) {
+		if !errs.has(fieldErr.Field) {
+			errs = append(errs, fieldErr)
+		}
+	}
+	if len(errs) > 0 {
+		return models.SongInput{}, errs
+	}
+
+	return input, nil
+}
+
+// DecodeObject строго разбирает JSON-объект data в структуру, на которую указывает v.
+// Неизвестные поля и значения неверных типов возвращаются вместе как Errors,
+// синтаксические ошибки JSON - как обычная ошибка.
+func DecodeObject(data []byte, v any) error {
+	errs, err := decodeObject(data, v)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// decodeObject разбирает JSON-объект data в структуру v поле за полем и
+// возвращает ошибки всех полей. Неизвестными считаются ключи, которых нет
+// среди JSON-имён полей структуры
+func decodeObject(data []byte, v any) (Errors, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return Errors{{Field: "$", Code: CodeInvalidType, Message: "must be an object"}}, nil
+		}
+		return nil, err
+	}
+	if raw == nil {
+		return Errors{{Field: "$", Code: CodeInvalidType, Message: "must be an object"}}, nil
+	}
+
+	fields := jsonFields(reflect.ValueOf(v).Elem())
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs Errors
+	for _, key := range keys {
+		field, ok := fields[key]
+		if !ok {
+			errs.add(key, CodeUnknownField, "unknown field")
+			continue
+		}
+		if err := json.Unmarshal(raw[key], field.Addr().Interface()); err != nil {
+			// Значение уже разобрано как JSON, поэтому любая ошибка здесь - ошибка типа
+			name, fieldType := key, field.Type()
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				if typeErr.Field != "" {
+					name += "." + typeErr.Field
+				}
+				fieldType = typeErr.Type
+			}
+			errs = append(errs, typeError(name, fieldType))
+		}
+	}
+	return errs, nil
+}
+
+// jsonFields возвращает поля структуры value по их JSON-именам
+func jsonFields(value reflect.Value) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = value.Field(i)
+	}
+	return fields
+}
+
+// typeError описывает значение поля field, которое не удалось разобрать в тип fieldType
+func typeError(field string, fieldType reflect.Type) models.FieldError {
+	message := fmt.Sprintf("must be of type %s", fieldType)
+	if fieldType == reflect.TypeOf(time.Time{}) {
+		message = "must be an RFC 3339 date-time"
+	}
+	return models.FieldError{Field: field, Code: CodeInvalidType, Message: message}
+}
+
+// DecodeError преобразует ошибки типов encoding/json в Errors. Прочие ошибки
+// (например, синтаксические) возвращаются без изменений.
+func DecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	var timeErr *time.ParseError
+	switch {
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "$"
+		}
+		return Errors{typeError(field, typeErr.Type)}
+	case errors.As(err, &timeErr):
+		return Errors{typeError("releaseDate", reflect.TypeOf(time.Time{}))}
+	default:
+		return err
+	}
+}
+
+// checkName проверяет обязательное название: длину и допустимые символы
+func checkName(errs *Errors, field, value string) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		errs.add(field, CodeRequired, "must not be empty")
+	case utf8.RuneCountInString(value) > MaxNameLength:
+		errs.add(field, CodeTooLong, fmt.Sprintf("must be at most %d characters", MaxNameLength))
+	case strings.IndexFunc(value, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0:
+		errs.add(field, CodeInvalidCharacters, "must not contain control or non-printable characters")
+	}
+}
+
+// checkReleaseDate проверяет, что дата выхода лежит в допустимом диапазоне
+func checkReleaseDate(errs *Errors, field string, value time.Time) {
+	maxDate := time.Now().AddDate(1, 0, 0)
+	if value.Before(MinReleaseDate) || value.After(maxDate) {
+		errs.add(field, CodeOutOfRange, fmt.Sprintf("must be between %s and %s", MinReleaseDate.Format(time.DateOnly), maxDate.Format(time.DateOnly)))
+	}
+}
+
+// checkText проверяет длину текста песни и отсутствие управляющих символов
+func checkText(errs *Errors, field, value string) {
+	switch {
+	case utf8.RuneCountInString(value) > MaxTextLength:
+		errs.add(field, CodeTooLong, fmt.Sprintf("must be at most %d characters", MaxTextLength))
+	case strings.IndexFunc(value, func(r rune) bool { return !unicode.IsPrint(r) && !unicode.IsSpace(r) }) >= 0:
+		errs.add(field, CodeInvalidCharacters, "must not contain control characters")
+	}
+}
+
+// checkLink проверяет, что ссылка - абсолютный URL со схемой http или https
+func checkLink(errs *Errors, field, value string) {
+	if value == "" {
+		return
+	}
+
+	checkURL(errs, field, value, MaxLinkLength)
+}
+
+// checkURL проверяет, что value - абсолютный http(s) URL не длиннее maxLength символов
+func checkURL(errs *Errors, field, value string, maxLength int) {
+	if utf8.RuneCountInString(value) > maxLength {
+		errs.add(field, CodeTooLong, fmt.Sprintf("must be at most %d characters", maxLength))
+		return
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || !parsed.IsAbs() || parsed.Host == "" {
+		errs.add(field, CodeInvalidURL, "must be an absolute URL")
+		return
+	}
+
+	if !allowedLinkSchemes[strings.ToLower(parsed.Scheme)] {
+		errs.add(field, CodeUnsupportedScheme, "must use http or https scheme")
+	}
+}
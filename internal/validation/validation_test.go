@@ -0,0 +1,105 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+// fieldCodes возвращает ошибки полей в виде "поле:код"
+func fieldCodes(errs Errors) []string {
+	codes := make([]string, 0, len(errs))
+	for _, fieldErr := range errs {
+		codes = append(codes, fieldErr.Field+":"+fieldErr.Code)
+	}
+	return codes
+}
+
+func TestDecodeSongInput(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"valid", `{"group":" Muse ","song":"Uprising","releaseDate":"2009-09-07T00:00:00Z","text":"line\nline","link":"https://example.com"}`, nil},
+		{"without optional fields", `{"group":"Muse","song":"Uprising"}`, nil},
+		{"null optional field", `{"group":"Muse","song":"Uprising","link":null}`, nil},
+		{"unknown fields", `{"group":"Muse","song":"Uprising","year":2009,"album":"The Resistance"}`,
+			[]string{"album:unknown_field", "year:unknown_field"}},
+		{"unknown field differing in case", `{"group":"Muse","song":"Uprising","Link":"https://example.com"}`,
+			[]string{"Link:unknown_field"}},
+		{"wrong types", `{"group":1,"song":"Uprising","releaseDate":"yesterday","text":[]}`,
+			[]string{"group:invalid_type", "releaseDate:invalid_type", "text:invalid_type"}},
+		{"all errors together", `{"group":"","song":2,"link":"ftp://example.com","extra":true}`,
+			[]string{"extra:unknown_field", "song:invalid_type", "group:required", "link:unsupported_scheme"}},
+		{"rules after trimming", `{"group":"   ","song":"Uprising","link":"  "}`, []string{"group:required"}},
+		{"release date out of range", `{"group":"Muse","song":"Uprising","releaseDate":"1800-01-01T00:00:00Z"}`,
+			[]string{"releaseDate:out_of_range"}},
+		{"not an object", `["Muse"]`, []string{"$:invalid_type"}},
+		{"null", `null`, []string{"$:invalid_type"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeSongInput([]byte(tt.body))
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("DecodeSongInput: %v", err)
+				}
+				return
+			}
+
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("error = %v, want field errors %v", err, tt.want)
+			}
+			if got := fieldCodes(errs); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("field errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeSongInputValues(t *testing.T) {
+	input, err := DecodeSongInput([]byte(`{"group":" Muse ","song":" Uprising ","releaseDate":"2009-09-07T00:00:00Z","text":" text ","link":" https://example.com "}`))
+	if err != nil {
+		t.Fatalf("DecodeSongInput: %v", err)
+	}
+
+	want := models.SongInput{
+		Group:       "Muse",
+		Song:        "Uprising",
+		ReleaseDate: time.Date(2009, time.September, 7, 0, 0, 0, 0, time.UTC),
+		Text:        " text ",
+		Link:        "https://example.com",
+	}
+	if input != want {
+		t.Errorf("input = %+v, want %+v", input, want)
+	}
+}
+
+func TestDecodeSongInputSyntaxError(t *testing.T) {
+	for _, body := range []string{``, `{"group":`, `{"group":"Muse"} {}`, `{'group':'Muse'}`} {
+		_, err := DecodeSongInput([]byte(body))
+		var errs Errors
+		if err == nil || errors.As(err, &errs) {
+			t.Errorf("DecodeSongInput(%q) = %v, want a syntax error", body, err)
+		}
+	}
+}
+
+func TestDecodeObjectNestedType(t *testing.T) {
+	var input models.WebhookInput
+	err := DecodeObject([]byte(`{"url":"https://example.com","eventTypes":["song.created",1]}`), &input)
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error = %v, want field errors", err)
+	}
+	if got := fieldCodes(errs); len(got) != 1 || !strings.HasPrefix(got[0], "eventTypes") || !strings.HasSuffix(got[0], ":invalid_type") {
+		t.Errorf("field errors = %v, want an invalid type of eventTypes", got)
+	}
+}
@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/inanmasov/music-service/internal/models"
+)
+
+const (
+	// MaxWebhookURLLength - максимальная длина адреса вебхука (VARCHAR(2048) в БД)
+	MaxWebhookURLLength = 2048
+	// MinWebhookSecretLength - минимальная длина секрета, заданного подписчиком
+	MinWebhookSecretLength = 16
+	// MaxWebhookSecretLength - максимальная длина секрета (VARCHAR(255) в БД)
+	MaxWebhookSecretLength = 255
+)
+
+// WebhookInput проверяет данные регистрации вебхука: адрес обязателен,
+// секрет и типы событий проверяются, только если заданы
+func WebhookInput(input models.WebhookInput) Errors {
+	var errs Errors
+
+	if input.URL == "" {
+		errs.add("url", CodeRequired, "must not be empty")
+	} else {
+		checkURL(&errs, "url", input.URL, MaxWebhookURLLength)
+	}
+
+	if input.Secret != "" {
+		length := utf8.RuneCountInString(input.Secret)
+		if length < MinWebhookSecretLength || length > MaxWebhookSecretLength {
+			errs.add("secret", CodeOutOfRange, fmt.Sprintf("must be between %d and %d characters", MinWebhookSecretLength, MaxWebhookSecretLength))
+		}
+	}
+
+	for i, eventType := range input.EventTypes {
+		if !models.IsEventType(eventType) {
+			errs.add(fmt.Sprintf("eventTypes[%d]", i), CodeUnknownEventType, "must be one of "+strings.Join(models.EventTypes, ", "))
+		}
+	}
+
+	return errs
+}
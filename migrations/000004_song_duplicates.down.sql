@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS song_redirects;
+DROP INDEX IF EXISTS idx_songs_normalized_title;
+DROP INDEX IF EXISTS idx_groups_normalized_name;
+DROP FUNCTION IF EXISTS normalize_title(TEXT);
@@ -0,0 +1,16 @@
+-- normalize_title приводит название к виду для поиска дубликатов: нижний регистр,
+-- любые последовательности знаков, кроме букв и цифр, заменены одним пробелом
+CREATE FUNCTION normalize_title(value TEXT) RETURNS TEXT
+    LANGUAGE SQL IMMUTABLE STRICT PARALLEL SAFE
+    AS $$ SELECT btrim(regexp_replace(lower(value), '[^[:alnum:]]+', ' ', 'g')) $$;
+
+CREATE INDEX idx_groups_normalized_name ON groups (normalize_title(name));
+CREATE INDEX idx_songs_normalized_title ON songs (group_id, normalize_title(song));
+
+CREATE TABLE song_redirects (
+    old_id INT PRIMARY KEY,
+    song_id INT NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    merged_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_song_redirects_song_id ON song_redirects (song_id);
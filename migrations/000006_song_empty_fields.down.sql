@@ -0,0 +1,5 @@
+-- Миграция необратима: после неё не узнать, какие NULL до неё были пустыми
+-- строками и датой 0001-01-01, а какие - NULL изначально. Откат намеренно не
+-- меняет данные: прежняя версия сервиса читает NULL так же, как пустые значения,
+-- а повторное применение миграции ничего не изменит
+SELECT 1;
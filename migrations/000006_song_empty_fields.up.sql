@@ -0,0 +1,7 @@
+-- Полная замена песни сохраняла пустые текст и ссылку как '' и отсутствующую дату
+-- как 0001-01-01, а добавление - как NULL. Приводим старые строки к NULL
+UPDATE songs
+SET text = NULLIF(text, ''),
+    link = NULLIF(link, ''),
+    release_date = NULLIF(release_date, DATE '0001-01-01')
+WHERE text = '' OR link = '' OR release_date = DATE '0001-01-01';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_outbox_events_unsequenced;
+DROP INDEX IF EXISTS idx_outbox_events_seq;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS seq;
+DROP SEQUENCE IF EXISTS outbox_events_seq;
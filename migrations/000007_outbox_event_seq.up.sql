@@ -0,0 +1,14 @@
+-- Позиция события в журнале в порядке фиксации транзакций. id событий выдаются
+-- до фиксации, поэтому событие с меньшим id может стать видимым позже события
+-- с большим, и поток событий, читающий по id, пропустил бы его. Позиции назначаются
+-- уже зафиксированным событиям, по одной транзакции за раз (outbox.AssignSeq)
+CREATE SEQUENCE outbox_events_seq;
+
+ALTER TABLE outbox_events ADD COLUMN seq BIGINT;
+
+-- Прежние события получают позиции, равные id, чтобы Last-Event-ID клиентов остались верны
+UPDATE outbox_events SET seq = id;
+SELECT setval('outbox_events_seq', COALESCE(MAX(id), 0) + 1, false) FROM outbox_events;
+
+CREATE UNIQUE INDEX idx_outbox_events_seq ON outbox_events (seq);
+CREATE INDEX idx_outbox_events_unsequenced ON outbox_events (id) WHERE seq IS NULL;
@@ -0,0 +1,2 @@
+-- Удалённые ключи не восстановить, а ключи в новом виде прежней версии не найти
+DELETE FROM idempotency_keys;
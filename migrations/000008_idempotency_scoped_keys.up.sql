@@ -0,0 +1,7 @@
+-- Ключи идемпотентности теперь хранятся как хеш метода, маршрута, клиента и ключа,
+-- поэтому прежние строки недостижимы: по ним уже не найти ни один запрос, и они
+-- только занимали бы место до истечения IDEMPOTENCY_TTL. Среди них могут быть
+-- ответы на регистрацию вебхука с его секретом, которые больше не сохраняются,
+-- поэтому строки удаляются сразу, а не по TTL. Повтор запроса, впервые
+-- выполненного до обновления, выполнится снова - как и без удаления строк
+DELETE FROM idempotency_keys;
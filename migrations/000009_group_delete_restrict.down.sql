@@ -0,0 +1,3 @@
+ALTER TABLE songs DROP CONSTRAINT songs_group_id_fkey;
+ALTER TABLE songs ADD CONSTRAINT songs_group_id_fkey
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE;
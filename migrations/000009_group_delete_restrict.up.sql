@@ -0,0 +1,6 @@
+-- Группа без песен удаляется сервисом вместе с событием group.deleted. Каскадное
+-- удаление заменяется запретом, чтобы удаление группы, в которую параллельно
+-- добавили песню, завершалось ошибкой, а не удаляло эту песню
+ALTER TABLE songs DROP CONSTRAINT songs_group_id_fkey;
+ALTER TABLE songs ADD CONSTRAINT songs_group_id_fkey
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE RESTRICT;
@@ -0,0 +1,83 @@
+// Package migrations содержит SQL-миграции схемы базы данных, встроенные в бинарный файл
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"net/url"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// FS - файлы миграций в формате golang-migrate: <версия>_<название>.up.sql и .down.sql
+//
+//go:embed *.sql
+var FS embed.FS
+
+// New создаёт golang-migrate для базы данных по строке подключения dsn.
+// Миграции читаются из FS, а не из рабочего каталога
+func New(dsn string) (*migrate.Migrate, error) {
+	src, err := iofs.New(FS, ".")
+	if err != nil {
+		return nil, err
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", src, dsn)
+	if err != nil {
+		// golang-migrate включает в ошибку строку подключения вместе с паролем
+		if parsed, parseErr := url.Parse(dsn); parseErr == nil {
+			return nil, errors.New(strings.ReplaceAll(err.Error(), dsn, parsed.Redacted()))
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+// Migration - миграция из FS
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+// List возвращает все миграции по возрастанию версии
+func List() ([]Migration, error) {
+	src, err := iofs.New(FS, ".")
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	var list []Migration
+	version, err := src.First()
+	for err == nil {
+		list = append(list, Migration{Version: version, Name: name(src, version)})
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Latest возвращает версию последней миграции или 0, если миграций нет
+func Latest() (uint, error) {
+	list, err := List()
+	if err != nil || len(list) == 0 {
+		return 0, err
+	}
+	return list[len(list)-1].Version, nil
+}
+
+// name возвращает название миграции version из имени её up-файла
+func name(src source.Driver, version uint) string {
+	body, identifier, err := src.ReadUp(version)
+	if err != nil {
+		return ""
+	}
+	body.Close()
+	return identifier
+}